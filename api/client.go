@@ -0,0 +1,407 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/utils"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default timeout of the requests sent to the daemon
+const DefaultClientTimeout = 30 * time.Second
+
+// Number of request errors kept by the client errors channel, the following ones are dropped until it's read
+const clientErrorsBuffer = 16
+
+// Remote scheduler, that sends the operations to a running daemon control API.
+// Process control operations (Start, Stop, Wait, RunOnce, Destroy) and cache operations are not available
+// remotely and they report an error.
+type Client struct {
+	baseURL		string
+	httpClient	*http.Client
+	errs		chan error
+	warnings	chan error
+	warningsOnce	sync.Once
+}
+
+var _ model.Scheduler = (*Client)(nil)
+
+// Creates a client for the daemon control API listening on the given unix domain socket
+func NewUnixClient(socketPath string) *Client {
+	return &Client{
+		baseURL: "http://unix" + BasePath,
+		errs:    make(chan error, clientErrorsBuffer),
+		httpClient: &http.Client{
+			Timeout: DefaultClientTimeout,
+			Transport: &http.Transport{
+				DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+					var dialer net.Dialer
+					return dialer.DialContext(ctx, "unix", socketPath)
+				},
+			},
+		},
+	}
+}
+
+// Creates a client for the daemon control API listening on the given HTTP address (e.g.: 127.0.0.1:8480)
+func NewHTTPClient(address string) *Client {
+	return &Client{
+		baseURL:    "http://" + address + BasePath,
+		httpClient: &http.Client{Timeout: DefaultClientTimeout},
+		errs:       make(chan error, clientErrorsBuffer),
+	}
+}
+
+// Retrieves the daemon status, it's used to verify that the daemon is reachable
+func (c *Client) Status() (Status, error) {
+	var status Status
+	err := c.do(http.MethodGet, "/status", nil, &status)
+	return status, err
+}
+
+// Sends the request to the daemon and decodes the JSON response in out, when not nil
+func (c *Client) do(method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode >= http.StatusBadRequest {
+		var errResp ErrorResponse
+		if errD := json.NewDecoder(resp.Body).Decode(&errResp); errD != nil || errResp.Error == "" {
+			return errors.New(fmt.Sprintf("Daemon request %s %s failed with status: %s", method, path, resp.Status))
+		}
+		return errors.New(errResp.Error)
+	}
+	if out != nil && resp.StatusCode != http.StatusNoContent {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
+
+// Reports the error of a request whose operation cannot return it, see Errors
+func (c *Client) report(err error) {
+	if err == nil {
+		return
+	}
+	select {
+	case c.errs <- err:
+	default:
+	}
+}
+
+func (c *Client) remoteError(operation string) error {
+	return errors.New(fmt.Sprintf("Operation %s is not available on a remote scheduler", operation))
+}
+
+func (c *Client) referenceId(index int) (string, error) {
+	var refs = make([]model.CommandConfigRef, 0)
+	if err := c.do(http.MethodGet, "/references", nil, &refs); err != nil {
+		return "", err
+	}
+	if index < 0 || index >= len(refs) {
+		return "", errors.New(fmt.Sprintf("Index out of bound: %v, must be 0 <= x < %v ", index, len(refs)))
+	}
+	return refs[index].UUID, nil
+}
+
+func (c *Client) IsRunning() bool {
+	status, err := c.Status()
+	return err == nil && status.Running
+}
+
+func (c *Client) Location() *time.Location {
+	status, err := c.Status()
+	if err == nil {
+		if loc, errL := utils.LoadLocation(status.TimeZone); errL == nil {
+			return loc
+		}
+	}
+	return time.Local
+}
+
+func (c *Client) Start() error {
+	return c.remoteError("start")
+}
+
+func (c *Client) Stop() error {
+	return c.remoteError("stop")
+}
+
+func (c *Client) RunOnce() error {
+	return c.remoteError("run once")
+}
+
+func (c *Client) Pause() error {
+	return c.do(http.MethodPost, "/pause", nil, nil)
+}
+
+func (c *Client) Resume() error {
+	return c.do(http.MethodPost, "/resume", nil, nil)
+}
+
+func (c *Client) Enable(key string) error {
+	return c.do(http.MethodPost, "/tasks/"+url.PathEscape(key)+"/enable", nil, nil)
+}
+
+func (c *Client) Disable(key string) error {
+	return c.do(http.MethodPost, "/tasks/"+url.PathEscape(key)+"/disable", nil, nil)
+}
+
+func (c *Client) State() model.SchedulerState {
+	status, err := c.Status()
+	if err != nil {
+		return model.SchedulerStateStopped
+	}
+	return status.State
+}
+
+func (c *Client) Trigger(id string) error {
+	return c.do(http.MethodPost, "/tasks/"+url.PathEscape(id)+"/trigger", nil, nil)
+}
+
+func (c *Client) Running() []model.Execution {
+	var out = make([]model.Execution, 0)
+	c.report(c.do(http.MethodGet, "/tasks/active", nil, &out))
+	return out
+}
+
+// Remote scheduler data is always up to date, there is nothing to load
+func (c *Client) Load() error {
+	return nil
+}
+
+func (c *Client) References() []model.CommandConfigRef {
+	var out = make([]model.CommandConfigRef, 0)
+	c.report(c.do(http.MethodGet, "/references", nil, &out))
+	return out
+}
+
+func (c *Client) Planned() []model.CommandConfig {
+	var out = make([]model.CommandConfig, 0)
+	c.report(c.do(http.MethodGet, "/tasks", nil, &out))
+	return out
+}
+
+func (c *Client) NextRunningTasks() []model.Execution {
+	var out = make([]model.Execution, 0)
+	c.report(c.do(http.MethodGet, "/tasks/next", nil, &out))
+	return out
+}
+
+func (c *Client) History(filter model.HistoryFilter) []model.RunRecord {
+	var query = url.Values{}
+	if filter.UUID != "" {
+		query.Set("uuid", filter.UUID)
+	}
+	if !filter.Since.IsZero() {
+		query.Set("since", filter.Since.Format(time.RFC3339Nano))
+	}
+	if filter.Status != "" {
+		query.Set("status", filter.Status)
+	}
+	if filter.Trigger != "" {
+		query.Set("trigger", string(filter.Trigger))
+	}
+	if filter.Limit > 0 {
+		query.Set("limit", strconv.Itoa(filter.Limit))
+	}
+	var out = make([]model.RunRecord, 0)
+	c.report(c.do(http.MethodGet, "/history?"+query.Encode(), nil, &out))
+	return out
+}
+
+func (c *Client) Deliveries(uuid string, limit int) []model.WebhookDelivery {
+	var query = url.Values{}
+	if uuid != "" {
+		query.Set("uuid", uuid)
+	}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	var out = make([]model.WebhookDelivery, 0)
+	c.report(c.do(http.MethodGet, "/deliveries?"+query.Encode(), nil, &out))
+	return out
+}
+
+func (c *Client) Get(key string) (model.CommandConfigRef, model.CommandConfig, error) {
+	var task Task
+	err := c.do(http.MethodGet, "/tasks/"+url.PathEscape(key), nil, &task)
+	return task.Reference, task.Command, err
+}
+
+func (c *Client) Update(key string, cmd model.CommandConfig) error {
+	return c.do(http.MethodPut, "/tasks/"+url.PathEscape(key), cmd, nil)
+}
+
+func (c *Client) Delete(key string) error {
+	return c.do(http.MethodDelete, "/tasks/"+url.PathEscape(key), nil, nil)
+}
+
+func (c *Client) AddAndPersist(cmd model.CommandConfig) error {
+	_, err := c.Add(cmd)
+	return err
+}
+
+func (c *Client) Add(cmd model.CommandConfig) (model.CommandConfigRef, error) {
+	var ref model.CommandConfigRef
+	err := c.do(http.MethodPost, "/tasks", cmd, &ref)
+	return ref, err
+}
+
+func (c *Client) UpdateAndPersist(cmd model.CommandConfig, index int) error {
+	id, err := c.referenceId(index)
+	if err != nil {
+		return err
+	}
+	return c.Update(id, cmd)
+}
+
+func (c *Client) DeleteAndPersist(index int) error {
+	id, err := c.referenceId(index)
+	if err != nil {
+		return err
+	}
+	return c.Delete(id)
+}
+
+func (c *Client) AddToCache(cmd model.CommandConfig) error {
+	return c.remoteError("add to cache")
+}
+
+func (c *Client) UpdateToCache(cmd model.CommandConfig, index int) error {
+	return c.remoteError("update to cache")
+}
+
+func (c *Client) DeleteFromCache(index int) error {
+	return c.remoteError("delete from cache")
+}
+
+// Remote scheduler process cannot be waited
+func (c *Client) Wait() {
+}
+
+// Streams the daemon events from the events endpoint, until the subscription is closed or the daemon stops
+func (c *Client) Subscribe(buffer int) *model.Subscription {
+	var bus = model.NewEventBus()
+	var subscription = bus.Subscribe(buffer)
+	var ctx, cancel = context.WithCancel(context.Background())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/events", nil)
+	if err != nil {
+		cancel()
+		bus.Close()
+		return subscription
+	}
+	go func() {
+		<-subscription.Done()
+		cancel()
+	}()
+	go func() {
+		defer bus.Close()
+		// The stream has no timeout, it lasts until the subscription is closed
+		var client = &http.Client{Transport: c.httpClient.Transport}
+		resp, err := client.Do(req)
+		if err != nil {
+			return
+		}
+		defer func() {
+			_ = resp.Body.Close()
+		}()
+		var scanner = bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+		for scanner.Scan() {
+			var line = scanner.Text()
+			if !strings.HasPrefix(line, "data: ") {
+				continue
+			}
+			var event model.Event
+			if json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event) == nil {
+				bus.Publish(event)
+			}
+		}
+	}()
+	return subscription
+}
+
+// Copies the daemon metrics, in the Prometheus text exposition format
+func (c *Client) WriteMetrics(w io.Writer) error {
+	resp, err := c.httpClient.Get(c.baseURL + "/metrics")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return errors.New(fmt.Sprintf("Unable to read the daemon metrics, status: %s", resp.Status))
+	}
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// Reports the errors of the daemon requests sent by the list operations (Running, References, Planned,
+// NextRunningTasks, History and Deliveries), that return an empty list when the request fails.
+// The scheduler errors are streamed by the daemon events endpoint, see Subscribe
+func (c *Client) Errors() chan error {
+	return c.errs
+}
+
+// Retrieves the daemon warning level events, as errors, streamed by the daemon events endpoint. The channel is
+// closed when the daemon stops or it's not reachable
+func (c *Client) Warnings() chan error {
+	c.warningsOnce.Do(func() {
+		c.warnings = make(chan error)
+		var subscription = c.Subscribe(model.DefaultEventBuffer)
+		go func() {
+			defer close(c.warnings)
+			for event := range subscription.Events() {
+				if event.Level != model.EventLevelWarning {
+					continue
+				}
+				var err = errors.New(event.Message)
+				if event.UUID != "" {
+					err = &model.TaskError{UUID: event.UUID, RunID: event.RunID, Err: err}
+				}
+				select {
+				case c.warnings <- err:
+				case <-subscription.Done():
+					return
+				}
+			}
+		}()
+	})
+	return c.warnings
+}
+
+// Remote scheduler cannot be destroyed
+func (c *Client) Destroy(saveState bool) {
+}
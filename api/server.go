@@ -0,0 +1,465 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/metrics"
+	"github.com/hellgate75/go-cron/model"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Root path of the API endpoints
+const BasePath = "/api"
+
+// Path of the metrics endpoint, on the metrics listener
+const MetricsPath = "/metrics"
+
+// Describes the scheduler status
+type Status struct {
+	State		model.SchedulerState	`yaml:"state,omitempty" json:"state,omitempty" xml:"state,omitempty"`
+	Running		bool					`yaml:"running,omitempty" json:"running,omitempty" xml:"running,omitempty"`
+	Tasks		int						`yaml:"tasks,omitempty" json:"tasks,omitempty" xml:"tasks,omitempty"`
+	TimeZone	string					`yaml:"timeZone,omitempty" json:"timeZone,omitempty" xml:"time-zone,omitempty"`
+}
+
+// Describes a task, with its reference and configuration
+type Task struct {
+	Reference	model.CommandConfigRef	`yaml:"reference,omitempty" json:"reference,omitempty" xml:"reference,omitempty"`
+	Command		model.CommandConfig		`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+}
+
+// Describes an API error response
+type ErrorResponse struct {
+	Error		string				`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// Exposes the scheduler as JSON REST endpoints
+type Server struct {
+	scheduler	model.Scheduler
+	lock		sync.Mutex
+	httpServers	[]*http.Server
+	// Closed on shutdown, to end the events streams
+	closing		chan struct{}
+	closeOnce	sync.Once
+}
+
+// Creates the API handler for the given scheduler
+func NewServer(scheduler model.Scheduler) *Server {
+	return &Server{
+		scheduler: scheduler,
+		closing:   make(chan struct{}),
+	}
+}
+
+// Verifies that the address is a loopback one, empty host is the IPv4 loopback address
+func LocalAddress(address string) (string, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return "", err
+	}
+	if host == "" {
+		host = "127.0.0.1"
+	}
+	if host != "localhost" {
+		if ip := net.ParseIP(host); ip == nil || !ip.IsLoopback() {
+			return "", errors.New(fmt.Sprintf("API address must be a loopback address, found: %s", host))
+		}
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Listens on the given loopback address and serves the API until Shutdown is called
+func (s *Server) ListenAndServe(address string) error {
+	var local, err = LocalAddress(address)
+	if err != nil {
+		return err
+	}
+	listener, err := net.Listen("tcp", local)
+	if err != nil {
+		return err
+	}
+	return s.Serve(listener)
+}
+
+// Listens on the given unix domain socket and serves the API until Shutdown is called. A socket file left by
+// a stopped daemon is replaced, while a socket of a running daemon is reported as error
+func (s *Server) ListenAndServeUnix(socketPath string) error {
+	if _, err := os.Stat(socketPath); err == nil {
+		if conn, errD := net.DialTimeout("unix", socketPath, time.Second); errD == nil {
+			_ = conn.Close()
+			return errors.New(fmt.Sprintf("Another daemon is listening on socket: %s", socketPath))
+		}
+		if err = os.Remove(socketPath); err != nil {
+			return err
+		}
+	}
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		return err
+	}
+	// Only the daemon user can send requests
+	_ = os.Chmod(socketPath, 0600)
+	return s.Serve(listener)
+}
+
+// Listens on the given address, also not loopback ones, and serves only the read-only metrics endpoint until
+// Shutdown is called
+func (s *Server) ListenAndServeMetrics(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	return s.serve(listener, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != MetricsPath {
+			writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Unknown path: %s", r.URL.Path)))
+			return
+		}
+		s.onlyMethod(w, r, http.MethodGet, s.metrics)
+	}))
+}
+
+// Serves the API on the given listener until Shutdown is called
+func (s *Server) Serve(listener net.Listener) error {
+	return s.serve(listener, s)
+}
+
+func (s *Server) serve(listener net.Listener, handler http.Handler) error {
+	var httpServer = &http.Server{Handler: handler}
+	s.lock.Lock()
+	s.httpServers = append(s.httpServers, httpServer)
+	s.lock.Unlock()
+	var err = httpServer.Serve(listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stops the API server, closing the events streams
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.lock.Lock()
+	var httpServers = s.httpServers
+	s.httpServers = nil
+	s.lock.Unlock()
+	s.closeOnce.Do(func() {
+		close(s.closing)
+	})
+	var err error
+	for _, httpServer := range httpServers {
+		if errS := httpServer.Shutdown(ctx); errS != nil {
+			err = errS
+		}
+	}
+	return err
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			writeError(w, http.StatusInternalServerError, errors.New(fmt.Sprintf("%v", rec)))
+		}
+	}()
+	if r.URL.Path != BasePath && !strings.HasPrefix(r.URL.Path, BasePath+"/") {
+		writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Unknown path: %s", r.URL.Path)))
+		return
+	}
+	var parts = strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, BasePath), "/"), "/")
+	switch {
+	case match(parts, "status"):
+		s.onlyMethod(w, r, http.MethodGet, s.status)
+	case match(parts, "pause"):
+		s.onlyMethod(w, r, http.MethodPost, s.pause)
+	case match(parts, "resume"):
+		s.onlyMethod(w, r, http.MethodPost, s.resume)
+	case match(parts, "history"):
+		s.onlyMethod(w, r, http.MethodGet, s.history)
+	case match(parts, "deliveries"):
+		s.onlyMethod(w, r, http.MethodGet, s.deliveries)
+	case match(parts, "events"):
+		s.onlyMethod(w, r, http.MethodGet, s.events)
+	case match(parts, "metrics"):
+		s.onlyMethod(w, r, http.MethodGet, s.metrics)
+	case match(parts, "references"):
+		s.onlyMethod(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, s.scheduler.References())
+		})
+	case match(parts, "tasks", "active"):
+		s.onlyMethod(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, s.scheduler.Running())
+		})
+	case match(parts, "tasks", "next"):
+		s.onlyMethod(w, r, http.MethodGet, func(w http.ResponseWriter, r *http.Request) {
+			writeJSON(w, http.StatusOK, s.scheduler.NextRunningTasks())
+		})
+	case match(parts, "tasks"):
+		switch r.Method {
+		case http.MethodGet:
+			writeJSON(w, http.StatusOK, s.scheduler.Planned())
+		case http.MethodPost:
+			s.addTask(w, r)
+		default:
+			writeMethodNotAllowed(w, r)
+		}
+	case len(parts) == 2 && parts[0] == "tasks":
+		switch r.Method {
+		case http.MethodGet:
+			s.getTask(w, r, parts[1])
+		case http.MethodPut:
+			s.updateTask(w, r, parts[1])
+		case http.MethodDelete:
+			s.deleteTask(w, r, parts[1])
+		default:
+			writeMethodNotAllowed(w, r)
+		}
+	case len(parts) == 3 && parts[0] == "tasks" && parts[2] == "trigger":
+		s.onlyMethod(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			s.trigger(w, r, parts[1])
+		})
+	case len(parts) == 3 && parts[0] == "tasks" && parts[2] == "enable":
+		s.onlyMethod(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			s.setEnabled(w, r, parts[1], s.scheduler.Enable)
+		})
+	case len(parts) == 3 && parts[0] == "tasks" && parts[2] == "disable":
+		s.onlyMethod(w, r, http.MethodPost, func(w http.ResponseWriter, r *http.Request) {
+			s.setEnabled(w, r, parts[1], s.scheduler.Disable)
+		})
+	default:
+		writeError(w, http.StatusNotFound, errors.New(fmt.Sprintf("Unknown path: %s", r.URL.Path)))
+	}
+}
+
+func match(parts []string, path ...string) bool {
+	if len(parts) != len(path) {
+		return false
+	}
+	for i, p := range path {
+		if parts[i] != p {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Server) onlyMethod(w http.ResponseWriter, r *http.Request, method string, handler http.HandlerFunc) {
+	if r.Method != method {
+		writeMethodNotAllowed(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+func (s *Server) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Status{
+		State:   s.scheduler.State(),
+		Running: s.scheduler.IsRunning(),
+		Tasks:   len(s.scheduler.Planned()),
+		TimeZone: s.scheduler.Location().String(),
+	})
+}
+
+// Writes the scheduler metrics in the Prometheus text exposition format
+func (s *Server) metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	w.WriteHeader(http.StatusOK)
+	_ = s.scheduler.WriteMetrics(w)
+}
+
+func (s *Server) pause(w http.ResponseWriter, r *http.Request) {
+	if err := s.scheduler.Pause(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.status(w, r)
+}
+
+func (s *Server) resume(w http.ResponseWriter, r *http.Request) {
+	if err := s.scheduler.Resume(); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	s.status(w, r)
+}
+
+func (s *Server) history(w http.ResponseWriter, r *http.Request) {
+	var query = r.URL.Query()
+	var filter = model.HistoryFilter{
+		UUID:    query.Get("uuid"),
+		Status:  query.Get("status"),
+		Trigger: model.TriggerSource(query.Get("trigger")),
+	}
+	if value := query.Get("since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid since time '%s', RFC3339 format required", value)))
+			return
+		}
+		filter.Since = since
+	}
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid limit '%s'", value)))
+			return
+		}
+		filter.Limit = limit
+	}
+	writeJSON(w, http.StatusOK, s.scheduler.History(filter))
+}
+
+func (s *Server) deliveries(w http.ResponseWriter, r *http.Request) {
+	var query = r.URL.Query()
+	var limit int
+	if value := query.Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, errors.New(fmt.Sprintf("Invalid limit '%s'", value)))
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, s.scheduler.Deliveries(query.Get("uuid"), limit))
+}
+
+// Streams the scheduler events as server sent events, named by event type, until the client disconnects. The
+// type query parameter filters the streamed event types (comma separated list)
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, errors.New("Events streaming is not supported"))
+		return
+	}
+	var types = make(map[model.EventType]bool)
+	for _, t := range strings.Split(r.URL.Query().Get("type"), ",") {
+		if strings.TrimSpace(t) != "" {
+			types[model.EventType(strings.TrimSpace(t))] = true
+		}
+	}
+	var subscription = s.scheduler.Subscribe(model.DefaultEventBuffer)
+	defer subscription.Close()
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case event, open := <-subscription.Events():
+			if !open {
+				return
+			}
+			if len(types) > 0 && !types[event.Type] {
+				continue
+			}
+			data, _ := json.Marshal(event)
+			_, _ = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) addTask(w http.ResponseWriter, r *http.Request) {
+	var cmd model.CommandConfig
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	ref, err := s.scheduler.Add(cmd)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, ref)
+}
+
+func (s *Server) getTask(w http.ResponseWriter, r *http.Request, key string) {
+	ref, cmd, err := s.scheduler.Get(key)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, Task{Reference: ref, Command: cmd})
+}
+
+func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, key string) {
+	ref, _, err := s.scheduler.Get(key)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	var cmd model.CommandConfig
+	if err := json.NewDecoder(r.Body).Decode(&cmd); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if err := s.scheduler.Update(ref.UUID, cmd); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	// The task is looked up by unique identifier, the update can rename it
+	s.getTask(w, r, ref.UUID)
+}
+
+// Runs the task on demand: unknown tasks are not found, while the tasks that cannot run are in conflict
+func (s *Server) trigger(w http.ResponseWriter, r *http.Request, key string) {
+	ref, _, err := s.scheduler.Get(key)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err = s.scheduler.Trigger(ref.UUID); err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, struct {
+		UUID	string	`json:"uuid"`
+	}{ref.UUID})
+}
+
+func (s *Server) setEnabled(w http.ResponseWriter, r *http.Request, key string, set func(key string) error) {
+	if _, _, err := s.scheduler.Get(key); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := set(key); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	s.getTask(w, r, key)
+}
+
+func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request, key string) {
+	if _, _, err := s.scheduler.Get(key); err != nil {
+		writeError(w, http.StatusNotFound, err)
+		return
+	}
+	if err := s.scheduler.Delete(key); err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, ErrorResponse{Error: err.Error()})
+}
+
+func writeMethodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, errors.New(fmt.Sprintf("Method %s not allowed on %s", r.Method, r.URL.Path)))
+}
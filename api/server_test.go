@@ -0,0 +1,292 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/api"
+	"github.com/hellgate75/go-cron/cron"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T) (*httptest.Server, model.Scheduler) {
+	scheduler, err := cron.NewEmptySchedulerInStore(store.NewMemoryStore(), false)
+	if err != nil {
+		t.Fatalf("Unable to create the scheduler: %v", err)
+	}
+	if err = scheduler.Start(); err != nil {
+		t.Fatalf("Unable to start the scheduler: %v", err)
+	}
+	var apiServer = api.NewServer(scheduler)
+	var server = httptest.NewServer(apiServer)
+	t.Cleanup(func() {
+		// Events streams end before the server waits for the requests
+		_ = apiServer.Shutdown(context.Background())
+		server.Close()
+		_ = scheduler.Stop()
+	})
+	return server, scheduler
+}
+
+// Sends the request and decodes the response in out, when not nil. It can be called by any goroutine
+func sendRequest(method string, url string, body string, out interface{}) (int, error) {
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Unable to create the request: %v", err))
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Request %s %s failed: %v", method, url, err))
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err = json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return resp.StatusCode, errors.New(fmt.Sprintf("Unable to decode the response of %s %s: %v", method, url, err))
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+// Sends the request, failing the test on errors. It must be called by the test goroutine
+func doRequest(t *testing.T, method string, url string, body string, out interface{}) int {
+	code, err := sendRequest(method, url, body, out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func taskJSON(name string, schedule string) string {
+	return fmt.Sprintf(`{"name":%q,"schedule":%q,"command":{"kind":"shell","shell":"true"}}`, name, schedule)
+}
+
+func TestTaskHandlers(t *testing.T) {
+	server, scheduler := newTestServer(t)
+	var tasks = server.URL + api.BasePath + "/tasks"
+
+	var ref model.CommandConfigRef
+	if status := doRequest(t, http.MethodPost, tasks, taskJSON("backup", "@yearly"), &ref); status != http.StatusCreated {
+		t.Fatalf("Add task status: expected %d, found %d", http.StatusCreated, status)
+	}
+	if ref.UUID == "" || ref.Name != "backup" {
+		t.Fatalf("Add task returned unexpected reference: %+v", ref)
+	}
+	if status := doRequest(t, http.MethodPost, tasks, taskJSON("backup", "@daily"), nil); status != http.StatusBadRequest {
+		t.Errorf("Duplicated name status: expected %d, found %d", http.StatusBadRequest, status)
+	}
+	if status := doRequest(t, http.MethodPost, tasks, "{", nil); status != http.StatusBadRequest {
+		t.Errorf("Invalid body status: expected %d, found %d", http.StatusBadRequest, status)
+	}
+
+	var task api.Task
+	if status := doRequest(t, http.MethodGet, tasks+"/backup", "", &task); status != http.StatusOK {
+		t.Fatalf("Get task status: expected %d, found %d", http.StatusOK, status)
+	}
+	if task.Reference.UUID != ref.UUID || task.Command.Schedule != "@yearly" {
+		t.Errorf("Get task returned unexpected task: %+v", task)
+	}
+
+	if status := doRequest(t, http.MethodPut, tasks+"/"+ref.UUID, taskJSON("backup", "@monthly"), &task); status != http.StatusOK {
+		t.Fatalf("Update task status: expected %d, found %d", http.StatusOK, status)
+	}
+	if task.Command.Schedule != "@monthly" {
+		t.Errorf("Update task: expected schedule @monthly, found %s", task.Command.Schedule)
+	}
+	if status := doRequest(t, http.MethodPut, tasks+"/missing", taskJSON("missing", "@daily"), nil); status != http.StatusNotFound {
+		t.Errorf("Update missing task status: expected %d, found %d", http.StatusNotFound, status)
+	}
+	// Renaming the task addressed by its old name
+	task = api.Task{}
+	if status := doRequest(t, http.MethodPut, tasks+"/backup", taskJSON("archive", "@monthly"), &task); status != http.StatusOK {
+		t.Fatalf("Rename task status: expected %d, found %d", http.StatusOK, status)
+	}
+	if task.Reference.UUID != ref.UUID || task.Reference.Name != "archive" {
+		t.Errorf("Rename task returned unexpected task: %+v", task)
+	}
+
+	var triggered struct {
+		UUID string `json:"uuid"`
+	}
+	if status := doRequest(t, http.MethodPost, tasks+"/archive/trigger", "", &triggered); status != http.StatusAccepted {
+		t.Fatalf("Trigger task status: expected %d, found %d", http.StatusAccepted, status)
+	}
+	if triggered.UUID != ref.UUID {
+		t.Errorf("Trigger task by name: expected unique identifier %s, found %s", ref.UUID, triggered.UUID)
+	}
+	if status := doRequest(t, http.MethodPost, tasks+"/missing/trigger", "", nil); status != http.StatusNotFound {
+		t.Errorf("Trigger missing task status: expected %d, found %d", http.StatusNotFound, status)
+	}
+	// The triggered run completes before the task is deleted
+	for deadline := time.Now().Add(5 * time.Second); len(scheduler.History(model.HistoryFilter{UUID: ref.UUID})) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("Triggered run not completed")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	if status := doRequest(t, http.MethodDelete, tasks+"/archive", "", nil); status != http.StatusNoContent {
+		t.Fatalf("Delete task status: expected %d, found %d", http.StatusNoContent, status)
+	}
+	if status := doRequest(t, http.MethodGet, tasks+"/archive", "", nil); status != http.StatusNotFound {
+		t.Errorf("Get deleted task status: expected %d, found %d", http.StatusNotFound, status)
+	}
+	if len(scheduler.References()) != 0 {
+		t.Errorf("Expected no tasks after delete, found %d", len(scheduler.References()))
+	}
+}
+
+func TestPauseResumeHandlers(t *testing.T) {
+	server, _ := newTestServer(t)
+	var base = server.URL + api.BasePath
+
+	var status api.Status
+	if code := doRequest(t, http.MethodPost, base+"/pause", "", &status); code != http.StatusOK {
+		t.Fatalf("Pause status: expected %d, found %d", http.StatusOK, code)
+	}
+	if status.State != model.SchedulerStatePaused {
+		t.Errorf("Pause: expected state %s, found %s", model.SchedulerStatePaused, status.State)
+	}
+	if code := doRequest(t, http.MethodPost, base+"/pause", "", nil); code != http.StatusConflict {
+		t.Errorf("Second pause status: expected %d, found %d", http.StatusConflict, code)
+	}
+	if code := doRequest(t, http.MethodPost, base+"/resume", "", &status); code != http.StatusOK {
+		t.Fatalf("Resume status: expected %d, found %d", http.StatusOK, code)
+	}
+	if status.State != model.SchedulerStateRunning {
+		t.Errorf("Resume: expected state %s, found %s", model.SchedulerStateRunning, status.State)
+	}
+	if code := doRequest(t, http.MethodGet, base+"/pause", "", nil); code != http.StatusMethodNotAllowed {
+		t.Errorf("Pause with GET status: expected %d, found %d", http.StatusMethodNotAllowed, code)
+	}
+}
+
+// Concurrent mutations and reads run against the started scheduler loop, the race detector reports unlocked access
+func TestConcurrentTaskHandlers(t *testing.T) {
+	server, scheduler := newTestServer(t)
+	var base = server.URL + api.BasePath
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var name = fmt.Sprintf("task-%d", i)
+			var ref model.CommandConfigRef
+			if code, err := sendRequest(http.MethodPost, base+"/tasks", taskJSON(name, "@hourly"), &ref); err != nil || code != http.StatusCreated {
+				t.Errorf("Add %s status: expected %d, found %d (error: %v)", name, http.StatusCreated, code, err)
+				return
+			}
+			// Concurrent additions never return the reference of another task
+			if ref.Name != name {
+				t.Errorf("Add %s returned the reference of task %s", name, ref.Name)
+			}
+			for _, path := range []string{"/tasks", "/tasks/next"} {
+				if _, err := sendRequest(http.MethodGet, base+path, "", nil); err != nil {
+					t.Error(err)
+				}
+			}
+			if code, err := sendRequest(http.MethodPut, base+"/tasks/"+name, taskJSON(name, "@daily"), nil); err != nil || code != http.StatusOK {
+				t.Errorf("Update %s status: expected %d, found %d (error: %v)", name, http.StatusOK, code, err)
+			}
+			if i%2 == 0 {
+				if code, err := sendRequest(http.MethodDelete, base+"/tasks/"+name, "", nil); err != nil || code != http.StatusNoContent {
+					t.Errorf("Delete %s status: expected %d, found %d (error: %v)", name, http.StatusNoContent, code, err)
+				}
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 4; i++ {
+			for _, path := range []string{"/pause", "/resume"} {
+				if _, err := sendRequest(http.MethodPost, base+path, "", nil); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}()
+	wg.Wait()
+	if len(scheduler.References()) != 4 {
+		t.Errorf("Expected 4 tasks, found %d", len(scheduler.References()))
+	}
+}
+
+func TestClientReportsListErrors(t *testing.T) {
+	server, _ := newTestServer(t)
+	var client = api.NewHTTPClient(strings.TrimPrefix(server.URL, "http://"))
+	if err := client.AddAndPersist(model.CommandConfig{Name: "backup", Schedule: "@hourly",
+		Command: model.CommandSpec{Kind: model.CommandKindShell, Shell: "true"}}); err != nil {
+		t.Fatalf("Unable to add task: %v", err)
+	}
+	if len(client.Planned()) != 1 || len(client.References()) != 1 {
+		t.Fatalf("Expected 1 planned task")
+	}
+	select {
+	case err := <-client.Errors():
+		t.Fatalf("Unexpected error: %v", err)
+	default:
+	}
+	server.Close()
+	if len(client.Planned()) != 0 {
+		t.Errorf("Expected no planned tasks from a stopped daemon")
+	}
+	select {
+	case err := <-client.Errors():
+		if err == nil {
+			t.Errorf("Expected the request error, found nil")
+		}
+	default:
+		t.Errorf("Expected the request error to be reported")
+	}
+	if err := client.DeleteAndPersist(0); err == nil {
+		t.Errorf("Expected the request error from index based operations")
+	}
+}
+
+func TestClientWarnings(t *testing.T) {
+	server, _ := newTestServer(t)
+	var client = api.NewHTTPClient(strings.TrimPrefix(server.URL, "http://"))
+	var warnings = client.Warnings()
+	if warnings == nil || client.Warnings() != warnings {
+		t.Fatalf("Expected the same not nil warnings channel")
+	}
+	var body = `{"name":"backup","command":{"kind":"shell","shell":"true"},"hooks":{"always":{"kind":"shell","shell":"false"}}}`
+	var ref model.CommandConfigRef
+	if code := doRequest(t, http.MethodPost, server.URL+api.BasePath+"/tasks", body, &ref); code != http.StatusCreated {
+		t.Fatalf("Add task status: expected %d, found %d", http.StatusCreated, code)
+	}
+	// The events stream is connected before the run
+	time.Sleep(200 * time.Millisecond)
+	if err := client.Trigger("backup"); err != nil {
+		t.Fatalf("Unable to trigger task: %v", err)
+	}
+	select {
+	case err := <-warnings:
+		var taskErr *model.TaskError
+		if !errors.As(err, &taskErr) || taskErr.UUID != ref.UUID || !strings.Contains(err.Error(), "hook failed") {
+			t.Errorf("Unexpected warning: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the failed hook warning")
+	}
+}
+
+func TestClientWarningsUnreachableDaemon(t *testing.T) {
+	var client = api.NewHTTPClient("127.0.0.1:1")
+	select {
+	case _, open := <-client.Warnings():
+		if open {
+			t.Errorf("Expected no warnings from an unreachable daemon")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the warnings channel to be closed")
+	}
+}
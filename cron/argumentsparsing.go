@@ -4,11 +4,16 @@ import (
 	"flag"
 	"fmt"
 	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/logger"
+	"github.com/hellgate75/go-cron/store"
+	"strings"
 )
 var command string
 var configPath string
 var encoding io.Encoding
 var encodingString string
+var storeKind store.Kind
+var storeString string
 
 var inputFormat string
 var inputFile string
@@ -21,13 +26,50 @@ var (
 	listTo = 1
 )
 
+var (
+	taskId   string
+	taskName string
+)
+
 var details bool
+var nextRuns = 1
 var query string
 var filter string
 var filterFile string
 
 var silent bool
 
+var (
+	historyTask    string
+	historySince   string
+	historyStatus  string
+	historyTrigger string
+	historyLimit   int
+)
+
+var (
+	deliveriesTask  string
+	deliveriesLimit int
+)
+
+var apiAddress string
+
+var metricsAddress string
+
+var (
+	logLevel      string
+	logFormat     string
+	logFile       string
+	logMaxSize    int
+	logMaxAge     string
+	logMaxBackups int
+)
+
+var (
+	triggerWait        bool
+	triggerWaitTimeout string
+)
+
 var nativeGobInFile bool
 var nativeGobOutFormat bool
 
@@ -41,6 +83,7 @@ func DefaultParser(commandString string) *flag.FlagSet {
 	defaultFile, _ := io.GetDefaultConfigFile(defaultEncoding)
 	fl.BoolVar(&silent, "silent", false, "Execute silent command output (explain)")
 	fl.StringVar(&configPath,"path", defaultFile, "Configuration file location")
+	fl.StringVar(&storeString,"store", string(store.KindFile), fmt.Sprintf("Scheduler store, the journal is kept next to the configuration file (available: %s)", store.KindList))
 	return fl
 }
 
@@ -65,7 +108,8 @@ func parse(fl *flag.FlagSet) error {
 		return err
 	}
 	encoding = io.EncodingFromValue(encodingString)
-	return nil
+	storeKind, err = store.KindFromValue(storeString)
+	return err
 }
 
 func getExplainCommandArgsParser() *flag.FlagSet {
@@ -79,18 +123,38 @@ func getExplainCommandArgsParser() *flag.FlagSet {
 }
 
 
+// Adds the arguments used to reach a running daemon
+func clientArgs(fl *flag.FlagSet) {
+	fl.StringVar(&apiAddress, "api", "", "Send the command to the daemon HTTP control API on given address, default is the daemon unix domain socket")
+}
+
 func getDaemonCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("daemon")
+	fl.StringVar(&apiAddress, "api", "", "Enable the HTTP control API on given loopback address (e.g.: 127.0.0.1:8480), empty for no API")
+	fl.StringVar(&metricsAddress, "metrics", "", "Enable the Prometheus metrics endpoint (/metrics) on given address (e.g.: :9480), empty for no metrics")
+	loggerArgs(fl)
 	return fl
 }
 
 func getOnceCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("explain")
+	loggerArgs(fl)
 	return fl
 }
 
+// Adds the scheduler logger arguments of the daemon and once modes
+func loggerArgs(fl *flag.FlagSet) {
+	fl.StringVar(&logLevel, "log-level", "info", fmt.Sprintf("Minimum level of the logged entries (available: %s)", strings.Join(logger.LevelList, ", ")))
+	fl.StringVar(&logFormat, "log-format", string(logger.FormatText), fmt.Sprintf("Log entries format (available: %s)", strings.Join(logger.FormatList, ", ")))
+	fl.StringVar(&logFile, "log-file", "", "Log file location, empty for the standard output")
+	fl.IntVar(&logMaxSize, "log-max-size", 0, "Log file size in megabytes that triggers the file rotation (0 for no size rotation)")
+	fl.StringVar(&logMaxAge, "log-max-age", "", "Log file age, as Go duration (e.g.: 24h), that triggers the file rotation, empty for no age rotation")
+	fl.IntVar(&logMaxBackups, "log-max-backups", 0, "Number of rotated log files kept (0 to keep all of them)")
+}
+
 func getAddCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("add")
+	clientArgs(fl)
 	fl.StringVar(&inputFormat,"in-form", io.DefaultEncodingFormatString, fmt.Sprintf("Input encoding format (available: %s)", io.EncodingList))
 	fl.StringVar(&inputFile, "in-file", "", "Input file absolute path")
 	fl.StringVar(&inputText,"in-text", "", "Input text value")
@@ -102,7 +166,10 @@ func getAddCommandArgsParser() *flag.FlagSet {
 
 func getRemoveCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("remove")
-	fl.IntVar(&listIndex,"index", listIndex, "Output list raw line number to be deleted")
+	clientArgs(fl)
+	fl.StringVar(&taskId, "id", "", "Unique identifier of the task to be deleted")
+	fl.StringVar(&taskName, "name", "", "Name of the task to be deleted")
+	fl.IntVar(&listIndex,"index", listIndex, "Output list raw line number to be deleted (legacy, prefer id or name)")
 	fl.IntVar(&listFrom,"from", listFrom, "Output list raw first line number to be deleted")
 	fl.IntVar(&listTo,"to", listTo, "Output list raw last line number to be deleted")
 	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
@@ -112,29 +179,35 @@ func getRemoveCommandArgsParser() *flag.FlagSet {
 
 func getUpdateCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("update")
+	clientArgs(fl)
 	fl.StringVar(&inputFormat,"in-form", io.DefaultEncodingFormatString, fmt.Sprintf("Input encoding format (available: %s)", io.EncodingList))
 	fl.StringVar(&inputFile, "in-file", "", "Input file absolute path")
 	fl.StringVar(&inputText,"in-text", "", "Input text value")
 	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
 	fl.BoolVar(&nativeGobInFile, "native-in", false, "Use native Gob file for input")
 	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
-	fl.IntVar(&listIndex,"index", listIndex, "Output list raw line number of change/replacement")
+	fl.StringVar(&taskId, "id", "", "Unique identifier of the task to be changed/replaced")
+	fl.StringVar(&taskName, "name", "", "Name of the task to be changed/replaced")
+	fl.IntVar(&listIndex,"index", listIndex, "Output list raw line number of change/replacement (legacy, prefer id or name)")
 	return fl
 }
 
 func getListCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("list")
+	clientArgs(fl)
 	fl.BoolVar(&details, "details", false, "Show details for each scheduler next execution processes, in the requested encoding format")
 	fl.StringVar(&query, "query", "", "Comma separated <column name>=<value> keys")
 	fl.StringVar(&filter, "filter", "", "Go style template output filter template text")
 	fl.StringVar(&filterFile, "filter-file", "", "Go style template output filter template template")
 	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
 	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	fl.IntVar(&nextRuns, "runs", nextRuns, "Number of next fire times reported for each task")
 	return fl
 }
 
 func getActiveCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("active")
+	clientArgs(fl)
 	fl.BoolVar(&details, "details", false, "Show details for each scheduler next execution processes, in the requested encoding format")
 	fl.StringVar(&query, "query", "", "Comma separated <column name>=<value> keys")
 	fl.StringVar(&filter, "filter", "", "Go style template output filter template text")
@@ -146,12 +219,92 @@ func getActiveCommandArgsParser() *flag.FlagSet {
 
 func getNextCommandArgsParser() *flag.FlagSet {
 	var fl  = DefaultParser("next")
+	clientArgs(fl)
 	fl.BoolVar(&details, "details", false, "Show details for each scheduler next execution processes, in the requested encoding format")
 	fl.StringVar(&query, "query", "", "Comma separated <column name>=<value> keys")
 	fl.StringVar(&filter, "filter", "", "Go style template output filter template text")
 	fl.StringVar(&filterFile, "filter-file", "", "Go style template output filter template template")
 	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
 	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	fl.IntVar(&nextRuns, "runs", nextRuns, "Number of next fire times reported for each task")
+	return fl
+}
+
+func getDeliveriesCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("deliveries")
+	clientArgs(fl)
+	fl.StringVar(&deliveriesTask, "uuid", "", "Show only the webhook deliveries of the task with given unique identifier")
+	fl.IntVar(&deliveriesLimit, "limit", 0, "Maximum number of most recent deliveries reported, 0 for all")
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
+
+func getHistoryCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("history")
+	clientArgs(fl)
+	fl.BoolVar(&details, "details", false, "Show details for each run record, including the command output, in the requested encoding format")
+	fl.StringVar(&historyTask, "uuid", "", "Show only the runs of the task with given unique identifier")
+	fl.StringVar(&historySince, "since", "", "Show only the runs started since given time (RFC3339) or since given Go duration ago (e.g.: 24h)")
+	fl.StringVar(&historyStatus, "status", "", "Show only the runs with given status (available: success, failure, timeout, cancelled)")
+	fl.StringVar(&historyTrigger, "trigger", "", "Show only the runs with given trigger source (available: schedule, retry, manual, dependency)")
+	fl.IntVar(&historyLimit, "limit", 0, "Maximum number of most recent runs reported, 0 for all")
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
+
+func getPauseCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("pause")
+	clientArgs(fl)
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
+
+func getResumeCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("resume")
+	clientArgs(fl)
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
+
+func getEnableCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("enable")
+	clientArgs(fl)
+	fl.StringVar(&taskId, "id", "", "Unique identifier of the task to be enabled")
+	fl.StringVar(&taskName, "name", "", "Name of the task to be enabled")
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
+
+func getDisableCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("disable")
+	clientArgs(fl)
+	fl.StringVar(&taskId, "id", "", "Unique identifier of the task to be disabled")
+	fl.StringVar(&taskName, "name", "", "Name of the task to be disabled")
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
 	return fl
 }
 
+func getTriggerCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("trigger")
+	clientArgs(fl)
+	fl.StringVar(&taskId, "id", "", "Unique identifier of the task to be run")
+	fl.StringVar(&taskName, "name", "", "Name of the task to be run")
+	fl.BoolVar(&triggerWait, "wait", false, "Wait for the run completion and show its output and exit status (always true when no daemon is running)")
+	fl.StringVar(&triggerWaitTimeout, "wait-timeout", "", "Maximum wait time, as Go duration (e.g.: 5m), empty for no limit")
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
+
+func getMigrateCommandArgsParser() *flag.FlagSet {
+	var fl  = DefaultParser("migrate")
+	fl.StringVar(&outputFormat, "out-form", io.DefaultEncodingFormatString, fmt.Sprintf("Output encoding format (available: text, %s)", io.EncodingList))
+	fl.BoolVar(&nativeGobOutFormat, "native-out", false, "Use native Gob format for output")
+	return fl
+}
@@ -1,16 +1,25 @@
 package cron
 
 import (
+	"context"
+	"encoding/xml"
 	"errors"
 	"fmt"
+	"github.com/hellgate75/go-cron/api"
 	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/logger"
 	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"github.com/hellgate75/go-cron/utils"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 )
 
-var Commands = []string{"help", "explain", "daemon", "once", "add", "remove", "update", "list", "active", "next"}
+var Commands = []string{"help", "explain", "daemon", "once", "add", "remove", "update", "list", "active", "next", "history", "deliveries", "pause", "resume", "enable", "disable", "trigger", "run", "migrate"}
 
 func header() string {
 	return "[" + time.Now().String() + " LOG ] "
@@ -30,20 +39,20 @@ func LogText(s string) {
 
 func LogMany(format string, d ...interface{}) {
 	if silent {
-		fmt.Printf(format, d)
+		fmt.Printf(format, d...)
 	} else {
-		fmt.Printf("%s%s\n", header(), fmt.Sprintf(format, d))
+		fmt.Printf("%s%s\n", header(), fmt.Sprintf(format, d...))
 	}
 }
 
 
 func LogResponse(err error, message string, out interface{}) {
 	var response = struct {
-		Error	error				`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+		Error	string				`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
 		Message string				`yaml:"message,omitempty" json:"message,omitempty" xml:"message,omitempty"`
 		Content	interface{}			`yaml:"content,omitempty" json:"content,omitempty" xml:"content,omitempty"`
 	}{
-		err,
+		errorText(err),
 		message,
 		out,
 	}
@@ -61,11 +70,53 @@ func LogResponse(err error, message string, out interface{}) {
 	LogText(string(data))
 }
 
+// Describes a not nil error as text
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+// Describes the workflow state as number of satisfied upstream dependencies, empty when there are no dependencies
+func upstreamSummary(states []model.DependencyState) string {
+	if len(states) == 0 {
+		return ""
+	}
+	var satisfied int
+	for _, d := range states {
+		if d.Satisfied {
+			satisfied++
+		}
+	}
+	return fmt.Sprintf("%d/%d", satisfied, len(states))
+}
+
+// Retrieves the task key given by the id or name arguments, empty when the list index must be used
+func taskKey() string {
+	if taskId != "" {
+		return taskId
+	}
+	return taskName
+}
+
+// Retrieves the reference of the planned task at given list line. Planned tasks list the cached tasks before
+// the persisted ones, that are the only ones with a reference
+func plannedReference(refs []model.CommandConfigRef, line int, total int) model.CommandConfigRef {
+	var idx = line - (total - len(refs))
+	if idx >= 0 && idx < len(refs) {
+		return refs[idx]
+	}
+	return model.CommandConfigRef{}
+}
+
 func LogListResponse(message string, out interface{}) {
 	var response = struct {
+		XMLName		xml.Name			`yaml:"-" json:"-" xml:"response"`
 		Title 		string				`yaml:"title,omitempty" json:"title,omitempty" xml:"title,omitempty"`
-		Response	interface{}			`yaml:"response,omitempty" json:"response,omitempty" xml:"response,omitempty"`
+		Response	interface{}			`yaml:"response,omitempty" json:"response,omitempty" xml:"item,omitempty"`
 	}{
+		xml.Name{},
 		fmt.Sprintf("List of %s\n", message),
 		out,
 	}
@@ -86,8 +137,209 @@ func LogListResponse(message string, out interface{}) {
 	LogText(string(data))
 }
 
+// Expresses a non zero time in the given location
+func localTime(t time.Time, loc *time.Location) time.Time {
+	if t.IsZero() {
+		return t
+	}
+	return t.In(loc)
+}
+
+// Retrieves the control API unix domain socket of the daemon running with the given configuration file
+func daemonSocketPath(path string) string {
+	return strings.TrimSuffix(path, filepath.Ext(path)) + ".sock"
+}
+
+// Connects the daemon running with the configuration file, using the HTTP control API when the api argument is
+// given, or the daemon unix domain socket. It returns nil when no daemon is reachable
+func connectDaemon() *api.Client {
+	var client *api.Client
+	if apiAddress != "" {
+		client = api.NewHTTPClient(apiAddress)
+	} else if configPath != "" && io.FileExists(daemonSocketPath(configPath)) {
+		client = api.NewUnixClient(daemonSocketPath(configPath))
+	} else {
+		return nil
+	}
+	if _, err := client.Status(); err != nil {
+		return nil
+	}
+	return client
+}
+
+// Time the commands wait for the other processes to release the scheduler folder
+const storeLockWait = 10 * time.Second
+
+// Lock of the scheduler folder, held until the command ends
+var storeLock *io.FolderLock
+
+// Acquires the lock of the configuration file folder, so the daemon and the commands changing the files
+// directly never share them
+func lockStore() error {
+	if storeLock != nil {
+		return nil
+	}
+	var dir = filepath.Dir(configPath)
+	var err = io.CreateFolder(dir, io.FolderMode)
+	if err == nil {
+		storeLock, err = io.LockFolder(dir, storeLockWait)
+	}
+	return err
+}
+
+// Releases the scheduler folder lock
+func unlockStore() {
+	if storeLock != nil {
+		_ = storeLock.Unlock()
+		storeLock = nil
+	}
+}
+
+// Opens the scheduler store given by the command line arguments, holding the scheduler folder lock. The
+// journal store keeps the journal file next to the configuration file, with the journal extension
+func openStore() (store.Store, error) {
+	var path = configPath
+	if storeKind == store.KindJournal {
+		path = strings.TrimSuffix(configPath, filepath.Ext(configPath)) + ".journal"
+	}
+	if storeKind != store.KindMemory {
+		if err := lockStore(); err != nil {
+			return nil, err
+		}
+	}
+	return store.Open(storeKind, path, encoding)
+}
+
+// Opens the scheduler of the daemon running with the configuration file, so the daemon applies the operations,
+// or it loads the scheduler from the store when no daemon is reachable
+func openScheduler() (model.Scheduler, error) {
+	if client := connectDaemon(); client != nil {
+		return client, nil
+	}
+	st, err := openStore()
+	if err != nil {
+		return nil, err
+	}
+	sc, err := LoadSchedulerFromStore(st, true)
+	if err != nil {
+		return nil, err
+	}
+	// Functions are registered by the daemon or the embedding program, not by the command line
+	sc.(*scheduler).remoteFunctions = true
+	return sc, nil
+}
+
+// Retrieves the error of the daemon requests that cannot return it, like the tasks lists, when the scheduler is
+// a daemon client. Local schedulers errors are reported by the scheduler events
+func daemonError(scheduler model.Scheduler) error {
+	if client, ok := scheduler.(*api.Client); ok {
+		select {
+		case err := <-client.Errors():
+			return err
+		default:
+		}
+	}
+	return nil
+}
+
+var schedulerLogger logger.Logger
+
+// Sets the logger of the daemon and once modes, replacing the one configured by the command line arguments
+func SetLogger(l logger.Logger) {
+	schedulerLogger = l
+}
+
+// Creates the daemon and once modes logger from the command line arguments, unless it has been set, and
+// returns the function releasing it
+func openLogger() (func(), error) {
+	if schedulerLogger != nil {
+		return func() {}, nil
+	}
+	level, err := logger.ParseLevel(logLevel)
+	if err != nil {
+		return nil, err
+	}
+	format, err := logger.ParseFormat(logFormat)
+	if err != nil {
+		return nil, err
+	}
+	if silent && format == logger.FormatText {
+		format = logger.FormatPlain
+	}
+	if logFile == "" {
+		schedulerLogger = logger.New(os.Stdout, level, format)
+		return func() {}, nil
+	}
+	var maxAge time.Duration
+	if logMaxAge != "" {
+		if maxAge, err = time.ParseDuration(logMaxAge); err != nil || maxAge <= 0 {
+			return nil, errors.New(fmt.Sprintf("Invalid log max age '%s', it must be a positive duration (e.g.: 24h)", logMaxAge))
+		}
+	}
+	file, err := logger.NewRotatingFile(logFile, int64(logMaxSize)*1024*1024, maxAge, logMaxBackups)
+	if err != nil {
+		return nil, err
+	}
+	schedulerLogger = logger.New(file, level, format)
+	return func() {
+		_ = file.Close()
+	}, nil
+}
+
+// Collects the log fields of a scheduler event: task, run and outcome details when declared
+func eventFields(event model.Event) logger.Fields {
+	var fields = logger.Fields{"event": string(event.Type)}
+	for key, value := range map[string]string{"task": event.UUID, "name": event.Name, "run": event.RunID,
+		"trigger": string(event.Trigger), "outcome": string(event.Outcome)} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	if event.Attempt > 0 {
+		fields["attempt"] = event.Attempt
+	}
+	if !event.Next.IsZero() {
+		fields["next"] = event.Next.Format(time.RFC3339)
+	}
+	if event.Outcome != "" {
+		fields["exitCode"] = event.ExitCode
+		fields["duration"] = event.Duration.String()
+	}
+	return fields
+}
+
+// Logs the scheduler events, with the given fields on the scheduler started and stopped events, until the returned
+// function is called. The returned function logs the events still buffered and the number of the lost ones
+func logSchedulerEvents(scheduler model.Scheduler, fields logger.Fields) func() {
+	var subscription = scheduler.Subscribe(4 * model.DefaultEventBuffer)
+	var finished = make(chan struct{})
+	go func() {
+		defer close(finished)
+		var dropped uint64
+		for event := range subscription.Events() {
+			if lost := subscription.Dropped(); lost > dropped {
+				logger.Warn(schedulerLogger, fmt.Sprintf("Lost %d scheduler events, the logger is too slow", lost-dropped), nil)
+				dropped = lost
+			}
+			var entryFields = eventFields(event)
+			if event.Type == model.EventSchedulerStarted || event.Type == model.EventSchedulerStopped {
+				for key, value := range fields {
+					entryFields[key] = value
+				}
+			}
+			level, _ := logger.ParseLevel(event.Level)
+			logger.Log(schedulerLogger, level, event.Message, entryFields)
+		}
+	}()
+	return func() {
+		subscription.Close()
+		<-finished
+	}
+}
+
 func Exec(command string) error {
 	var err error
+	defer unlockStore()
 	switch command {
 	case "daemon":
 		return executeDaemonCommand()
@@ -105,6 +357,22 @@ func Exec(command string) error {
 		return executeActiveCommand(true)
 	case "next":
 		return executeNextCommand(true)
+	case "history":
+		return executeHistoryCommand(true)
+	case "deliveries":
+		return executeDeliveriesCommand(true)
+	case "pause":
+		return executePauseCommand()
+	case "resume":
+		return executeResumeCommand()
+	case "enable":
+		return executeEnableCommand()
+	case "disable":
+		return executeDisableCommand()
+	case "trigger", "run":
+		return executeTriggerCommand()
+	case "migrate":
+		return executeMigrateCommand()
 	default:
 		LogMany("Cannot describe unknown command: <%s>\n", command)
 		LogMany("Available commands: %v\n", Commands)
@@ -119,33 +387,85 @@ func executeDaemonCommand() error {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
 	}()
-	//TODO: Implement logger for the daemon
 	err = parse(getDaemonCommandArgsParser())
 	if err != nil {
 		return err
 	}
-	var scheduler model.Scheduler
+	closeLogger, err := openLogger()
+	if err != nil {
+		return err
+	}
+	defer closeLogger()
+	var emptyScheduler = configPath == ""
 	if configPath == "" {
 		configPath = fmt.Sprintf("%s%c%s/%s.%s", io.HomeFolder(), os.PathSeparator, ",go-cron", "scheduler", encoding.String())
-		scheduler, err = NewEmptyScheduler(configPath, encoding, true)
-		if err != nil {
-			return err
-		}
+	}
+	st, err := openStore()
+	if err != nil {
+		return err
+	}
+	var scheduler = newScheduler(st, true)
+	var fields = logger.Fields{"config": configPath}
+	if apiAddress != "" {
+		fields["api"] = apiAddress
+	}
+	if metricsAddress != "" {
+		fields["metrics"] = metricsAddress
+	}
+	// Events are logged before loading the scheduler, to report the recovered files
+	var stopLogging = logSchedulerEvents(scheduler, fields)
+	defer stopLogging()
+	if emptyScheduler {
+		err = scheduler.save()
 	} else {
-		scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
-		if err != nil {
-			return err
-		}
 		err = scheduler.Load()
-		if err != nil {
-			return err
-		}
 	}
+	if err != nil {
+		return err
+	}
+	// Control API is always available to the command line clients on the daemon unix domain socket
+	var apiServer = api.NewServer(scheduler)
 	err = scheduler.Start()
 	if err != nil {
 		return err
 	}
+	if scheduler.State() == model.SchedulerStatePaused {
+		logger.Warn(schedulerLogger, "Scheduler is paused, new runs are scheduled after the resume command", nil)
+	}
+	var socketPath = daemonSocketPath(configPath)
+	go func() {
+		if errA := apiServer.ListenAndServeUnix(socketPath); errA != nil {
+			logger.Error(schedulerLogger, fmt.Sprintf("Unable to start control API on socket %s, error: %v", socketPath, errA), logger.Fields{"socket": socketPath})
+		}
+	}()
+	if apiAddress != "" {
+		go func() {
+			if errA := apiServer.ListenAndServe(apiAddress); errA != nil {
+				logger.Error(schedulerLogger, fmt.Sprintf("Unable to start control API on %s, error: %v", apiAddress, errA), logger.Fields{"api": apiAddress})
+			}
+		}()
+	}
+	if metricsAddress != "" {
+		go func() {
+			if errM := apiServer.ListenAndServeMetrics(metricsAddress); errM != nil {
+				logger.Error(schedulerLogger, fmt.Sprintf("Unable to start metrics endpoint on %s, error: %v", metricsAddress, errM), logger.Fields{"metrics": metricsAddress})
+			}
+		}()
+	}
+	defer func() {
+		_ = apiServer.Shutdown(context.Background())
+		_ = os.Remove(socketPath)
+	}()
+	// Stop gracefully on interrupt, giving running tasks the stop grace period to complete
+	var signals = make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		logger.Info(schedulerLogger, fmt.Sprintf("Received signal %v, stopping scheduler ...", sig), nil)
+		_ = scheduler.Stop()
+	}()
 	scheduler.Wait()
+	signal.Stop(signals)
 	return err
 }
 
@@ -157,27 +477,34 @@ func executeOnceCommand() error {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
 	}()
-	//TODO: Implement logger for the once execution
 	err = parse(getOnceCommandArgsParser())
 	if err != nil {
 		return err
 	}
-	var scheduler model.Scheduler
+	closeLogger, err := openLogger()
+	if err != nil {
+		return err
+	}
+	defer closeLogger()
+	var emptyScheduler = configPath == ""
 	if configPath == "" {
 		configPath = fmt.Sprintf("%s%c%s/%s.%s", io.HomeFolder(), os.PathSeparator, ",go-cron", "scheduler", encoding.String())
-		scheduler, err = NewEmptyScheduler(configPath, encoding, false)
-		if err != nil {
-			return err
-		}
+	}
+	st, err := openStore()
+	if err != nil {
+		return err
+	}
+	var scheduler = newScheduler(st, false)
+	// Events are logged before loading the scheduler, to report the recovered files
+	var stopLogging = logSchedulerEvents(scheduler, logger.Fields{"config": configPath})
+	defer stopLogging()
+	if emptyScheduler {
+		err = scheduler.save()
 	} else {
-		scheduler, err = LoadSchedulerFrom(configPath, encoding, false)
-		if err != nil {
-			return err
-		}
 		err = scheduler.Load()
-		if err != nil {
-			return err
-		}
+	}
+	if err != nil {
+		return err
 	}
 	err = scheduler.RunOnce()
 	if err != nil {
@@ -224,11 +551,7 @@ func executeAddCommand() error {
 			}
 		}
 		if err == nil {
-			scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
-			if err != nil {
-				return err
-			}
-			err = scheduler.Load()
+			scheduler, err = openScheduler()
 			if err != nil {
 				return err
 			}
@@ -255,39 +578,45 @@ func executeRemoveCommand() error {
 	if configPath == ""  || encoding.String() == "" {
 		err = errors.New(fmt.Sprint("Invalid parameters"))
 	} else {
-		scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
-		if err != nil {
-			return err
-		}
-		err = scheduler.Load()
+		scheduler, err = openScheduler()
 		if err != nil {
 			return err
 		}
 
-		if listFrom != listTo {
-			if listFrom > listTo {
-				var outRes = make([]interface{}, 0)
-				//	Items range removal
-				for i := listFrom; i <= listTo; i++ {
-					//	Single item removal
-					err = scheduler.DeleteAndPersist(i)
-					outRes = append(outRes, struct{
-						Index	int					`yaml:"index,omitempty" json:"index,omitempty" xml:"index,omitempty"`
-						Error 	error				`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
-					}{
-						i,
-						err,
-					})
-				}
-				LogResponse(err, "Removing command in range of indexes", outRes)
-			} else {
-				//	Items range removal
-				for i := listTo; i <= listFrom; i++ {
-					//	Single item removal
-					err = scheduler.DeleteAndPersist(i)
-					LogResponse(err, "Removing command at index", fmt.Sprintf("Removing item at index %v", i))
+		if key := taskKey(); key != "" {
+			//	Single item removal by unique identifier or name
+			err = scheduler.Delete(key)
+			LogResponse(err, "Removing command", fmt.Sprintf("Removing item %s", key))
+		} else if listFrom != listTo {
+			var from, to = listFrom, listTo
+			if from > to {
+				from, to = to, from
+			}
+			//	Items range removal, the indexes are resolved before any removal shifts them
+			var refs = scheduler.References()
+			if err = daemonError(scheduler); err != nil {
+				return err
+			}
+			var outRes = make([]interface{}, 0)
+			for i := from; i <= to; i++ {
+				var id string
+				if i >= 0 && i < len(refs) {
+					id = refs[i].UUID
+					err = scheduler.Delete(id)
+				} else {
+					err = errors.New(fmt.Sprintf("Index out of bound: %v, must be 0 <= x < %v ", i, len(refs)))
 				}
+				outRes = append(outRes, struct{
+					Index	int					`yaml:"index" json:"index" xml:"index"`
+					UUID	string				`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+					Error 	string				`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+				}{
+					i,
+					id,
+					errorText(err),
+				})
 			}
+			LogResponse(nil, "Removing command in range of indexes", outRes)
 		} else {
 			//	Single item removal
 			err = scheduler.DeleteAndPersist(listIndex)
@@ -337,16 +666,17 @@ func executeUpdateCommand() error {
 			}
 		}
 		if err == nil {
-			scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
+			scheduler, err = openScheduler()
 			if err != nil {
 				return err
 			}
-			err = scheduler.Load()
-			if err != nil {
-				return err
+			if key := taskKey(); key != "" {
+				err = scheduler.Update(key, inputCommand)
+				LogResponse(err, fmt.Sprintf("Update command %s", key), inputCommand)
+			} else {
+				err = scheduler.UpdateAndPersist(inputCommand, listIndex)
+				LogResponse(err, fmt.Sprintf("Update command at index %v", listIndex), inputCommand)
 			}
-			err = scheduler.UpdateAndPersist(inputCommand, listIndex)
-			LogResponse(err, fmt.Sprintf("Update command at index %v", listIndex), inputCommand)
 			err = nil
 		}
 	}
@@ -372,16 +702,19 @@ func executeListCommand(parseArgs bool, configList ...model.CommandConfig) error
 		err = errors.New(fmt.Sprint("Invalid parameters"))
 	} else {
 		var list = make([]model.CommandConfig, 0)
+		var refs = make([]model.CommandConfigRef, 0)
+		var location = time.Local
 		if len(configList) == 0 {
-			scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
+			scheduler, err = openScheduler()
 			if err != nil {
 				return err
 			}
-			err = scheduler.Load()
-			if err != nil {
+			list = scheduler.Planned()
+			refs = scheduler.References()
+			if err = daemonError(scheduler); err != nil {
 				return err
 			}
-			list = scheduler.Planned()
+			location = scheduler.Location()
 		} else {
 			list = configList
 		}
@@ -391,10 +724,14 @@ func executeListCommand(parseArgs bool, configList ...model.CommandConfig) error
 			for idx, r := range list {
 				newList = append(newList, struct{
 					Line		int					`yaml:"line,omitempty" json:"line,omitempty" xml:"line,omitempty"`
+					UUID		string				`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
 					Command		model.CommandConfig `yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					NextRuns	[]time.Time			`yaml:"nextRuns,omitempty" json:"nextRuns,omitempty" xml:"next-run,omitempty"`
 				}{
 					idx,
+					plannedReference(refs, idx, len(list)).UUID,
 					r,
+					r.NextRuns(utils.NowIn(r.Location(location)), nextRuns),
 				})
 			}
 			LogListResponse("Planned Tasks", newList)
@@ -404,10 +741,18 @@ func executeListCommand(parseArgs bool, configList ...model.CommandConfig) error
 				cmd := r.Command
 				newList = append(newList, struct{
 					Line		int					`yaml:"line,omitempty" json:"line,omitempty" xml:"line,omitempty"`
-					Command		model.CommandValue `yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					UUID		string				`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+					Name		string				`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+					Enabled		bool				`yaml:"enabled" json:"enabled" xml:"enabled"`
+					Command		model.CommandSpec	`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					NextRuns	[]time.Time			`yaml:"nextRuns,omitempty" json:"nextRuns,omitempty" xml:"next-run,omitempty"`
 				}{
 					idx,
+					plannedReference(refs, idx, len(list)).UUID,
+					r.Name,
+					r.IsEnabled(),
 					cmd,
+					r.NextRuns(utils.NowIn(r.Location(location)), nextRuns),
 				})
 			}
 			LogListResponse("Planned Tasks", newList)
@@ -435,15 +780,14 @@ func executeActiveCommand(parseArgs bool, configList ...model.Execution) error {
 	} else {
 		var list = make([]model.Execution, 0)
 		if len(configList) == 0 {
-			scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
+			scheduler, err = openScheduler()
 			if err != nil {
 				return err
 			}
-			err = scheduler.Load()
-			if err != nil {
+			list = scheduler.Running()
+			if err = daemonError(scheduler); err != nil {
 				return err
 			}
-			list = scheduler.Running()
 		} else {
 			list = configList
 		}
@@ -458,14 +802,32 @@ func executeActiveCommand(parseArgs bool, configList ...model.Execution) error {
 					Scheduled	bool				 `yaml:"isScheduled,omitempty" json:"isScheduled,omitempty" xml:"is-scheduled,omitempty"`
 					NextExec	time.Time			 `yaml:"nextExecution,omitempty" json:"nextExecution,omitempty" xml:"next-execution,omitempty"`
 					NoRuns		int					 `yaml:"numberOfExecutions,omitempty" json:"numberOfExecutions,omitempty" xml:"number-of-execution,omitempty"`
+					Policy		model.ConcurrencyPolicy	`yaml:"concurrency,omitempty" json:"concurrency,omitempty" xml:"concurrency,omitempty"`
+					Running		int					 `yaml:"running,omitempty" json:"running,omitempty" xml:"running,omitempty"`
+					Queued		int					 `yaml:"queued,omitempty" json:"queued,omitempty" xml:"queued,omitempty"`
+					Skipped		int					 `yaml:"skipped,omitempty" json:"skipped,omitempty" xml:"skipped,omitempty"`
+					Triggered	int					 `yaml:"triggered,omitempty" json:"triggered,omitempty" xml:"triggered,omitempty"`
+					LastTrigger	time.Time			 `yaml:"lastTriggered,omitempty" json:"lastTriggered,omitempty" xml:"last-triggered,omitempty"`
+					Workflow	int					 `yaml:"workflowRuns,omitempty" json:"workflowRuns,omitempty" xml:"workflow-runs,omitempty"`
+					LastWorkflow	time.Time		 `yaml:"lastWorkflowRun,omitempty" json:"lastWorkflowRun,omitempty" xml:"last-workflow-run,omitempty"`
+					Upstream	[]model.DependencyState `yaml:"dependencies,omitempty" json:"dependencies,omitempty" xml:"dependency,omitempty"`
 					Command		model.CommandConfig  `yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
 				}{
 					idx,
 					r.UUID,
-					r.Last,
+					localTime(r.Last, r.Location()),
 					r.Scheduled,
-					r.Next,
+					localTime(r.Next, r.Location()),
 					r.Times,
+					r.Command.Policy(),
+					r.Running,
+					r.Queued,
+					r.Skipped,
+					r.Triggered,
+					localTime(r.LastTriggered, r.Location()),
+					r.WorkflowRuns,
+					localTime(r.LastWorkflowRun, r.Location()),
+					r.Dependencies,
 					r.Command,
 				})
 			}
@@ -479,12 +841,24 @@ func executeActiveCommand(parseArgs bool, configList ...model.Execution) error {
 					Uuid		string				 `yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
 					LastExec	time.Time			 `yaml:"lastExecution,omitempty" json:"lastExecution,omitempty" xml:"last-execution,omitempty"`
 					Scheduled	bool				 `yaml:"isScheduled,omitempty" json:"isScheduled,omitempty" xml:"is-scheduled,omitempty"`
-					Command		model.CommandValue `yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					Running		int					 `yaml:"running,omitempty" json:"running,omitempty" xml:"running,omitempty"`
+					Queued		int					 `yaml:"queued,omitempty" json:"queued,omitempty" xml:"queued,omitempty"`
+					Skipped		int					 `yaml:"skipped,omitempty" json:"skipped,omitempty" xml:"skipped,omitempty"`
+					Triggered	int					 `yaml:"triggered,omitempty" json:"triggered,omitempty" xml:"triggered,omitempty"`
+					Workflow	int					 `yaml:"workflowRuns,omitempty" json:"workflowRuns,omitempty" xml:"workflow-runs,omitempty"`
+					Upstream	string				 `yaml:"upstream,omitempty" json:"upstream,omitempty" xml:"upstream,omitempty"`
+					Command		model.CommandSpec	`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
 				}{
 					idx,
 					r.UUID,
-					r.Last,
+					localTime(r.Last, r.Location()),
 					r.Scheduled,
+					r.Running,
+					r.Queued,
+					r.Skipped,
+					r.Triggered,
+					r.WorkflowRuns,
+					upstreamSummary(r.Dependencies),
 					cmd,
 				})
 			}
@@ -513,15 +887,14 @@ func executeNextCommand(parseArgs bool, configList ...model.Execution) error {
 	} else {
 		var list = make([]model.Execution, 0)
 		if len(configList) == 0 {
-			scheduler, err = LoadSchedulerFrom(configPath, encoding, true)
+			scheduler, err = openScheduler()
 			if err != nil {
 				return err
 			}
-			err = scheduler.Load()
-			if err != nil {
+			list = scheduler.NextRunningTasks()
+			if err = daemonError(scheduler); err != nil {
 				return err
 			}
-			list = scheduler.NextRunningTasks()
 		} else {
 			list = configList
 		}
@@ -537,14 +910,16 @@ func executeNextCommand(parseArgs bool, configList ...model.Execution) error {
 					NextExec	time.Time			 `yaml:"nextExecution,omitempty" json:"nextExecution,omitempty" xml:"next-execution,omitempty"`
 					NoRuns		int					 `yaml:"numberOfExecutions,omitempty" json:"numberOfExecutions,omitempty" xml:"number-of-execution,omitempty"`
 					Command		model.CommandConfig  `yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					NextRuns	[]time.Time			 `yaml:"nextRuns,omitempty" json:"nextRuns,omitempty" xml:"next-run,omitempty"`
 				}{
 					idx,
 					r.UUID,
-					r.Last,
+					localTime(r.Last, r.Location()),
 					r.Scheduled,
-					r.Next,
+					localTime(r.Next, r.Location()),
 					r.Times,
 					r.Command,
+					r.NextRuns(nextRuns),
 				})
 			}
 			LogListResponse("Next Execution Tasks", newList)
@@ -557,13 +932,15 @@ func executeNextCommand(parseArgs bool, configList ...model.Execution) error {
 					Uuid		string				 `yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
 					LastExec	time.Time			 `yaml:"lastExecution,omitempty" json:"lastExecution,omitempty" xml:"last-execution,omitempty"`
 					Scheduled	bool				 `yaml:"isScheduled,omitempty" json:"isScheduled,omitempty" xml:"is-scheduled,omitempty"`
-					Command		model.CommandValue `yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					Command		model.CommandSpec	`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+					NextRuns	[]time.Time			`yaml:"nextRuns,omitempty" json:"nextRuns,omitempty" xml:"next-run,omitempty"`
 				}{
 					idx,
 					r.UUID,
-					r.Last,
+					localTime(r.Last, r.Location()),
 					r.Scheduled,
 					cmd,
+					r.NextRuns(nextRuns),
 				})
 			}
 			LogListResponse("Next Execution Tasks", newList)
@@ -571,3 +948,377 @@ func executeNextCommand(parseArgs bool, configList ...model.Execution) error {
 	}
 	return err
 }
+
+// Parses the history since argument, as RFC3339 time or Go duration before now
+func parseHistorySince(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, errors.New(fmt.Sprintf("Invalid since value '%s', expected RFC3339 time or Go duration", value))
+	}
+	return t, nil
+}
+
+func executeHistoryCommand(parseArgs bool, configList ...model.RunRecord) error {
+	var err error
+	var scheduler model.Scheduler
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	if parseArgs {
+		err = parse(getHistoryCommandArgsParser())
+		if err != nil {
+			return err
+		}
+	}
+	if len(configList) == 0 && (configPath == ""  || encoding.String() == "") {
+		err = errors.New(fmt.Sprint("Invalid parameters"))
+	} else {
+		var filter = model.HistoryFilter{
+			UUID:    historyTask,
+			Status:  historyStatus,
+			Trigger: model.TriggerSource(historyTrigger),
+			Limit:   historyLimit,
+		}
+		filter.Since, err = parseHistorySince(historySince)
+		if err != nil {
+			return err
+		}
+		var list = make([]model.RunRecord, 0)
+		if len(configList) == 0 {
+			scheduler, err = openScheduler()
+			if err != nil {
+				return err
+			}
+			list = scheduler.History(filter)
+			if err = daemonError(scheduler); err != nil {
+				return err
+			}
+		} else {
+			list = filter.Apply(configList)
+		}
+		if details {
+			var newList = make([]interface{}, 0)
+			for idx, r := range list {
+				newList = append(newList, struct{
+					Line		int					 `yaml:"line,omitempty" json:"line,omitempty" xml:"line,omitempty"`
+					Run			model.RunRecord		 `yaml:"run,omitempty" json:"run,omitempty" xml:"run,omitempty"`
+				}{
+					idx,
+					r,
+				})
+			}
+			LogListResponse("Tasks Run History", newList)
+		} else {
+			var newList = make([]interface{}, 0)
+			for idx, r := range list {
+				newList = append(newList, struct{
+					Line		int					 `yaml:"line,omitempty" json:"line,omitempty" xml:"line,omitempty"`
+					RunId		string				 `yaml:"runId,omitempty" json:"runId,omitempty" xml:"run-id,omitempty"`
+					Uuid		string				 `yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+					Trigger		model.TriggerSource	 `yaml:"trigger,omitempty" json:"trigger,omitempty" xml:"trigger,omitempty"`
+					Attempt		int					 `yaml:"attempt,omitempty" json:"attempt,omitempty" xml:"attempt,omitempty"`
+					Start		time.Time			 `yaml:"start,omitempty" json:"start,omitempty" xml:"start,omitempty"`
+					Duration	time.Duration		 `yaml:"duration,omitempty" json:"duration,omitempty" xml:"duration,omitempty"`
+					Success		bool				 `yaml:"success,omitempty" json:"success,omitempty" xml:"success,omitempty"`
+					Outcome		model.RunOutcome	 `yaml:"outcome,omitempty" json:"outcome,omitempty" xml:"outcome,omitempty"`
+					ExitCode	int					 `yaml:"exitCode,omitempty" json:"exitCode,omitempty" xml:"exit-code,omitempty"`
+				}{
+					idx,
+					r.RunID,
+					r.UUID,
+					r.Trigger,
+					r.Attempt,
+					r.Start,
+					r.Duration,
+					r.Success,
+					r.Outcome,
+					r.ExitCode,
+				})
+			}
+			LogListResponse("Tasks Run History", newList)
+		}
+	}
+	return err
+}
+
+func executeDeliveriesCommand(parseArgs bool, configList ...model.WebhookDelivery) error {
+	var err error
+	var scheduler model.Scheduler
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	if parseArgs {
+		err = parse(getDeliveriesCommandArgsParser())
+		if err != nil {
+			return err
+		}
+	}
+	if len(configList) == 0 && (configPath == ""  || encoding.String() == "") {
+		err = errors.New(fmt.Sprint("Invalid parameters"))
+	} else {
+		var list = configList
+		if len(configList) == 0 {
+			scheduler, err = openScheduler()
+			if err != nil {
+				return err
+			}
+			list = scheduler.Deliveries(deliveriesTask, deliveriesLimit)
+			if err = daemonError(scheduler); err != nil {
+				return err
+			}
+		}
+		var newList = make([]interface{}, 0)
+		for idx, d := range list {
+			newList = append(newList, struct{
+				Line		int						 `yaml:"line,omitempty" json:"line,omitempty" xml:"line,omitempty"`
+				Delivery	model.WebhookDelivery	 `yaml:"delivery,omitempty" json:"delivery,omitempty" xml:"delivery,omitempty"`
+			}{
+				idx,
+				d,
+			})
+		}
+		LogListResponse("Webhook Deliveries", newList)
+	}
+	return err
+}
+
+func executePauseCommand() error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	err = parse(getPauseCommandArgsParser())
+	if err != nil {
+		return err
+	}
+	return executeStateCommand("Pausing scheduler", func(scheduler model.Scheduler) error {
+		return scheduler.Pause()
+	})
+}
+
+func executeResumeCommand() error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	err = parse(getResumeCommandArgsParser())
+	if err != nil {
+		return err
+	}
+	return executeStateCommand("Resuming scheduler", func(scheduler model.Scheduler) error {
+		return scheduler.Resume()
+	})
+}
+
+func executeEnableCommand() error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	err = parse(getEnableCommandArgsParser())
+	if err != nil {
+		return err
+	}
+	return executeTaskStateCommand("Enabling command", func(scheduler model.Scheduler, key string) error {
+		return scheduler.Enable(key)
+	})
+}
+
+func executeDisableCommand() error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	err = parse(getDisableCommandArgsParser())
+	if err != nil {
+		return err
+	}
+	return executeTaskStateCommand("Disabling command", func(scheduler model.Scheduler, key string) error {
+		return scheduler.Disable(key)
+	})
+}
+
+// Applies a scheduler state change and reports the new scheduler state
+func executeStateCommand(message string, change func(scheduler model.Scheduler) error) error {
+	if configPath == ""  || encoding.String() == "" {
+		return errors.New(fmt.Sprint("Invalid parameters"))
+	}
+	scheduler, err := openScheduler()
+	if err != nil {
+		return err
+	}
+	err = change(scheduler)
+	LogResponse(err, message, fmt.Sprintf("Scheduler state: %s", scheduler.State()))
+	return nil
+}
+
+func executeMigrateCommand() error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	err = parse(getMigrateCommandArgsParser())
+	if err != nil {
+		return err
+	}
+	if configPath == ""  || encoding.String() == "" {
+		return errors.New(fmt.Sprint("Invalid parameters"))
+	}
+	if storeKind != store.KindFile {
+		return errors.New(fmt.Sprintf("Migration applies to the %s store only", store.KindFile))
+	}
+	if connectDaemon() != nil {
+		return errors.New(fmt.Sprint("Daemon is running on the configuration, stop it before migrating the configuration files"))
+	}
+	if err = lockStore(); err != nil {
+		return err
+	}
+	count, err := MigrateScheduler(configPath, encoding)
+	LogResponse(err, "Migrating configuration files", fmt.Sprintf("Migrated tasks: %v", count))
+	return nil
+}
+
+// Applies a state change to the task given by the id or name arguments and reports the task configuration
+func executeTaskStateCommand(message string, change func(scheduler model.Scheduler, key string) error) error {
+	var key = taskKey()
+	if configPath == ""  || encoding.String() == "" || key == "" {
+		return errors.New(fmt.Sprint("Invalid parameters, task id or name is required"))
+	}
+	scheduler, err := openScheduler()
+	if err != nil {
+		return err
+	}
+	err = change(scheduler, key)
+	var cmd model.CommandConfig
+	if err == nil {
+		_, cmd, err = scheduler.Get(key)
+	}
+	LogResponse(err, fmt.Sprintf("%s %s", message, key), cmd)
+	return nil
+}
+
+func executeTriggerCommand() error {
+	var err error
+	var scheduler model.Scheduler
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	err = parse(getTriggerCommandArgsParser())
+	if err != nil {
+		return err
+	}
+	var key = taskKey()
+	if configPath == ""  || encoding.String() == "" || key == "" {
+		return errors.New(fmt.Sprint("Invalid parameters, task id or name is required"))
+	}
+	var waitTimeout time.Duration
+	if triggerWaitTimeout != "" {
+		if waitTimeout, err = time.ParseDuration(triggerWaitTimeout); err != nil {
+			return errors.New(fmt.Sprintf("Invalid wait timeout '%s', expected Go duration", triggerWaitTimeout))
+		}
+	}
+	scheduler, err = openScheduler()
+	if err != nil {
+		return err
+	}
+	ref, cmd, err := scheduler.Get(key)
+	if err != nil {
+		LogResponse(err, fmt.Sprintf("Triggering command %s", key), nil)
+		return nil
+	}
+	var since = time.Now()
+	err = scheduler.Trigger(ref.UUID)
+	if err != nil {
+		LogResponse(err, fmt.Sprintf("Triggering command %s", key), nil)
+		return nil
+	}
+	// Without a daemon the run belongs to this process, that must wait for its completion
+	if _, remote := scheduler.(*api.Client); !remote || triggerWait {
+		record, errW := waitManualRun(scheduler, ref.UUID, cmd.Retry, since, waitTimeout)
+		if errW == nil && !remote {
+			// Downstream runs and notifications also belong to this process, it exits when they complete
+			errW = waitLocalRuns(scheduler, waitTimeout)
+		}
+		if errW != nil {
+			LogResponse(errW, fmt.Sprintf("Run of command %s", key), nil)
+		} else {
+			LogResponse(nil, fmt.Sprintf("Run of command %s, outcome: %s, exit code: %v", key, record.Outcome, record.ExitCode), record)
+		}
+		return nil
+	}
+	LogResponse(nil, fmt.Sprintf("Triggering command %s", key), fmt.Sprintf("Command id: %s triggered", ref.UUID))
+	return nil
+}
+
+// Waits for the completion of all the runs of the scheduler in this process, with their notifications
+func waitLocalRuns(scheduler model.Scheduler, timeout time.Duration) error {
+	var done = make(chan struct{})
+	go func() {
+		scheduler.Wait()
+		close(done)
+	}()
+	if timeout <= 0 {
+		<-done
+		return nil
+	}
+	select {
+	case <-done:
+		return nil
+	case <-time.After(timeout):
+		return errors.New(fmt.Sprintf("Downstream runs not completed in %s", timeout))
+	}
+}
+
+// Waits for the completion of the manual run, triggered since given time, of the task with given unique
+// identifier. It retrieves the record of the last attempt, when the run is retried
+func waitManualRun(scheduler model.Scheduler, id string, retry model.RetryConfig, since time.Time,
+	timeout time.Duration) (model.RunRecord, error) {
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	for {
+		var started bool
+		var last model.RunRecord
+		for _, r := range scheduler.History(model.HistoryFilter{UUID: id, Since: since}) {
+			if r.Trigger == model.TriggerManual {
+				started = true
+				last = r
+			} else if started && r.Trigger == model.TriggerRetry {
+				last = r
+			}
+		}
+		if err := daemonError(scheduler); err != nil {
+			return last, err
+		}
+		if started && !(retry.ShouldRetry(last) && last.Attempt < retry.Attempts()) {
+			return last, nil
+		}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			return last, errors.New(fmt.Sprintf("Command id: %s run not completed in %s", id, timeout))
+		}
+		time.Sleep(200 * time.Millisecond)
+	}
+}
@@ -0,0 +1,112 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/utils"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Runs the steps of a composite command, sequentially or in parallel, and aggregates their results: the run
+// output is the steps output in the steps order, the run exit code and error are the ones of the first failed
+// step. Unless the command continues on error, the first failed step stops the steps still to run
+func runCompositeCommand(ctx context.Context, scheduler *scheduler, id string, execution *model.Execution, composite model.CompositeCommand) (utils.CommandResult, []model.StepResult, error) {
+	var stepsCtx, cancel = context.WithCancel(ctx)
+	defer cancel()
+	var results = make([]*model.StepResult, len(composite.Steps))
+	var outputs = make([]utils.CommandResult, len(composite.Steps))
+	var errs = make([]error, len(composite.Steps))
+	var failed = -1
+	var mutex sync.Mutex
+	var run = func(idx int) {
+		var step = composite.Steps[idx]
+		var start = time.Now()
+		var result, steps, err = runCommandSpec(stepsCtx, scheduler, id, execution, step)
+		var stepResult = model.StepResult{
+			Step:     idx + 1,
+			Command:  step.String(),
+			Start:    start,
+			Duration: time.Since(start),
+			Outcome:  model.OutcomeOf(err),
+			ExitCode: result.ExitCode,
+			Stdout:   model.TruncateOutput(result.Stdout, scheduler.historyConfig.Output()),
+			Stderr:   model.TruncateOutput(result.Stderr, scheduler.historyConfig.Output()),
+			Steps:    steps,
+		}
+		if err != nil {
+			stepResult.Error = err.Error()
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		results[idx] = &stepResult
+		outputs[idx] = result
+		errs[idx] = err
+		if err != nil && failed < 0 {
+			failed = idx
+			if !composite.ContinueOnError {
+				cancel()
+			}
+		}
+	}
+	if composite.Parallel() {
+		var wg sync.WaitGroup
+		for idx := range composite.Steps {
+			wg.Add(1)
+			go func(idx int) {
+				defer func() {
+					if r := recover(); r != nil {
+						mutex.Lock()
+						if failed < 0 {
+							failed = idx
+						}
+						errs[idx] = errors.New(fmt.Sprintf("%v", r))
+						mutex.Unlock()
+					}
+					wg.Done()
+				}()
+				run(idx)
+			}(idx)
+		}
+		wg.Wait()
+	} else {
+		for idx := range composite.Steps {
+			if stepsCtx.Err() != nil {
+				break
+			}
+			run(idx)
+		}
+	}
+	var steps = make([]model.StepResult, 0)
+	var stdout, stderr = make([]string, 0), make([]string, 0)
+	for idx, r := range results {
+		if r == nil {
+			// Step not run, after the failure of a previous step
+			continue
+		}
+		steps = append(steps, *r)
+		stdout = append(stdout, outputs[idx].Stdout)
+		stderr = append(stderr, outputs[idx].Stderr)
+	}
+	var result = utils.CommandResult{
+		Stdout: strings.Join(stdout, ""),
+		Stderr: strings.Join(stderr, ""),
+	}
+	if failed >= 0 {
+		result.ExitCode = outputs[failed].ExitCode
+	}
+	switch {
+	case ctx.Err() != nil:
+		// Run timeout or cancellation
+		if failed < 0 {
+			result.ExitCode = -1
+		}
+		return result, steps, ctx.Err()
+	case failed >= 0:
+		return result, steps, errors.New(fmt.Sprintf("Step %d of %d failed: %v", failed+1, len(composite.Steps), errs[failed]))
+	}
+	return result, steps, nil
+}
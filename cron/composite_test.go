@@ -0,0 +1,86 @@
+package cron
+
+import (
+	"context"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunCompositeCommand(t *testing.T) {
+	var cases = []struct {
+		name      string
+		composite model.CompositeCommand
+		stdout    string
+		exitCode  int
+		error     string
+		outcomes  []model.RunOutcome
+	}{
+		{"sequential", model.CompositeCommand{Steps: []model.CommandSpec{model.NewShellCommand("echo a"),
+			model.NewShellCommand("echo b")}}, "a\nb\n", 0, "", []model.RunOutcome{model.OutcomeSuccess, model.OutcomeSuccess}},
+		{"sequential failure", model.CompositeCommand{Steps: []model.CommandSpec{model.NewShellCommand("echo a"),
+			model.NewShellCommand(`sh -c "exit 3"`), model.NewShellCommand("echo c")}}, "a\n", 3, "Step 2 of 3 failed",
+			[]model.RunOutcome{model.OutcomeSuccess, model.OutcomeFailure}},
+		{"sequential continue on error", model.CompositeCommand{Steps: []model.CommandSpec{model.NewShellCommand("echo a"),
+			model.NewShellCommand(`sh -c "exit 3"`), model.NewShellCommand("echo c")}, ContinueOnError: true}, "a\nc\n", 3,
+			"Step 2 of 3 failed", []model.RunOutcome{model.OutcomeSuccess, model.OutcomeFailure, model.OutcomeSuccess}},
+		// The output follows the steps order, not the completion order
+		{"parallel", model.CompositeCommand{Mode: model.CompositeParallel, Steps: []model.CommandSpec{
+			model.NewShellCommand(`sh -c "sleep 0.2; echo a"`), model.NewShellCommand("echo b")}}, "a\nb\n", 0, "",
+			[]model.RunOutcome{model.OutcomeSuccess, model.OutcomeSuccess}},
+		{"parallel failure", model.CompositeCommand{Mode: model.CompositeParallel, Steps: []model.CommandSpec{
+			model.NewShellCommand(`sh -c "exit 4"`), model.NewShellCommand(`sh -c "sleep 5; echo b"`)}}, "", 4, "Step 1 of 2 failed",
+			[]model.RunOutcome{model.OutcomeFailure, model.OutcomeCancelled}},
+		{"parallel continue on error", model.CompositeCommand{Mode: model.CompositeParallel, ContinueOnError: true,
+			Steps: []model.CommandSpec{model.NewShellCommand(`sh -c "exit 4"`), model.NewShellCommand(`sh -c "sleep 0.2; echo b"`)}},
+			"b\n", 4, "Step 1 of 2 failed", []model.RunOutcome{model.OutcomeFailure, model.OutcomeSuccess}},
+		{"nested", model.CompositeCommand{Steps: []model.CommandSpec{model.NewShellCommand("echo a"),
+			model.NewCompositeCommand(model.CompositeCommand{Mode: model.CompositeParallel, Steps: []model.CommandSpec{
+				model.NewShellCommand("echo b"), model.NewShellCommand("echo c")}})}}, "a\nb\nc\n", 0, "",
+			[]model.RunOutcome{model.OutcomeSuccess, model.OutcomeSuccess}},
+	}
+	for _, c := range cases {
+		var s = newScheduler(store.NewMemoryStore(), false)
+		var execution = &model.Execution{UUID: "id", Command: model.CommandConfig{Name: c.name}}
+		var start = time.Now()
+		result, steps, err := runCompositeCommand(context.Background(), s, "id", execution, c.composite)
+		if time.Since(start) > 3*time.Second {
+			t.Errorf("%s: expected failed step to stop the running steps", c.name)
+		}
+		if result.Stdout != c.stdout || result.ExitCode != c.exitCode {
+			t.Errorf("%s: expected output %q and exit code %d, found %q and %d", c.name, c.stdout, c.exitCode, result.Stdout, result.ExitCode)
+		}
+		if (c.error == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected error %q, found: %v", c.name, c.error, err)
+		}
+		if len(steps) != len(c.outcomes) {
+			t.Errorf("%s: expected %d step results, found %+v", c.name, len(c.outcomes), steps)
+			continue
+		}
+		for idx, step := range steps {
+			if step.Step != idx+1 || step.Outcome != c.outcomes[idx] || step.Command != c.composite.Steps[idx].String() {
+				t.Errorf("%s: expected step %d %s with outcome %s, found %+v", c.name, idx+1, c.composite.Steps[idx], c.outcomes[idx], step)
+			}
+		}
+		if c.name == "nested" && (len(steps[1].Steps) != 2 || steps[1].Steps[1].Stdout != "c\n") {
+			t.Errorf("%s: expected nested steps results, found %+v", c.name, steps[1].Steps)
+		}
+	}
+}
+
+func TestRunCompositeCommandCancelled(t *testing.T) {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	var composite = model.CompositeCommand{Steps: []model.CommandSpec{model.NewShellCommand("echo a"),
+		model.NewShellCommand("sleep 5"), model.NewShellCommand("echo c")}}
+	result, steps, err := runCompositeCommand(ctx, s, "id", &model.Execution{UUID: "id"}, composite)
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected run timeout, found: %v", err)
+	}
+	if len(steps) != 2 || result.Stdout != "a\n" {
+		t.Errorf("Expected the steps after the timeout not run, found output %q and steps %+v", result.Stdout, steps)
+	}
+}
@@ -0,0 +1,156 @@
+package cron
+
+import (
+	"context"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"testing"
+	"time"
+)
+
+func TestApplyConcurrencyPolicy(t *testing.T) {
+	var cases = []struct {
+		policy    model.ConcurrencyPolicy
+		running   int
+		run       bool
+		skipped   bool
+		cancelled bool
+	}{
+		{"", 0, true, false, false},
+		{"", 1, false, true, false},
+		{model.ConcurrencyAllow, 0, true, false, false},
+		{model.ConcurrencyAllow, 2, true, false, false},
+		{model.ConcurrencyForbid, 0, true, false, false},
+		{model.ConcurrencyForbid, 1, false, true, false},
+		{model.ConcurrencyQueue, 0, true, false, false},
+		{model.ConcurrencyQueue, 1, true, false, false},
+		{model.ConcurrencyReplace, 0, true, false, false},
+		{model.ConcurrencyReplace, 1, true, false, true},
+	}
+	for _, c := range cases {
+		var s = newScheduler(store.NewMemoryStore(), false)
+		var execution = &model.Execution{UUID: "id", Running: c.running, Command: model.CommandConfig{Concurrency: c.policy}}
+		var cancelled bool
+		if c.running > 0 {
+			s.activeRuns["id"] = map[string]context.CancelFunc{"run": func() {
+				cancelled = true
+			}}
+		}
+		run, skipped := applyConcurrencyPolicy(s, execution)
+		if run != c.run || skipped != c.skipped || cancelled != c.cancelled {
+			t.Errorf("Policy %q, running %d: expected run %v, skipped %v, cancelled %v, found %v, %v, %v", c.policy,
+				c.running, c.run, c.skipped, c.cancelled, run, skipped, cancelled)
+		}
+		if skipped && (execution.Skipped != 1 || execution.Last.IsZero()) {
+			t.Errorf("Policy %q: expected skipped run counted as last execution, found %+v", c.policy, execution)
+		} else if !skipped && (execution.Skipped != 0 || !execution.Last.IsZero()) {
+			t.Errorf("Policy %q: unexpected skipped run, found %+v", c.policy, execution)
+		}
+	}
+}
+
+// Acquires a run slot in background, sending the release function when acquired
+func acquireAsync(s *scheduler, execution *model.Execution) (chan func(), chan error) {
+	var acquired, failed = make(chan func(), 1), make(chan error, 1)
+	go func() {
+		release, err := acquireRunSlot(s, execution)
+		if err != nil {
+			failed <- err
+			return
+		}
+		acquired <- release
+	}()
+	return acquired, failed
+}
+
+func TestAcquireRunSlot(t *testing.T) {
+	var cases = []struct {
+		name       string
+		maxRunning int
+		first      model.ConcurrencyPolicy
+		second     model.ConcurrencyPolicy
+		sameTask   bool
+		waits      bool
+	}{
+		{"no limit", 0, model.ConcurrencyAllow, model.ConcurrencyAllow, true, false},
+		{"queue same task", 0, model.ConcurrencyQueue, model.ConcurrencyQueue, true, true},
+		{"queue other task", 0, model.ConcurrencyQueue, model.ConcurrencyQueue, false, false},
+		{"forbid no limit", 0, model.ConcurrencyForbid, model.ConcurrencyForbid, true, false},
+		{"replace no limit", 0, model.ConcurrencyReplace, model.ConcurrencyReplace, true, false},
+		{"scheduler limit", 1, model.ConcurrencyAllow, model.ConcurrencyReplace, false, true},
+		{"scheduler limit free", 2, model.ConcurrencyQueue, model.ConcurrencyForbid, false, false},
+	}
+	for _, c := range cases {
+		var s = newScheduler(store.NewMemoryStore(), false)
+		s.maxRunning = c.maxRunning
+		s.resetRunState()
+		var first = &model.Execution{UUID: "first", Command: model.CommandConfig{Concurrency: c.first}}
+		var second = &model.Execution{UUID: "second", Command: model.CommandConfig{Concurrency: c.second}}
+		if c.sameTask {
+			second.UUID = first.UUID
+		}
+		releaseFirst, err := acquireRunSlot(s, first)
+		if err != nil {
+			t.Fatalf("%s: unable to acquire the first slot: %v", c.name, err)
+		}
+		acquired, _ := acquireAsync(s, second)
+		select {
+		case release := <-acquired:
+			if c.waits {
+				t.Errorf("%s: expected the second run to wait for the first one", c.name)
+			}
+			release()
+		case <-time.After(100 * time.Millisecond):
+			if !c.waits {
+				t.Errorf("%s: unexpected wait of the second run", c.name)
+			}
+		}
+		releaseFirst()
+		if c.waits {
+			select {
+			case release := <-acquired:
+				release()
+			case <-time.After(time.Second):
+				t.Errorf("%s: expected the second run to start when the first one releases its slot", c.name)
+			}
+		}
+		// All the slots have been released
+		if release, err := acquireRunSlot(s, second); err != nil {
+			t.Errorf("%s: unable to acquire the released slots: %v", c.name, err)
+		} else {
+			release()
+		}
+	}
+}
+
+func TestAcquireRunSlotStop(t *testing.T) {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	s.maxRunning = 1
+	s.resetRunState()
+	var ctx, cancel = context.WithCancel(context.Background())
+	s.context, s.cancel = ctx, cancel
+	var queued = &model.Execution{UUID: "queued", Command: model.CommandConfig{Concurrency: model.ConcurrencyQueue}}
+	release, err := acquireRunSlot(s, &model.Execution{UUID: "other"})
+	if err != nil {
+		t.Fatalf("Unable to acquire the scheduler slot: %v", err)
+	}
+	_, failed := acquireAsync(s, queued)
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err = <-failed:
+		if err != context.Canceled {
+			t.Errorf("Expected cancelled wait, found: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the waiting run to stop with the scheduler")
+	}
+	release()
+	// The task slot taken before waiting the scheduler slot has been released
+	s.context = context.Background()
+	if release, err = acquireRunSlot(s, queued); err != nil {
+		t.Errorf("Unable to acquire the released task slot: %v", err)
+	} else {
+		release()
+	}
+}
@@ -0,0 +1,115 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+)
+
+// Subscribes the scheduler events, with given buffer size, slow subscribers lose the events
+func (s *scheduler) Subscribe(buffer int) *model.Subscription {
+	return s.events.Subscribe(buffer)
+}
+
+// Retrieves the error level events, as errors, until the scheduler is destroyed. Errors of task events are
+// *model.TaskError values
+func (s *scheduler) Errors() chan error {
+	s.errorsOnce.Do(func() {
+		s.errors = s.levelAdapter(model.EventLevelError)
+	})
+	return s.errors
+}
+
+// Retrieves the warning level events, as errors, until the scheduler is destroyed. Errors of task events are
+// *model.TaskError values
+func (s *scheduler) Warnings() chan error {
+	s.warningsOnce.Do(func() {
+		s.warnings = s.levelAdapter(model.EventLevelWarning)
+	})
+	return s.warnings
+}
+
+// Forwards the events with given level in a new channel. The adapter has its own subscription, so a channel
+// that is not read loses the events but it doesn't block the scheduler. The channel is closed when the scheduler
+// is destroyed, even if it's not read
+func (s *scheduler) levelAdapter(level string) chan error {
+	var c = make(chan error)
+	var subscription = s.events.SubscribeFilter(model.DefaultEventBuffer, func(event model.Event) bool {
+		return event.Level == level
+	})
+	go func() {
+		defer close(c)
+		for event := range subscription.Events() {
+			select {
+			case c <- eventError(event):
+			case <-subscription.Done():
+				return
+			}
+		}
+	}()
+	return c
+}
+
+func eventError(event model.Event) error {
+	var err = errors.New(event.Message)
+	if event.UUID != "" {
+		return &model.TaskError{UUID: event.UUID, RunID: event.RunID, Err: err}
+	}
+	return err
+}
+
+// Publishes the events of the errors and warnings sent by the Go function commands in the execution context pipes,
+// until the pipe is closed
+func (s *scheduler) drainPipe(pipe chan error, eventType model.EventType) {
+	go func() {
+		for err := range pipe {
+			if err == nil {
+				continue
+			}
+			var event = model.Event{Type: eventType, Message: err.Error()}
+			var taskErr *model.TaskError
+			if errors.As(err, &taskErr) {
+				event.UUID = taskErr.UUID
+				event.RunID = taskErr.RunID
+			}
+			s.events.Publish(event)
+		}
+	}()
+}
+
+// Publishes a scheduler error, not related to a run outcome
+func (s *scheduler) publishError(err error) {
+	s.events.Publish(model.Event{Type: model.EventError, Message: err.Error(), Error: err.Error()})
+}
+
+// Publishes the recovery of corrupted stored data as warning, the recovered data are valid
+func (s *scheduler) recovered(err error) error {
+	var recovered *io.RecoveredError
+	if errors.As(err, &recovered) {
+		s.events.Publish(model.Event{Type: model.EventWarning, Message: err.Error()})
+		return nil
+	}
+	return err
+}
+
+// Creates an event of the task with given unique identifier, with the task name when known
+func taskEvent(eventType model.EventType, execution *model.Execution, id string, format string, args ...interface{}) model.Event {
+	var event = model.Event{Type: eventType, UUID: id, Message: fmt.Sprintf(format, args...)}
+	if execution != nil {
+		event.Name = execution.Command.Name
+	}
+	return event
+}
+
+// Completes the task event with the run details
+func runEvent(event model.Event, record model.RunRecord) model.Event {
+	event.RunID = record.RunID
+	event.Trigger = record.Trigger
+	event.Attempt = record.Attempt
+	event.Outcome = record.Outcome
+	event.ExitCode = record.ExitCode
+	event.Duration = record.Duration
+	event.Error = record.Error
+	return event
+}
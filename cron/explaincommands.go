@@ -5,6 +5,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/hellgate75/go-cron/io"
 	"github.com/hellgate75/go-cron/model"
+	"os"
 	"time"
 )
 
@@ -24,6 +25,22 @@ func Explain(command string, subCommand string) {
 		explainActiveCommand()
 	case "next":
 		explainNextCommand()
+	case "history":
+		explainHistoryCommand()
+	case "deliveries":
+		explainDeliveriesCommand()
+	case "pause":
+		explainPauseCommand()
+	case "resume":
+		explainResumeCommand()
+	case "enable":
+		explainEnableCommand()
+	case "disable":
+		explainDisableCommand()
+	case "trigger", "run":
+		explainTriggerCommand()
+	case "migrate":
+		explainMigrateCommand()
 	default:
 		fmt.Printf("Cannot explain unknown data command: <%s>\n", command)
 		fmt.Printf("Available commands: %v\n", Commands[2:])
@@ -35,7 +52,7 @@ func explainAddCommand() {
 	_ = parse(getAddCommandArgsParser())
 	p := 10 * time.Hour
 	c := model.CommandConfig{
-		Command: "myCommand myArg1 myArg2 ...",
+		Command: model.NewShellCommand("myCommand myArg1 myArg2 ..."),
 		Repeat: 5,
 		Since: time.Now(),
 		Period: p.String(),
@@ -66,7 +83,7 @@ func explainUpdateCommand() {
 	_ = parse(getUpdateCommandArgsParser())
 	p := 10 * time.Hour
 	c := model.CommandConfig{
-		Command: "myCommand myArg1 myArg2 ...",
+		Command: model.NewShellCommand("myCommand myArg1 myArg2 ..."),
 		Repeat: 5,
 		Since: time.Now(),
 		Period: p.String(),
@@ -89,7 +106,7 @@ func explainListCommand() {
 	_ = parse(getListCommandArgsParser())
 	p := 10 * time.Hour
 	c := model.CommandConfig{
-		Command: "myCommand myArg1 myArg2 ...",
+		Command: model.NewShellCommand("myCommand myArg1 myArg2 ..."),
 		Repeat: 5,
 		Since: time.Now(),
 		Period: p.String(),
@@ -108,7 +125,7 @@ func explainActiveCommand() {
 	p := 20 * time.Minute
 	p2 := 1 * time.Minute
 	c := model.CommandConfig{
-		Command: "myCommand myArg1 myArg2 ...",
+		Command: model.NewShellCommand("myCommand myArg1 myArg2 ..."),
 		Repeat: 5,
 		Since: time.Now().Add(-p2),
 		Period: p.String(),
@@ -136,7 +153,7 @@ func explainNextCommand() {
 	_ = parse(getNextCommandArgsParser())
 	p := 20 * time.Minute
 	c := model.CommandConfig{
-		Command: "myCommand myArg1 myArg2 ...",
+		Command: model.NewShellCommand("myCommand myArg1 myArg2 ..."),
 		Repeat: 5,
 		Since: time.Now(),
 		Period: p.String(),
@@ -158,3 +175,95 @@ func explainNextCommand() {
 	_ = executeNextCommand(false, e)
 }
 
+func explainHistoryCommand() {
+	_ = parse(getHistoryCommandArgsParser())
+	host, _ := os.Hostname()
+	start := time.Now().Add(-2 * time.Minute)
+	r := model.RunRecord{
+		RunID: uuid.New().String(),
+		UUID: uuid.New().String(),
+		Trigger: model.TriggerSchedule,
+		Host: host,
+		Start: start,
+		End: start.Add(1500 * time.Millisecond),
+		Duration: 1500 * time.Millisecond,
+		Success: true,
+		ExitCode: 0,
+		Stdout: "myCommand output",
+	}
+	if ! silent {
+		fmt.Printf("List tasks run history records, in summary (text table) or detail mode (encoding format)\n")
+		fmt.Printf("Output sample:\n")
+	}
+	_ = executeHistoryCommand(false, r)
+}
+
+func explainDeliveriesCommand() {
+	_ = parse(getDeliveriesCommandArgsParser())
+	d := model.WebhookDelivery{
+		Time: time.Now().Add(-2 * time.Minute),
+		Webhook: "chat",
+		URL: "https://chat.example.com/hooks/go-cron",
+		Event: model.NotifyFailure,
+		UUID: uuid.New().String(),
+		RunID: uuid.New().String(),
+		Attempt: 1,
+		StatusCode: 200,
+		Duration: 120 * time.Millisecond,
+		Delivered: true,
+	}
+	if ! silent {
+		fmt.Printf("List webhook delivery log records, of the task run notifications\n")
+		fmt.Printf("Output sample:\n")
+	}
+	_ = executeDeliveriesCommand(false, d)
+}
+
+
+func explainPauseCommand() {
+	_ = parse(getPauseCommandArgsParser())
+	if ! silent {
+		fmt.Printf("Suspend the scheduling of new runs, until the scheduler is resumed: \n")
+	}
+	fmt.Printf("No prototype data \n")
+}
+
+func explainResumeCommand() {
+	_ = parse(getResumeCommandArgsParser())
+	if ! silent {
+		fmt.Printf("Resume the scheduling of new runs of a paused scheduler: \n")
+	}
+	fmt.Printf("No prototype data \n")
+}
+
+func explainEnableCommand() {
+	_ = parse(getEnableCommandArgsParser())
+	if ! silent {
+		fmt.Printf("Enable the scheduling of one configuration item, including its unique identifier or name: \n")
+	}
+	fmt.Printf("No prototype data \n")
+}
+
+func explainDisableCommand() {
+	_ = parse(getDisableCommandArgsParser())
+	if ! silent {
+		fmt.Printf("Disable the scheduling of one configuration item, including its unique identifier or name: \n")
+	}
+	fmt.Printf("No prototype data \n")
+}
+
+func explainTriggerCommand() {
+	_ = parse(getTriggerCommandArgsParser())
+	if ! silent {
+		fmt.Printf("Run immediately one configuration item, including its unique identifier or name: \n")
+	}
+	fmt.Printf("No prototype data \n")
+}
+
+func explainMigrateCommand() {
+	_ = parse(getMigrateCommandArgsParser())
+	if ! silent {
+		fmt.Printf("Convert the configuration files written by previous versions to the current format: \n")
+	}
+	fmt.Printf("No prototype data \n")
+}
@@ -26,6 +26,22 @@ func Help(command string, subCommand string) {
 		helpActiveCommand()
 	case "next":
 		helpNextCommand()
+	case "history":
+		helpHistoryCommand()
+	case "deliveries":
+		helpDeliveriesCommand()
+	case "pause":
+		helpPauseCommand()
+	case "resume":
+		helpResumeCommand()
+	case "enable":
+		helpEnableCommand()
+	case "disable":
+		helpDisableCommand()
+	case "trigger", "run":
+		helpTriggerCommand()
+	case "migrate":
+		helpMigrateCommand()
 	default:
 		fmt.Printf("Cannot describe unknown command: <%s>\n", command)
 		fmt.Printf("Available commands: %v\n", Commands)
@@ -93,3 +109,51 @@ func helpNextCommand() {
 	PrintHelp(fl)
 }
 
+func helpHistoryCommand() {
+	var fl  = getHistoryCommandArgsParser()
+	fmt.Printf("List tasks run history records, in summary (text table) or detail mode (encoding format)\n")
+	PrintHelp(fl)
+}
+
+func helpDeliveriesCommand() {
+	var fl  = getDeliveriesCommandArgsParser()
+	fmt.Printf("List webhook delivery log records, of the task run notifications\n")
+	PrintHelp(fl)
+}
+
+
+func helpPauseCommand() {
+	var fl  = getPauseCommandArgsParser()
+	fmt.Printf("Suspend the scheduling of new runs, until the scheduler is resumed, also across daemon restarts\n")
+	PrintHelp(fl)
+}
+
+func helpResumeCommand() {
+	var fl  = getResumeCommandArgsParser()
+	fmt.Printf("Resume the scheduling of new runs of a paused scheduler\n")
+	PrintHelp(fl)
+}
+
+func helpEnableCommand() {
+	var fl  = getEnableCommandArgsParser()
+	fmt.Printf("Enable the scheduling of one configuration item by unique identifier or name\n")
+	PrintHelp(fl)
+}
+
+func helpDisableCommand() {
+	var fl  = getDisableCommandArgsParser()
+	fmt.Printf("Disable the scheduling of one configuration item by unique identifier or name\n")
+	PrintHelp(fl)
+}
+
+func helpTriggerCommand() {
+	var fl  = getTriggerCommandArgsParser()
+	fmt.Printf("Run immediately one configuration item by unique identifier or name, out of its time table\n")
+	PrintHelp(fl)
+}
+
+func helpMigrateCommand() {
+	var fl  = getMigrateCommandArgsParser()
+	fmt.Printf("Convert the configuration files written by previous versions to the current format, the daemon must be stopped\n")
+	PrintHelp(fl)
+}
@@ -0,0 +1,90 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/utils"
+	"time"
+)
+
+// Context key of the run attempt described to the hook commands
+type hookRunKey struct{}
+
+type hookRun struct {
+	hook   model.HookEvent
+	record model.RunRecord
+}
+
+// Verifies the hook commands consistency, as the task command
+func validateHooks(cmd model.CommandConfig, functions bool) error {
+	if err := cmd.Hooks.Validate(); err != nil {
+		return err
+	}
+	for _, hook := range cmd.Hooks.All() {
+		if functions {
+			if err := validateFunctions(hook.Command); err != nil {
+				return errors.New(fmt.Sprintf("Invalid %s hook: %v", hook.Hook, err))
+			}
+		}
+		if err := validateShellCommands(hook.Command, cmd.Process); err != nil {
+			return errors.New(fmt.Sprintf("Invalid %s hook: %v", hook.Hook, err))
+		}
+	}
+	return nil
+}
+
+// Runs the hook commands of a completed run attempt and collects their results. Hook failures are reported as
+// warnings and don't change the run outcome
+func runHooks(scheduler *scheduler, execution *model.Execution, id string, record model.RunRecord) []model.HookResult {
+	var hooks = execution.Command.Hooks.Commands(record.Outcome)
+	if len(hooks) == 0 {
+		return nil
+	}
+	var results = make([]model.HookResult, 0)
+	for _, hook := range hooks {
+		results = append(results, runHook(scheduler, execution, id, hook, record))
+	}
+	return results
+}
+
+// Runs a hook command with the task runners and process options, within the command timeout. Hooks of timed
+// out attempts run, while the scheduler stop cancels them
+func runHook(scheduler *scheduler, execution *model.Execution, id string, hook model.HookCommand, record model.RunRecord) (result model.HookResult) {
+	var ctx, cancel = scheduler.runContext(scheduler.baseContext(), execution.Command)
+	defer cancel()
+	ctx = context.WithValue(ctx, hookRunKey{}, hookRun{hook: hook.Hook, record: record})
+	result = model.HookResult{Hook: hook.Hook, Command: hook.Command.String(), Start: time.Now()}
+	var out = utils.CommandResult{ExitCode: -1}
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+		result.Duration = time.Since(result.Start)
+		result.Outcome = model.OutcomeOf(err)
+		result.ExitCode = out.ExitCode
+		result.Stdout = model.TruncateOutput(out.Stdout, scheduler.historyConfig.Output())
+		result.Stderr = model.TruncateOutput(out.Stderr, scheduler.historyConfig.Output())
+		if err != nil {
+			result.Error = err.Error()
+			var event = taskEvent(model.EventWarning, execution, id, "Execution of command id : %s, %s hook failed, exit code: %v, error: %v", id, hook.Hook, out.ExitCode, err)
+			event.RunID = record.RunID
+			event.Attempt = record.Attempt
+			event.Error = err.Error()
+			scheduler.events.Publish(event)
+		}
+	}()
+	out, result.Steps, err = runCommandSpec(ctx, scheduler, id, execution, hook.Command)
+	return result
+}
+
+// Retrieves the process options of the task, with the run attempt environment variables when running a hook
+func commandProcessOptions(ctx context.Context, execution *model.Execution) model.ProcessOptions {
+	var options = execution.Command.Process
+	if run, ok := ctx.Value(hookRunKey{}).(hookRun); ok {
+		options.Env = append(append([]string{}, options.Env...), run.record.HookEnvironment(run.hook, execution.Command.Name)...)
+	}
+	return options
+}
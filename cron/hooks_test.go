@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"runtime"
+	"testing"
+)
+
+// Hook printing the hook event, the run outcome and the run standard output
+func printHook() *model.CommandSpec {
+	var hook = model.NewExecCommand("/bin/sh", "-c", `printf '%s %s %s' "$GO_CRON_HOOK" "$GO_CRON_RUN_OUTCOME" "$GO_CRON_RUN_STDOUT"`)
+	return &hook
+}
+
+func TestRunHooks(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var all = model.RunHooks{OnSuccess: printHook(), OnFailure: printHook(), Always: printHook()}
+	var failingHook = model.NewExecCommand("/bin/sh", "-c", "echo broken >&2; exit 2")
+	var tasks = []struct {
+		name     string
+		command  model.CommandSpec
+		hooks    model.RunHooks
+		expected []model.HookResult
+	}{
+		{"success", model.NewShellCommand("echo ok"), all, []model.HookResult{
+			{Hook: model.HookOnSuccess, Outcome: model.OutcomeSuccess, Stdout: "onSuccess success ok\n"},
+			{Hook: model.HookAlways, Outcome: model.OutcomeSuccess, Stdout: "always success ok\n"},
+		}},
+		{"failure", model.NewShellCommand("false"), all, []model.HookResult{
+			{Hook: model.HookOnFailure, Outcome: model.OutcomeSuccess, Stdout: "onFailure failure "},
+			{Hook: model.HookAlways, Outcome: model.OutcomeSuccess, Stdout: "always failure "},
+		}},
+		{"success hook only", model.NewShellCommand("false"), model.RunHooks{OnSuccess: printHook()}, nil},
+		// NUL bytes in the run output can't be passed in the hook environment
+		{"binary output", model.NewExecCommand("/bin/sh", "-c", `printf 'a\000b'; exit 1`), model.RunHooks{OnFailure: printHook()}, []model.HookResult{
+			{Hook: model.HookOnFailure, Outcome: model.OutcomeSuccess, Stdout: "onFailure failure ab"},
+		}},
+		// Hook failures don't change the run outcome
+		{"failed hook", model.NewShellCommand("true"), model.RunHooks{Always: &failingHook}, []model.HookResult{
+			{Hook: model.HookAlways, Outcome: model.OutcomeFailure, ExitCode: 2, Stderr: "broken\n"},
+		}},
+	}
+	for _, task := range tasks {
+		if err := s.AddToCache(model.CommandConfig{Name: task.name, Command: task.command, Hooks: task.hooks}); err != nil {
+			t.Fatalf("Unable to add task %s: %v", task.name, err)
+		}
+		if err := s.Trigger(task.name); err != nil {
+			t.Fatalf("Unable to trigger task %s: %v", task.name, err)
+		}
+	}
+	s.runs.Wait()
+	for _, task := range tasks {
+		var records = s.History(model.HistoryFilter{UUID: taskUUID(t, s, task.name)})
+		if len(records) != 1 {
+			t.Errorf("%s: expected 1 run record, found %d", task.name, len(records))
+			continue
+		}
+		var hooks = records[0].Hooks
+		if len(hooks) != len(task.expected) {
+			t.Errorf("%s: expected %d hook results, found %+v", task.name, len(task.expected), hooks)
+			continue
+		}
+		if task.name == "failed hook" && records[0].Outcome != model.OutcomeSuccess {
+			t.Errorf("%s: expected successful run, found %s", task.name, records[0].Outcome)
+		}
+		for idx, expected := range task.expected {
+			var hook = hooks[idx]
+			if hook.Hook != expected.Hook || hook.Outcome != expected.Outcome || hook.ExitCode != expected.ExitCode ||
+				hook.Stdout != expected.Stdout || hook.Stderr != expected.Stderr {
+				t.Errorf("%s: expected hook result %+v, found %+v", task.name, expected, hook)
+			}
+			if hook.Start.IsZero() || hook.Command == "" {
+				t.Errorf("%s: incomplete hook result %+v", task.name, hook)
+			}
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package cron
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/utils"
+	"mime"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"strings"
+	"time"
+)
+
+// Sends the mail notification of a run final attempt, accordingly to the scheduler mail configuration merged
+// with the task one. Mails are sent in the background, so the SMTP server never delays the task runs
+func (s *scheduler) mailRun(execution *model.Execution, id string, previous model.RunOutcome, record model.RunRecord, events []model.NotifyEvent) {
+	s.deliveriesMutex.Lock()
+	var config = s.mail.Merge(execution.Command.Mail)
+	s.deliveriesMutex.Unlock()
+	if !config.IsEnabled() || len(events) == 0 {
+		return
+	}
+	if config.OnlyOnFailure && record.Outcome == model.OutcomeSuccess {
+		return
+	}
+	var notification = model.Notification{Event: mostSpecific(events), UUID: id, Name: execution.Command.Name, Previous: previous, Run: record}
+	s.notifications.Add(1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				s.publishError(errors.New(fmt.Sprintf("%v", r)))
+			}
+			s.notifications.Done()
+		}()
+		s.sendMail(config, notification)
+	}()
+}
+
+// Retrieves the most specific of the run events
+func mostSpecific(events []model.NotifyEvent) model.NotifyEvent {
+	for _, candidate := range model.NotifyEventList {
+		for _, event := range events {
+			if event == candidate {
+				return event
+			}
+		}
+	}
+	return ""
+}
+
+// Sends the notification mail and records the outcome in the delivery log
+func (s *scheduler) sendMail(config model.MailConfig, notification model.Notification) {
+	var delivery = model.WebhookDelivery{
+		Time:    time.Now(),
+		Webhook: "mail",
+		URL:     "smtp://" + config.Server,
+		Event:   notification.Event,
+		UUID:    notification.UUID,
+		RunID:   notification.Run.RunID,
+		Attempt: 1,
+	}
+	from, to, message, err := composeMail(config, notification)
+	if err == nil {
+		err = utils.SendMail(utils.SMTPOptions{
+			Server:             config.Server,
+			Username:           config.Username,
+			Password:           config.Password,
+			StartTLS:           config.TLSMode() != model.StartTLSNever,
+			RequireTLS:         config.TLSMode() == model.StartTLSAlways,
+			InsecureSkipVerify: config.InsecureSkipVerify,
+			Timeout:            config.TimeoutDuration(),
+		}, from, to, message)
+	}
+	delivery.Duration = time.Since(delivery.Time)
+	delivery.Delivered = err == nil
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if errD := s.addDelivery(delivery); errD != nil {
+		s.publishError(errors.New(fmt.Sprintf("Unable to save webhook delivery log, error: %v", errD)))
+	}
+	if err != nil {
+		s.events.Publish(model.Event{Type: model.EventWarning, UUID: notification.UUID, Name: notification.Name,
+			RunID: notification.Run.RunID, Error: err.Error(),
+			Message: fmt.Sprintf("Mail notification of %s event to %s failed, error: %v",
+				notification.Event, strings.Join(config.To, ", "), err)})
+	}
+}
+
+// Creates the mail message, with the sender and recipients envelope addresses
+func composeMail(config model.MailConfig, notification model.Notification) (string, []string, []byte, error) {
+	sender, err := mail.ParseAddress(config.Sender())
+	if err != nil {
+		return "", nil, nil, errors.New(fmt.Sprintf("Invalid mail sender '%s': %v", config.Sender(), err))
+	}
+	var to = make([]string, 0)
+	var headerTo = make([]string, 0)
+	for _, recipient := range config.To {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return "", nil, nil, errors.New(fmt.Sprintf("Invalid mail recipient '%s': %v", recipient, err))
+		}
+		to = append(to, address.Address)
+		headerTo = append(headerTo, address.String())
+	}
+	subject, err := config.RenderSubject(notification)
+	if err != nil {
+		return "", nil, nil, errors.New(fmt.Sprintf("Invalid mail subject: %v", err))
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", sender.String())
+	fmt.Fprintf(&message, "To: %s\r\n", strings.Join(headerTo, ", "))
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: text/plain; charset=utf-8\r\n")
+	fmt.Fprintf(&message, "Content-Transfer-Encoding: quoted-printable\r\n")
+	fmt.Fprintf(&message, "X-Go-Cron-Task: %s\r\n", notification.UUID)
+	fmt.Fprintf(&message, "\r\n")
+	var body = quotedprintable.NewWriter(&message)
+	_, _ = body.Write([]byte(strings.ReplaceAll(mailBody(notification, !config.OmitOutput), "\n", "\r\n")))
+	if err = body.Close(); err != nil {
+		return "", nil, nil, err
+	}
+	return sender.Address, to, message.Bytes(), nil
+}
+
+// Describes the run in the mail body, with the run output when required
+func mailBody(notification model.Notification, output bool) string {
+	var run = notification.Run
+	var text strings.Builder
+	fmt.Fprintf(&text, "Task:      %s (%s)\n", notification.Name, notification.UUID)
+	fmt.Fprintf(&text, "Event:     %s\n", notification.Event)
+	fmt.Fprintf(&text, "Run:       %s\n", run.RunID)
+	var host = run.Host
+	if host == "" {
+		host, _ = os.Hostname()
+	}
+	fmt.Fprintf(&text, "Host:      %s\n", host)
+	if run.Trigger != "" {
+		fmt.Fprintf(&text, "Trigger:   %s\n", run.Trigger)
+	}
+	fmt.Fprintf(&text, "Attempt:   %d\n", run.Attempt)
+	fmt.Fprintf(&text, "Start:     %s\n", run.Start.Format(time.RFC3339))
+	fmt.Fprintf(&text, "Duration:  %s\n", run.Duration)
+	fmt.Fprintf(&text, "Outcome:   %s\n", run.Outcome)
+	fmt.Fprintf(&text, "Exit code: %d\n", run.ExitCode)
+	if run.Error != "" {
+		fmt.Fprintf(&text, "Error:     %s\n", run.Error)
+	}
+	if notification.Previous != "" {
+		fmt.Fprintf(&text, "Previous:  %s\n", notification.Previous)
+	}
+	if output {
+		fmt.Fprintf(&text, "\nStandard output:\n%s\n", run.Stdout)
+		fmt.Fprintf(&text, "\nStandard error:\n%s\n", run.Stderr)
+	}
+	return text.String()
+}
@@ -0,0 +1,312 @@
+package cron
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"math/big"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Mail received by the fake SMTP server
+type receivedMail struct {
+	from string
+	to   []string
+	data string
+	user string
+	tls  bool
+}
+
+// Fake SMTP server, advertising STARTTLS when required and accepting the PLAIN authentication of one user
+type smtpServer struct {
+	listener net.Listener
+	startTLS bool
+	user     string
+	password string
+	config   *tls.Config
+	lock     sync.Mutex
+	mails    []receivedMail
+}
+
+func newSMTPServer(t *testing.T, startTLS bool, user string, password string) *smtpServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Unable to listen: %v", err)
+	}
+	var server = &smtpServer{listener: listener, startTLS: startTLS, user: user, password: password,
+		config: &tls.Config{Certificates: []tls.Certificate{selfSignedCertificate(t)}}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	t.Cleanup(func() {
+		_ = listener.Close()
+	})
+	return server
+}
+
+func (s *smtpServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *smtpServer) received() []receivedMail {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]receivedMail{}, s.mails...)
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	var text = textproto.NewConn(conn)
+	var mail receivedMail
+	_ = text.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := text.ReadLine()
+		if err != nil {
+			return
+		}
+		var verb = strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+		switch {
+		case verb == "EHLO" || verb == "HELO":
+			var lines = []string{"250-fake"}
+			if s.startTLS && !mail.tls {
+				lines = append(lines, "250-STARTTLS")
+			}
+			lines = append(lines, "250-AUTH PLAIN", "250 8BITMIME")
+			_ = text.PrintfLine("%s", strings.Join(lines, "\r\n"))
+		case verb == "STARTTLS" && s.startTLS && !mail.tls:
+			_ = text.PrintfLine("220 ready to start TLS")
+			var secure = tls.Server(conn, s.config)
+			if secure.Handshake() != nil {
+				return
+			}
+			conn = secure
+			text = textproto.NewConn(secure)
+			mail.tls = true
+		case verb == "AUTH":
+			var fields = strings.Fields(line)
+			decoded, _ := base64.StdEncoding.DecodeString(fields[len(fields)-1])
+			var credentials = strings.Split(string(decoded), "\x00")
+			if len(fields) == 3 && strings.ToUpper(fields[1]) == "PLAIN" && len(credentials) == 3 &&
+				credentials[1] == s.user && credentials[2] == s.password {
+				mail.user = credentials[1]
+				_ = text.PrintfLine("235 authenticated")
+			} else {
+				_ = text.PrintfLine("535 authentication failed")
+			}
+		case verb == "MAIL":
+			mail.from = envelopeAddress(line)
+			_ = text.PrintfLine("250 ok")
+		case verb == "RCPT":
+			mail.to = append(mail.to, envelopeAddress(line))
+			_ = text.PrintfLine("250 ok")
+		case verb == "DATA":
+			_ = text.PrintfLine("354 go ahead")
+			data, err := text.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			mail.data = string(data)
+			s.lock.Lock()
+			s.mails = append(s.mails, mail)
+			s.lock.Unlock()
+			_ = text.PrintfLine("250 queued")
+		case verb == "QUIT":
+			_ = text.PrintfLine("221 bye")
+			return
+		case verb == "RSET" || verb == "NOOP":
+			_ = text.PrintfLine("250 ok")
+		default:
+			_ = text.PrintfLine("502 command not implemented")
+		}
+	}
+}
+
+// Extracts the address of the MAIL and RCPT commands, ignoring the command parameters
+func envelopeAddress(line string) string {
+	var start, end = strings.Index(line, "<"), strings.Index(line, ">")
+	if start < 0 || end < start {
+		return ""
+	}
+	return line[start+1 : end]
+}
+
+func selfSignedCertificate(t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %v", err)
+	}
+	var template = x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "fake smtp"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %v", err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func newMailScheduler(config model.MailConfig) *scheduler {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	s.mail = config
+	return s
+}
+
+func TestMailPlainAuth(t *testing.T) {
+	var server = newSMTPServer(t, false, "cron", "secret")
+	var s = newMailScheduler(model.MailConfig{Server: server.address(), Username: "cron", Password: "secret",
+		From: "Go Cron <cron@example.com>", To: []string{"ops@example.com", "Dev <dev@example.com>"}})
+	notify(s, newNotifyExecution("backup"), model.RunRecord{RunID: "run-1", Outcome: model.OutcomeFailure, Error: "exit status 2", Stdout: "dump started"})
+
+	var mails = server.received()
+	if len(mails) != 1 {
+		t.Fatalf("Expected 1 mail, found %d", len(mails))
+	}
+	var mail = mails[0]
+	if mail.user != "cron" || mail.tls {
+		t.Errorf("Expected PLAIN authentication of user cron without TLS, found user '%s' and TLS %v", mail.user, mail.tls)
+	}
+	if mail.from != "cron@example.com" || strings.Join(mail.to, ",") != "ops@example.com,dev@example.com" {
+		t.Errorf("Unexpected envelope: from %s to %v", mail.from, mail.to)
+	}
+	for _, expected := range []string{"Subject: go-cron: backup failure", "To: <ops@example.com>, \"Dev\" <dev@example.com>",
+		"X-Go-Cron-Task: task-1", "Error:     exit status 2", "dump started"} {
+		if !strings.Contains(mail.data, expected) {
+			t.Errorf("Missing '%s' in mail:\n%s", expected, mail.data)
+		}
+	}
+	var deliveries = s.Deliveries("task-1", 0)
+	if len(deliveries) != 1 || !deliveries[0].Delivered || deliveries[0].Webhook != "mail" || deliveries[0].Event != model.NotifyFailure {
+		t.Errorf("Unexpected delivery log: %+v", deliveries)
+	}
+}
+
+func TestMailPlainAuthRejected(t *testing.T) {
+	var server = newSMTPServer(t, false, "cron", "secret")
+	var s = newMailScheduler(model.MailConfig{Server: server.address(), Username: "cron", Password: "wrong", To: []string{"ops@example.com"}})
+	notify(s, newNotifyExecution("backup"), model.RunRecord{Outcome: model.OutcomeFailure})
+
+	if len(server.received()) != 0 {
+		t.Errorf("Expected no mails, found %d", len(server.received()))
+	}
+	var deliveries = s.Deliveries("", 0)
+	if len(deliveries) != 1 || deliveries[0].Delivered || !strings.Contains(deliveries[0].Error, "535") {
+		t.Errorf("Expected authentication failure in the delivery log, found: %+v", deliveries)
+	}
+}
+
+func TestMailStartTLS(t *testing.T) {
+	var tests = []struct {
+		name      string
+		advertise bool
+		mode      model.StartTLSMode
+		delivered bool
+		tls       bool
+		error     string
+	}{
+		{"auto upgrades", true, model.StartTLSAuto, true, true, ""},
+		{"auto falls back to plain", false, model.StartTLSAuto, true, false, ""},
+		{"always upgrades", true, model.StartTLSAlways, true, true, ""},
+		{"always refuses plain", false, model.StartTLSAlways, false, false, "doesn't support STARTTLS"},
+		{"never stays plain", true, model.StartTLSNever, true, false, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var server = newSMTPServer(t, test.advertise, "cron", "secret")
+			var s = newMailScheduler(model.MailConfig{Server: server.address(), Username: "cron", Password: "secret",
+				StartTLS: test.mode, InsecureSkipVerify: true, To: []string{"ops@example.com"}})
+			notify(s, newNotifyExecution("backup"), model.RunRecord{Outcome: model.OutcomeFailure})
+
+			var deliveries = s.Deliveries("", 0)
+			if len(deliveries) != 1 || deliveries[0].Delivered != test.delivered {
+				t.Fatalf("Expected delivered %v, found: %+v", test.delivered, deliveries)
+			}
+			if !strings.Contains(deliveries[0].Error, test.error) {
+				t.Errorf("Expected error containing '%s', found '%s'", test.error, deliveries[0].Error)
+			}
+			var mails = server.received()
+			if !test.delivered {
+				if len(mails) != 0 {
+					t.Errorf("Expected no mails, found %d", len(mails))
+				}
+				return
+			}
+			if len(mails) != 1 || mails[0].tls != test.tls || mails[0].user != "cron" {
+				t.Errorf("Expected 1 authenticated mail with TLS %v, found: %+v", test.tls, mails)
+			}
+		})
+	}
+}
+
+func TestMailStartTLSVerifiesCertificate(t *testing.T) {
+	var server = newSMTPServer(t, true, "", "")
+	var s = newMailScheduler(model.MailConfig{Server: server.address(), StartTLS: model.StartTLSAlways, To: []string{"ops@example.com"}})
+	notify(s, newNotifyExecution("backup"), model.RunRecord{Outcome: model.OutcomeFailure})
+
+	var deliveries = s.Deliveries("", 0)
+	if len(deliveries) != 1 || deliveries[0].Delivered || !strings.Contains(deliveries[0].Error, "certificate") {
+		t.Errorf("Expected certificate verification failure, found: %+v", deliveries)
+	}
+}
+
+func TestMailOnlyOnFailure(t *testing.T) {
+	var server = newSMTPServer(t, false, "", "")
+	var s = newMailScheduler(model.MailConfig{Server: server.address(), To: []string{"ops@example.com"}})
+	var execution = newNotifyExecution("backup")
+	execution.Command.Mail = &model.MailConfig{OnlyOnFailure: true}
+	var runs = []struct {
+		outcome model.RunOutcome
+		subject string
+	}{
+		{model.OutcomeSuccess, ""},
+		{model.OutcomeFailure, "go-cron: backup failure"},
+		{model.OutcomeTimeout, "go-cron: backup timeout"},
+		// Recovery runs are successful runs
+		{model.OutcomeSuccess, ""},
+		{model.OutcomeCancelled, ""},
+	}
+	var expected = make([]string, 0)
+	for idx, run := range runs {
+		notify(s, execution, model.RunRecord{Outcome: run.outcome})
+		if run.subject != "" {
+			expected = append(expected, run.subject)
+		}
+		var mails = server.received()
+		if len(mails) != len(expected) {
+			t.Fatalf("Run %d (%s): expected %d mails, found %d", idx, run.outcome, len(expected), len(mails))
+		}
+		if run.subject != "" && !strings.Contains(mails[len(mails)-1].data, "Subject: "+run.subject) {
+			t.Errorf("Run %d (%s): expected subject '%s' in mail:\n%s", idx, run.outcome, run.subject, mails[len(mails)-1].data)
+		}
+	}
+
+	// Without the filter successful runs are mailed too
+	execution.Command.Mail = nil
+	notify(s, execution, model.RunRecord{Outcome: model.OutcomeSuccess})
+	if len(server.received()) != len(expected)+1 {
+		t.Errorf("Expected successful run mail, found %d mails", len(server.received()))
+	}
+}
+
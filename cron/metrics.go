@@ -0,0 +1,78 @@
+package cron
+
+import (
+	"github.com/hellgate75/go-cron/metrics"
+	"github.com/hellgate75/go-cron/model"
+	"io"
+	"sync"
+	"time"
+)
+
+// Scheduler and tasks metrics, fed by the executor and exposed in the Prometheus text exposition format
+type schedulerMetrics struct {
+	registry    *metrics.Registry
+	runs        *metrics.Vec
+	failures    *metrics.Vec
+	timeouts    *metrics.Vec
+	duration    *metrics.Vec
+	lastSuccess *metrics.Vec
+	next        *metrics.Vec
+	running     *metrics.Vec
+	loopLag     *metrics.Vec
+	queueDepth  *metrics.Vec
+	// Serializes the scrapes, the collected gauges are rebuilt and written under one lock
+	collect     sync.Mutex
+}
+
+func newSchedulerMetrics() *schedulerMetrics {
+	var r = metrics.NewRegistry()
+	return &schedulerMetrics{
+		registry:    r,
+		runs:        r.Counter("go_cron_task_runs_total", "Completed task run attempts.", "task", "name"),
+		failures:    r.Counter("go_cron_task_failures_total", "Failed, timed out or cancelled task run attempts.", "task", "name"),
+		timeouts:    r.Counter("go_cron_task_timeouts_total", "Timed out task run attempts.", "task", "name"),
+		duration:    r.Histogram("go_cron_task_duration_seconds", "Task run attempts duration.", nil, "task", "name"),
+		lastSuccess: r.Gauge("go_cron_task_last_success_timestamp_seconds", "Completion time of the last successful task run attempt.", "task", "name"),
+		next:        r.Gauge("go_cron_task_next_run_timestamp_seconds", "Next scheduled task run time.", "task", "name"),
+		running:     r.Gauge("go_cron_task_running", "Currently running task runs.", "task", "name"),
+		loopLag:     r.Gauge("go_cron_scheduler_loop_lag_seconds", "Delay of the last scheduler loop iteration from its planned time."),
+		queueDepth:  r.Gauge("go_cron_scheduler_queue_depth", "Task runs due and waiting for a running slot."),
+	}
+}
+
+// Records the outcome and duration of a completed run attempt
+func (m *schedulerMetrics) observeRun(id string, name string, record model.RunRecord) {
+	m.runs.Inc(id, name)
+	m.duration.Observe(record.Duration.Seconds(), id, name)
+	switch record.Outcome {
+	case model.OutcomeSuccess:
+		m.lastSuccess.Set(seconds(record.End), id, name)
+	case model.OutcomeTimeout:
+		m.timeouts.Inc(id, name)
+		m.failures.Inc(id, name)
+	default:
+		m.failures.Inc(id, name)
+	}
+}
+
+func seconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
+
+// Writes the scheduler and tasks metrics, next run time and running runs are collected from the current
+// executions state
+func (s *scheduler) WriteMetrics(w io.Writer) error {
+	s.metrics.collect.Lock()
+	defer s.metrics.collect.Unlock()
+	s.metrics.next.Reset()
+	s.metrics.running.Reset()
+	s.execMutex.Lock()
+	for _, execution := range s.runningTasks {
+		if !execution.Next.IsZero() && execution.Command.IsEnabled() && !execution.Command.DependencyOnly() {
+			s.metrics.next.Set(seconds(execution.Next), execution.UUID, execution.Command.Name)
+		}
+		s.metrics.running.Set(float64(execution.Running), execution.UUID, execution.Command.Name)
+	}
+	s.execMutex.Unlock()
+	return s.metrics.registry.WriteText(w)
+}
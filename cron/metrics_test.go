@@ -0,0 +1,81 @@
+package cron
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	var sc = newScheduler(store.NewMemoryStore(), false)
+	var shell = model.CommandSpec{Kind: model.CommandKindShell, Shell: "true"}
+	if err := sc.AddToCache(model.CommandConfig{Name: "backup", Schedule: "@daily", Command: shell}); err != nil {
+		t.Fatalf("Unable to add task: %v", err)
+	}
+	if err := sc.AddToCache(model.CommandConfig{Name: "report", DependsOn: []model.Dependency{{Task: "backup"}}, Command: shell}); err != nil {
+		t.Fatalf("Unable to add task: %v", err)
+	}
+	var executions = prepareExecutions(sc)
+	if len(executions) != 2 {
+		t.Fatalf("Expected 2 executions, found %d", len(executions))
+	}
+	var backup = executions[0]
+	backup.Next = time.Unix(1700003600, 0)
+	sc.metrics.observeRun(backup.UUID, "backup", model.RunRecord{
+		Outcome:  model.OutcomeSuccess,
+		Duration: 200 * time.Millisecond,
+		End:      time.Unix(1700000000, 0),
+	})
+	sc.metrics.observeRun(backup.UUID, "backup", model.RunRecord{Outcome: model.OutcomeTimeout, Duration: 2 * time.Second})
+
+	var expected = []string{
+		fmt.Sprintf("go_cron_task_runs_total{task=\"%s\",name=\"backup\"} 2\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_failures_total{task=\"%s\",name=\"backup\"} 1\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_timeouts_total{task=\"%s\",name=\"backup\"} 1\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_duration_seconds_bucket{task=\"%s\",name=\"backup\",le=\"0.25\"} 1\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_duration_seconds_bucket{task=\"%s\",name=\"backup\",le=\"+Inf\"} 2\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_duration_seconds_count{task=\"%s\",name=\"backup\"} 2\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_last_success_timestamp_seconds{task=\"%s\",name=\"backup\"} 1.7e+09\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_next_run_timestamp_seconds{task=\"%s\",name=\"backup\"} 1.7000036e+09\n", backup.UUID),
+		fmt.Sprintf("go_cron_task_running{task=\"%s\",name=\"backup\"} 0\n", backup.UUID),
+		"# TYPE go_cron_task_duration_seconds histogram\n",
+		"# TYPE go_cron_scheduler_queue_depth gauge\n",
+	}
+	var out bytes.Buffer
+	if err := sc.WriteMetrics(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var text = out.String()
+	for _, line := range expected {
+		if !strings.Contains(text, line) {
+			t.Errorf("Missing line %q in exposition text:\n%s", line, text)
+		}
+	}
+	// Dependency only tasks have no next run time
+	if strings.Contains(text, "go_cron_task_next_run_timestamp_seconds{task=\""+executions[1].UUID) {
+		t.Errorf("Unexpected next run time of dependency only task:\n%s", text)
+	}
+
+	// Concurrent scrapes must always render the collected series
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				var out bytes.Buffer
+				_ = sc.WriteMetrics(&out)
+				if !strings.Contains(out.String(), expected[7]) || !strings.Contains(out.String(), expected[8]) {
+					t.Errorf("Concurrent scrape lost the collected series:\n%s", out.String())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,45 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/store"
+)
+
+// Rewrites the configuration file, the command config files and the running tasks file in the current format,
+// converting the untyped commands stored by previous versions, and returns the number of migrated tasks
+func (s *scheduler) migrate() (int, error) {
+	var count int
+	for idx, ref := range s.commands {
+		cmd, err := s.loadItem(ref.UUID)
+		if err != nil {
+			return count, errors.New(fmt.Sprintf("Unable to migrate task id: %s, error: %v", ref.UUID, err))
+		}
+		if err = cmd.Command.Validate(); err != nil {
+			return count, errors.New(fmt.Sprintf("Unable to migrate task id: %s, error: %v", ref.UUID, err))
+		}
+		// The reference command is a copy of the stored one, legacy text encodings could not describe all of them
+		s.commands[idx].Command = cmd.Command
+		if err = s.saveItem(ref.UUID, *cmd); err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := s.save(); err != nil {
+		return count, err
+	}
+	return count, s.saveExecutions()
+}
+
+// Loads an existing scheduler and stores its configuration in the current format, converting the untyped
+// commands stored by previous versions. It returns the number of migrated tasks
+func MigrateScheduler(file string, encoding io.Encoding) (int, error) {
+	var sc = newScheduler(store.NewFileStore(file, encoding), false)
+	defer sc.Destroy(false)
+	var err = sc.Load()
+	if err != nil {
+		return 0, err
+	}
+	return sc.migrate()
+}
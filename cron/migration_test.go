@@ -0,0 +1,74 @@
+package cron
+
+import (
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Configuration file written before the typed command specs, with untyped command values
+const legacyConfig = `{
+	"commands": [
+		{"uuid": "11111111-1111-1111-1111-111111111111", "name": "backup", "command": "tar -czf /tmp/backup.tgz /data"},
+		{"uuid": "22222222-2222-2222-2222-222222222222", "name": "report", "command": ["/usr/bin/report", "--daily"]},
+		{"uuid": "33333333-3333-3333-3333-333333333333", "name": "pipeline", "command": {"mode": "parallel", "steps": ["echo first", ["echo", "second"], ["echo nested"]]}}
+	]
+}`
+
+func TestMigrateScheduler(t *testing.T) {
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "config.json")
+	files, err := filepath.Glob(filepath.Join("..", "store", "testdata", "legacy", "*.gob"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Unable to find the legacy fixtures: %v", err)
+	}
+	for _, fixture := range files {
+		data, err := os.ReadFile(fixture)
+		if err != nil {
+			t.Fatalf("Unable to read fixture %s: %v", fixture, err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, filepath.Base(fixture)), data, 0600); err != nil {
+			t.Fatalf("Unable to copy fixture %s: %v", fixture, err)
+		}
+	}
+	if err = os.WriteFile(file, []byte(legacyConfig), 0600); err != nil {
+		t.Fatalf("Unable to write the legacy configuration: %v", err)
+	}
+	count, err := MigrateScheduler(file, io.EncodingJson)
+	if err != nil || count != 3 {
+		t.Fatalf("Expected 3 migrated tasks, found %d, error: %v", count, err)
+	}
+	// The migrated files are read in the current format
+	var config model.SchedulerConfig
+	if err = io.ReadConfig(io.EncodingJson, file, &config); err != nil {
+		t.Fatalf("Unable to read the migrated configuration: %v", err)
+	}
+	var expected = map[string]model.CommandKind{
+		"backup":   model.CommandKindShell,
+		"report":   model.CommandKindExec,
+		"pipeline": model.CommandKindComposite,
+	}
+	var fs = store.NewFileStore(file, io.EncodingJson)
+	for _, ref := range config.Commands {
+		if ref.Command.Kind != expected[ref.Name] {
+			t.Errorf("%s: expected reference command kind %s, found %s", ref.Name, expected[ref.Name], ref.Command.Kind)
+		}
+		var cmd *model.CommandConfig
+		if err = io.ReadNative(filepath.Join(dir, ref.UUID+".gob"), &cmd); err != nil || cmd == nil {
+			t.Errorf("%s: migrated item not readable in the current format: %v", ref.Name, err)
+		} else if cmd.Command.String() != ref.Command.String() {
+			t.Errorf("%s: expected reference command %s, found %s", ref.Name, cmd.Command, ref.Command)
+		}
+	}
+	executions, err := fs.LoadExecutions()
+	if err != nil || len(executions) != 3 {
+		t.Fatalf("Expected 3 migrated running tasks, found %d, error: %v", len(executions), err)
+	}
+	var saved = make([]model.Execution, 0)
+	if err = io.ReadNative(filepath.Join(dir, "executions.gob"), &saved); err != nil || len(saved) != 3 {
+		t.Errorf("Migrated running tasks not readable in the current format: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Verifies the webhooks consistency
+func validateWebhooks(webhooks []model.Webhook) error {
+	for _, hook := range webhooks {
+		if err := hook.Validate(); err != nil {
+			return err
+		}
+		if err := validateRetryConfig(hook.Retry); err != nil {
+			return errors.New(fmt.Sprintf("Invalid webhook %s retry policy: %v", hook.DisplayName(), err))
+		}
+	}
+	return nil
+}
+
+// Retrieves the final outcome of the previous run of the task, from the last history record when the task
+// didn't run since the scheduler started
+func (s *scheduler) previousOutcome(id string) model.RunOutcome {
+	s.deliveriesMutex.Lock()
+	outcome, ok := s.lastOutcomes[id]
+	s.deliveriesMutex.Unlock()
+	if ok {
+		return outcome
+	}
+	var records = s.History(model.HistoryFilter{UUID: id, Limit: 1})
+	if len(records) == 0 {
+		return ""
+	}
+	return records[0].Outcome
+}
+
+// Collects the notified events of a run final attempt. Cancelled runs are not notified
+func runNotifyEvents(previous model.RunOutcome, record model.RunRecord, retry model.RetryConfig) []model.NotifyEvent {
+	var events = make([]model.NotifyEvent, 0)
+	switch record.Outcome {
+	case model.OutcomeSuccess:
+		events = append(events, model.NotifySuccess)
+		if previous == model.OutcomeFailure || previous == model.OutcomeTimeout {
+			events = append(events, model.NotifyRecovery)
+		}
+	case model.OutcomeFailure, model.OutcomeTimeout:
+		events = append(events, model.NotifyFailure)
+		if record.Outcome == model.OutcomeTimeout {
+			events = append(events, model.NotifyTimeout)
+		}
+		if retry.Attempts() > 1 && record.Attempt >= retry.Attempts() {
+			events = append(events, model.NotifyRetryExhausted)
+		}
+	}
+	return events
+}
+
+// Sends the notifications of a run final attempt by mail and to the task and the scheduler webhooks. Deliveries
+// run in the background, so the notifications never delay the task runs
+func (s *scheduler) notifyRun(execution *model.Execution, id string, previous model.RunOutcome, record model.RunRecord) {
+	if record.Outcome == "" {
+		return
+	}
+	s.deliveriesMutex.Lock()
+	if record.Outcome != model.OutcomeCancelled {
+		s.lastOutcomes[id] = record.Outcome
+	}
+	var webhooks = append(append([]model.Webhook{}, execution.Command.Webhooks...), s.webhooks...)
+	s.deliveriesMutex.Unlock()
+	var events = runNotifyEvents(previous, record, execution.Command.Retry)
+	s.mailRun(execution, id, previous, record, events)
+	for _, hook := range webhooks {
+		var event = hook.Match(events)
+		if event == "" {
+			continue
+		}
+		var notification = model.Notification{Event: event, UUID: id, Name: execution.Command.Name, Previous: previous, Run: record}
+		s.notifications.Add(1)
+		go func(hook model.Webhook) {
+			defer func() {
+				if r := recover(); r != nil {
+					s.publishError(errors.New(fmt.Sprintf("%v", r)))
+				}
+				s.notifications.Done()
+			}()
+			s.deliver(hook, notification)
+		}(hook)
+	}
+}
+
+// Sends the notification to the webhook, retrying the failed deliveries accordingly to the webhook retry
+// policy. Retries are abandoned when the scheduler stops
+func (s *scheduler) deliver(hook model.Webhook, notification model.Notification) {
+	var attempts = hook.Retry.Attempts()
+	body, errR := hook.Render(notification)
+	if errR != nil {
+		// Body template errors are not retried
+		attempts = 1
+	}
+	for attempt := 1; attempt <= attempts; attempt++ {
+		var delivery = model.WebhookDelivery{
+			Time:    time.Now(),
+			Webhook: hook.DisplayName(),
+			URL:     hook.URL,
+			Event:   notification.Event,
+			UUID:    notification.UUID,
+			RunID:   notification.Run.RunID,
+			Attempt: attempt,
+		}
+		var err error
+		if errR != nil {
+			err = errors.New(fmt.Sprintf("Invalid webhook body: %v", errR))
+		} else {
+			delivery.StatusCode, err = sendWebhook(hook, body)
+		}
+		delivery.Duration = time.Since(delivery.Time)
+		delivery.Delivered = err == nil
+		if err != nil {
+			delivery.Error = err.Error()
+		}
+		if errD := s.addDelivery(delivery); errD != nil {
+			s.publishError(errors.New(fmt.Sprintf("Unable to save webhook delivery log, error: %v", errD)))
+		}
+		if err == nil {
+			return
+		}
+		s.events.Publish(model.Event{Type: model.EventWarning, UUID: notification.UUID, Name: notification.Name,
+			RunID: notification.Run.RunID, Attempt: attempt, Error: err.Error(),
+			Message: fmt.Sprintf("Webhook %s delivery of %s event failed, attempt %d of %d, error: %v",
+				hook.DisplayName(), notification.Event, attempt, attempts, err)})
+		if attempt == attempts {
+			return
+		}
+		select {
+		case <-time.After(hook.Retry.DelayAfter(attempt)):
+		case <-s.baseContext().Done():
+			return
+		}
+	}
+}
+
+// Sends the webhook request and returns the response status code, non 2xx status codes are errors
+func sendWebhook(hook model.Webhook, body string) (int, error) {
+	var ctx, cancel = context.WithTimeout(context.Background(), hook.TimeoutDuration())
+	defer cancel()
+	request, err := http.NewRequestWithContext(ctx, hook.RequestMethod(), hook.URL, strings.NewReader(body))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Invalid webhook request: %v", err))
+	}
+	for _, h := range hook.Headers {
+		request.Header.Add(h.Name, h.Value)
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	_, _ = ioutil.ReadAll(response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, errors.New(fmt.Sprintf("Webhook request failed with status: %s", response.Status))
+	}
+	return response.StatusCode, nil
+}
+
+// Load the webhook delivery log form the store
+func (s *scheduler) loadDeliveries() error {
+	var err error
+	var records []model.WebhookDelivery
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+		s.deliveriesMutex.Unlock()
+	}()
+	s.deliveriesMutex.Lock()
+	records, err = s.store.LoadDeliveries()
+	err = s.recovered(err)
+	if err == nil {
+		s.deliveries = records
+	}
+	return err
+}
+
+// Add a delivery to the webhook delivery log, keeping the most recent records, and save the log to the store
+func (s *scheduler) addDelivery(delivery model.WebhookDelivery) error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+		s.deliveriesMutex.Unlock()
+	}()
+	s.deliveriesMutex.Lock()
+	s.deliveries = append(s.deliveries, delivery)
+	if len(s.deliveries) > model.DefaultDeliveryLogRecords {
+		s.deliveries = s.deliveries[len(s.deliveries)-model.DefaultDeliveryLogRecords:]
+	}
+	err = s.store.SaveDeliveries(s.deliveries)
+	return err
+}
+
+// Retrieves the webhook delivery log of the task with given unique identifier (all tasks when empty), in
+// chronological order, limited to the given number of most recent records (all when zero or less)
+func (s *scheduler) Deliveries(uuid string, limit int) []model.WebhookDelivery {
+	s.deliveriesMutex.Lock()
+	defer s.deliveriesMutex.Unlock()
+	var out = make([]model.WebhookDelivery, 0)
+	for _, d := range s.deliveries {
+		if uuid == "" || d.UUID == uuid {
+			out = append(out, d)
+		}
+	}
+	if limit > 0 && len(out) > limit {
+		out = out[len(out)-limit:]
+	}
+	return out
+}
@@ -0,0 +1,212 @@
+package cron
+
+import (
+	"encoding/json"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// Local webhook endpoint, answering with the queued status codes (200 when empty) and recording the requests
+type webhookServer struct {
+	*httptest.Server
+	lock     sync.Mutex
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+}
+
+func newWebhookServer(t *testing.T, statuses ...int) *webhookServer {
+	var ws = &webhookServer{statuses: statuses}
+	ws.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		ws.lock.Lock()
+		ws.bodies = append(ws.bodies, string(body))
+		ws.headers = append(ws.headers, r.Header.Clone())
+		var status = http.StatusOK
+		if len(ws.statuses) > 0 {
+			status, ws.statuses = ws.statuses[0], ws.statuses[1:]
+		}
+		ws.lock.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ws.Close)
+	return ws
+}
+
+func (ws *webhookServer) requests() []string {
+	ws.lock.Lock()
+	defer ws.lock.Unlock()
+	return append([]string{}, ws.bodies...)
+}
+
+func newNotifyExecution(name string, webhooks ...model.Webhook) *model.Execution {
+	return &model.Execution{
+		UUID:    "task-1",
+		Command: model.CommandConfig{Name: name, Webhooks: webhooks},
+	}
+}
+
+func notify(s *scheduler, execution *model.Execution, record model.RunRecord) {
+	record.UUID = execution.UUID
+	s.notifyRun(execution, execution.UUID, s.previousOutcome(execution.UUID), record)
+	s.notifications.Wait()
+}
+
+func TestWebhookTemplateRendering(t *testing.T) {
+	var ws = newWebhookServer(t)
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var hook = model.Webhook{
+		URL:     ws.URL,
+		Headers: []model.HttpHeader{{Name: "X-Token", Value: "secret"}},
+		Body:    `{"text":"{{.Name}} {{.Event}}","error":{{json .Run.Error}},"code":{{.Run.ExitCode}}}`,
+	}
+	if err := hook.Validate(); err != nil {
+		t.Fatalf("Unexpected webhook validation error: %v", err)
+	}
+	notify(s, newNotifyExecution("backup", hook), model.RunRecord{RunID: "run-1", Outcome: model.OutcomeFailure, ExitCode: 2, Error: "disk \"full\""})
+
+	var bodies = ws.requests()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 request, found %d", len(bodies))
+	}
+	var expected = `{"text":"backup failure","error":"disk \"full\"","code":2}`
+	if bodies[0] != expected {
+		t.Errorf("Unexpected body: %s, expected: %s", bodies[0], expected)
+	}
+	if ws.headers[0].Get("X-Token") != "secret" || ws.headers[0].Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected request headers: %v", ws.headers[0])
+	}
+}
+
+func TestWebhookDefaultBody(t *testing.T) {
+	var ws = newWebhookServer(t)
+	var s = newScheduler(store.NewMemoryStore(), false)
+	s.webhooks = []model.Webhook{{URL: ws.URL, On: []model.NotifyEvent{model.NotifySuccess}}}
+	notify(s, newNotifyExecution("backup"), model.RunRecord{RunID: "run-1", Outcome: model.OutcomeSuccess})
+
+	var bodies = ws.requests()
+	if len(bodies) != 1 {
+		t.Fatalf("Expected 1 request, found %d", len(bodies))
+	}
+	var notification model.Notification
+	if err := json.Unmarshal([]byte(bodies[0]), &notification); err != nil {
+		t.Fatalf("Body is not a notification: %v", err)
+	}
+	if notification.Event != model.NotifySuccess || notification.UUID != "task-1" || notification.Name != "backup" ||
+		notification.Run.RunID != "run-1" {
+		t.Errorf("Unexpected notification: %+v", notification)
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	var ws = newWebhookServer(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusOK)
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var hook = model.Webhook{Name: "alerts", URL: ws.URL, Retry: model.RetryConfig{MaxAttempts: 4, Delay: "10ms"}}
+	notify(s, newNotifyExecution("backup", hook), model.RunRecord{RunID: "run-1", Outcome: model.OutcomeFailure})
+
+	if len(ws.requests()) != 3 {
+		t.Fatalf("Expected 3 requests, found %d", len(ws.requests()))
+	}
+	var deliveries = s.Deliveries("task-1", 0)
+	var expected = []struct {
+		status    int
+		delivered bool
+	}{
+		{http.StatusInternalServerError, false},
+		{http.StatusBadGateway, false},
+		{http.StatusOK, true},
+	}
+	if len(deliveries) != len(expected) {
+		t.Fatalf("Expected %d deliveries, found %d", len(expected), len(deliveries))
+	}
+	for idx, d := range deliveries {
+		if d.Attempt != idx+1 || d.StatusCode != expected[idx].status || d.Delivered != expected[idx].delivered ||
+			d.Webhook != "alerts" || d.Event != model.NotifyFailure || d.RunID != "run-1" {
+			t.Errorf("Unexpected delivery %d: %+v", idx, d)
+		}
+		if d.Delivered != (d.Error == "") {
+			t.Errorf("Delivery %d error must be reported only on failure: %+v", idx, d)
+		}
+	}
+}
+
+func TestWebhookRetriesExhausted(t *testing.T) {
+	var ws = newWebhookServer(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusOK)
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var hook = model.Webhook{URL: ws.URL, Retry: model.RetryConfig{MaxAttempts: 2, Delay: "10ms"}}
+	notify(s, newNotifyExecution("backup", hook), model.RunRecord{Outcome: model.OutcomeFailure})
+
+	var deliveries = s.Deliveries("", 0)
+	if len(ws.requests()) != 2 || len(deliveries) != 2 {
+		t.Fatalf("Expected 2 attempts, found %d requests and %d deliveries", len(ws.requests()), len(deliveries))
+	}
+	if deliveries[1].Delivered || deliveries[1].Webhook != ws.URL {
+		t.Errorf("Unexpected last delivery: %+v", deliveries[1])
+	}
+}
+
+func TestWebhookBodyErrorNotRetried(t *testing.T) {
+	var ws = newWebhookServer(t)
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var hook = model.Webhook{URL: ws.URL, Body: "{{.Missing}}", Retry: model.RetryConfig{MaxAttempts: 3, Delay: "10ms"}}
+	notify(s, newNotifyExecution("backup", hook), model.RunRecord{Outcome: model.OutcomeFailure})
+
+	var deliveries = s.Deliveries("", 0)
+	if len(ws.requests()) != 0 {
+		t.Errorf("Expected no requests, found %d", len(ws.requests()))
+	}
+	if len(deliveries) != 1 || deliveries[0].Delivered || deliveries[0].Error == "" {
+		t.Errorf("Expected one failed delivery, found: %+v", deliveries)
+	}
+}
+
+func TestWebhookDeliveryLogPerOutcome(t *testing.T) {
+	var ws = newWebhookServer(t)
+	var s = newScheduler(store.NewMemoryStore(), false)
+	s.webhooks = []model.Webhook{{URL: ws.URL, On: []model.NotifyEvent{model.NotifyFailure, model.NotifyTimeout, model.NotifyRecovery}}}
+	var execution = newNotifyExecution("backup")
+	var runs = []struct {
+		outcome model.RunOutcome
+		event   model.NotifyEvent
+	}{
+		// Success of a task that never failed is not notified
+		{model.OutcomeSuccess, ""},
+		{model.OutcomeFailure, model.NotifyFailure},
+		{model.OutcomeSuccess, model.NotifyRecovery},
+		{model.OutcomeTimeout, model.NotifyTimeout},
+		// Cancelled runs are not notified and don't change the previous outcome
+		{model.OutcomeCancelled, ""},
+		{model.OutcomeSuccess, model.NotifyRecovery},
+		{model.OutcomeSuccess, ""},
+	}
+	var expected = make([]model.NotifyEvent, 0)
+	for idx, run := range runs {
+		notify(s, execution, model.RunRecord{RunID: string(rune('a' + idx)), Outcome: run.outcome})
+		if run.event != "" {
+			expected = append(expected, run.event)
+		}
+		var deliveries = s.Deliveries("task-1", 0)
+		if len(deliveries) != len(expected) {
+			t.Fatalf("Run %d (%s): expected %d deliveries, found %d", idx, run.outcome, len(expected), len(deliveries))
+		}
+		if run.event != "" {
+			var last = deliveries[len(deliveries)-1]
+			if last.Event != run.event || !last.Delivered || last.RunID != string(rune('a'+idx)) {
+				t.Errorf("Run %d (%s): unexpected delivery %+v", idx, run.outcome, last)
+			}
+		}
+	}
+	if len(ws.requests()) != len(expected) {
+		t.Errorf("Expected %d requests, found %d", len(expected), len(ws.requests()))
+	}
+	// The delivery log is persisted in the store
+	stored, err := s.store.LoadDeliveries()
+	if err != nil || len(stored) != len(expected) {
+		t.Errorf("Expected %d stored deliveries, found %d (error: %v)", len(expected), len(stored), err)
+	}
+}
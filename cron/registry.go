@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/utils"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Creates the ComputableValue run by a func command, from the command arguments
+type ComputableFactory func(args model.FuncArgs) (model.ComputableValue, error)
+
+var registry = make(map[string]ComputableFactory)
+var registryMutex sync.RWMutex
+
+// Registers the ComputableValue run by the func commands with given name. Use model.ComputableFunc to
+// register a Go function. Command arguments are available in the ExecutionContext Arguments field
+func Register(name string, value model.ComputableValue) error {
+	if value == nil {
+		return errors.New(fmt.Sprintf("Nil function cannot be registered with name: %s", name))
+	}
+	return RegisterFactory(name, func(args model.FuncArgs) (model.ComputableValue, error) {
+		return value, nil
+	})
+}
+
+// Registers the factory creating, from the command arguments, the ComputableValue run by the func commands
+// with given name. The factory is called at each run
+func RegisterFactory(name string, factory ComputableFactory) error {
+	if strings.TrimSpace(name) == "" {
+		return errors.New("Registered function name must not be empty")
+	}
+	if factory == nil {
+		return errors.New(fmt.Sprintf("Nil factory cannot be registered with name: %s", name))
+	}
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	if _, ok := registry[name]; ok {
+		return errors.New(fmt.Sprintf("Function already registered with name: %s", name))
+	}
+	registry[name] = factory
+	return nil
+}
+
+// Removes the function registered with given name, tasks calling it fail until it's registered again
+func Unregister(name string) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	delete(registry, name)
+}
+
+// Collects the registered function names, in alphabetical order
+func Registered() []string {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	var names = make([]string, 0)
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func registeredFactory(name string) (ComputableFactory, error) {
+	registryMutex.RLock()
+	factory, ok := registry[name]
+	registryMutex.RUnlock()
+	if !ok && len(Registered()) == 0 {
+		return nil, errors.New(fmt.Sprintf("Unknown registered function: %s, no function is registered in the scheduler process", name))
+	} else if !ok {
+		return nil, errors.New(fmt.Sprintf("Unknown registered function: %s (registered: %s)", name, strings.Join(Registered(), ", ")))
+	}
+	return factory, nil
+}
+
+// Verifies that the functions called by the command, also in composite command steps, are registered
+func validateFunctions(command model.CommandSpec) error {
+	switch command.Kind {
+	case model.CommandKindFunc:
+		if command.Func != nil {
+			if _, err := registeredFactory(command.Func.Name); err != nil {
+				return err
+			}
+		}
+	case model.CommandKindComposite:
+		if command.Composite != nil {
+			for idx, step := range command.Composite.Steps {
+				if err := validateFunctions(step); err != nil {
+					return errors.New(fmt.Sprintf("Invalid composite command step %d: %v", idx+1, err))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Resolves the registered function at execution time and runs it, with the command arguments
+func runFuncCommand(ctx context.Context, scheduler *scheduler, id string, execution *model.Execution, call model.FuncCall) (utils.CommandResult, error) {
+	factory, err := registeredFactory(call.Name)
+	if err != nil {
+		return utils.CommandResult{ExitCode: -1}, err
+	}
+	computable, err := factory(call.Args)
+	if err == nil && computable == nil {
+		err = errors.New(fmt.Sprintf("Registered function %s factory returned no function", call.Name))
+	}
+	if err != nil {
+		return utils.CommandResult{ExitCode: -1}, errors.New(fmt.Sprintf("Unable to create registered function %s: %v", call.Name, err))
+	}
+	var context = createExecutionContextFrom(ctx, execution, scheduler)
+	context.Arguments = call.Args
+	err = runUntilDone(ctx, func() error {
+		return computable.Compute(context)
+	})
+	return functionResult(err), err
+}
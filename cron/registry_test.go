@@ -0,0 +1,160 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"strings"
+	"testing"
+)
+
+func funcCommand(name string) model.CommandSpec {
+	return model.NewFuncCommand(name, nil)
+}
+
+func TestRegister(t *testing.T) {
+	var noop = model.ComputableFunc(func(model.ExecutionContext) error {
+		return nil
+	})
+	var noopFactory = func(model.FuncArgs) (model.ComputableValue, error) {
+		return noop, nil
+	}
+	var cases = []struct {
+		name    string
+		value   model.ComputableValue
+		factory ComputableFactory
+		error   bool
+	}{
+		{"beta", noop, nil, false},
+		{"alpha", nil, noopFactory, false},
+		{"", noop, nil, true},
+		{" ", nil, noopFactory, true},
+		{"gamma", nil, nil, true},
+		{"beta", noop, nil, true},
+		{"alpha", nil, noopFactory, true},
+	}
+	defer func() {
+		for _, name := range Registered() {
+			Unregister(name)
+		}
+	}()
+	for _, c := range cases {
+		var err error
+		if c.factory != nil {
+			err = RegisterFactory(c.name, c.factory)
+		} else {
+			err = Register(c.name, c.value)
+		}
+		if c.error != (err != nil) {
+			t.Errorf("Function %q: expected error %v, found: %v", c.name, c.error, err)
+		}
+	}
+	if names := strings.Join(Registered(), ","); names != "alpha,beta" {
+		t.Errorf("Expected registered functions alpha,beta, found %s", names)
+	}
+	Unregister("beta")
+	Unregister("missing")
+	if names := strings.Join(Registered(), ","); names != "alpha" {
+		t.Errorf("Expected registered function alpha, found %s", names)
+	}
+	// Removed names can be registered again
+	if err := Register("beta", noop); err != nil {
+		t.Errorf("Unable to register again the function beta: %v", err)
+	}
+}
+
+func TestValidateFunctions(t *testing.T) {
+	if err := validateFunctions(funcCommand("greet")); err == nil || !strings.Contains(err.Error(), "no function is registered") {
+		t.Errorf("Expected empty registry error, found: %v", err)
+	}
+	if err := Register("greet", model.ComputableFunc(func(model.ExecutionContext) error {
+		return nil
+	})); err != nil {
+		t.Fatalf("Unable to register function: %v", err)
+	}
+	defer Unregister("greet")
+	var cases = []struct {
+		name    string
+		command model.CommandSpec
+		error   string
+	}{
+		{"shell", model.NewShellCommand("true"), ""},
+		{"registered", funcCommand("greet"), ""},
+		{"unknown", funcCommand("missing"), "Unknown registered function: missing (registered: greet)"},
+		{"composite", model.NewCompositeCommand(model.CompositeCommand{Steps: []model.CommandSpec{funcCommand("greet"),
+			model.NewShellCommand("true")}}), ""},
+		{"composite unknown", model.NewCompositeCommand(model.CompositeCommand{Steps: []model.CommandSpec{funcCommand("greet"),
+			funcCommand("missing")}}), "Invalid composite command step 2: Unknown registered function: missing"},
+		{"nested unknown", model.NewCompositeCommand(model.CompositeCommand{Steps: []model.CommandSpec{
+			model.NewCompositeCommand(model.CompositeCommand{Steps: []model.CommandSpec{funcCommand("missing")}})}}),
+			"Invalid composite command step 1: Invalid composite command step 1: Unknown registered function: missing"},
+	}
+	for _, c := range cases {
+		var err = validateFunctions(c.command)
+		if (c.error == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected error %q, found: %v", c.name, c.error, err)
+		}
+	}
+	// Func commands edited for a remote process are not verified against the local registry
+	var cmd = model.CommandConfig{Name: "remote", Command: funcCommand("missing")}
+	if err := validateCommandConfig(cmd, false); err != nil {
+		t.Errorf("Unexpected error validating a remote func command: %v", err)
+	}
+	if err := validateCommandConfig(cmd, true); err == nil {
+		t.Errorf("Expected error validating an unknown func command")
+	}
+}
+
+func TestRunFuncCommand(t *testing.T) {
+	var received interface{}
+	var factories = map[string]ComputableFactory{
+		"echo": func(args model.FuncArgs) (model.ComputableValue, error) {
+			return model.ComputableFunc(func(c model.ExecutionContext) error {
+				var err error
+				received, err = c.Arguments.Value()
+				return err
+			}), nil
+		},
+		"failing": func(model.FuncArgs) (model.ComputableValue, error) {
+			return model.ComputableFunc(func(model.ExecutionContext) error {
+				return errors.New("compute failed")
+			}), nil
+		},
+		"broken": func(model.FuncArgs) (model.ComputableValue, error) {
+			return nil, errors.New("invalid arguments")
+		},
+		"empty": func(model.FuncArgs) (model.ComputableValue, error) {
+			return nil, nil
+		},
+	}
+	for name, factory := range factories {
+		if err := RegisterFactory(name, factory); err != nil {
+			t.Fatalf("Unable to register function %s: %v", name, err)
+		}
+		defer Unregister(name)
+	}
+	args, _ := model.NewFuncArgs(map[string]interface{}{"to": "world"})
+	var cases = []struct {
+		name     string
+		exitCode int
+		error    string
+	}{
+		{"echo", 0, ""},
+		{"failing", 1, "compute failed"},
+		{"broken", -1, "Unable to create registered function broken: invalid arguments"},
+		{"empty", -1, "factory returned no function"},
+		{"missing", -1, "Unknown registered function: missing"},
+	}
+	var s = newScheduler(store.NewMemoryStore(), false)
+	for _, c := range cases {
+		var execution = &model.Execution{UUID: "id", Command: model.CommandConfig{Name: c.name}}
+		result, err := runFuncCommand(context.Background(), s, "id", execution, model.FuncCall{Name: c.name, Args: args})
+		if result.ExitCode != c.exitCode || (c.error == "") != (err == nil) || (err != nil && !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected exit code %d and error %q, found %d and %v", c.name, c.exitCode, c.error, result.ExitCode, err)
+		}
+	}
+	if value, ok := received.(map[string]interface{}); !ok || value["to"] != "world" {
+		t.Errorf("Expected the command arguments in the execution context, found %v", received)
+	}
+}
@@ -1,13 +1,15 @@
 package cron
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/hellgate75/go-cron/io"
 	"github.com/hellgate75/go-cron/model"
-	"os"
-	"path/filepath"
+	"github.com/hellgate75/go-cron/store"
+	"github.com/hellgate75/go-cron/utils"
+	"reflect"
 	"sync"
 	"time"
 )
@@ -17,78 +19,237 @@ type scheduler struct {
 	cache        	map[string]model.CommandConfig
 	commands     	[]model.CommandConfigRef
 	cacheCommands   []model.CommandConfigRef
-	runningTasks 	[]model.Execution
+	// Upstream dependencies of the tasks, by task unique identifier
+	dependsOn		map[string][]model.Dependency
+	runningTasks 	[]*model.Execution
 	syncRun      	bool
 	running      	bool
-	dir          	string
-	file         	string
-	enc          	io.Encoding
+	store        	store.Store
+	events			*model.EventBus
+	metrics			*schedulerMetrics
 	errors		 	chan error
+	errorsOnce		sync.Once
 	warnings		chan error
+	warningsOnce	sync.Once
+	errorsPipe		chan error
+	warningsPipe	chan error
 	execMutex		sync.Mutex
 	uuid			string
+	timeZone		string
+	history			[]model.RunRecord
+	historyConfig	model.HistoryConfig
+	historyMutex	sync.Mutex
+	webhooks		[]model.Webhook
+	mail			model.MailConfig
+	deliveries		[]model.WebhookDelivery
+	deliveriesMutex	sync.Mutex
+	lastOutcomes	map[string]model.RunOutcome
+	notifications	sync.WaitGroup
+	stopGracePeriod	string
+	context			context.Context
+	cancel			context.CancelFunc
+	runs			sync.WaitGroup
+	done			chan struct{}
+	maxRunning		int
+	slots			chan struct{}
+	taskSlots		map[string]chan struct{}
+	activeRuns		map[string]map[string]context.CancelFunc
+	runCounter		int
+	stopping		bool
+	// Guards the running and stopping states, changed by Start and Stop while the tasks runs read them
+	stateMutex		sync.Mutex
+	paused			bool
+	// Functions are registered in the process running the tasks (e.g.: command line editing a daemon store),
+	// so the func commands are not verified against the local registry
+	remoteFunctions	bool
 }
 
 func (s *scheduler) IsRunning() bool {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
 	return s.running
 }
 
-func (s *scheduler) Errors() chan error {
-	return s.errors
+// Stops the scheduling loop, after a fatal tasks execution error
+func (s *scheduler) halt() {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	s.running = false
 }
 
-func (s *scheduler) Warnings() chan error {
-	return s.warnings
+// Reports if the scheduler is stopping, so the runs waiting for their turn are not started
+func (s *scheduler) isStopping() bool {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	return s.stopping
+}
+
+func (s *scheduler) Location() *time.Location {
+	if loc, err := utils.LoadLocation(s.timeZone); err == nil {
+		return loc
+	}
+	return time.Local
 }
 
 func (s *scheduler) Start() error {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
 	if s.running {
 		return errors.New("scheduler is already running")
 	}
-	go func(scheduler *scheduler) {
-		for scheduler.running {
-			_ = s.loadExecutions()
-			if checkNextSchedulerTasks(scheduler) {
+	_ = s.loadExecutions()
+	s.resetRunState()
+	s.stopping = false
+	s.context, s.cancel = context.WithCancel(context.Background())
+	s.done = make(chan struct{})
+	s.running = true
+	s.events.Publish(model.Event{Type: model.EventSchedulerStarted, Message: "Scheduler started"})
+	go func(scheduler *scheduler, ctx context.Context, done chan struct{}) {
+		defer close(done)
+		var planned = time.Now()
+		for scheduler.IsRunning() {
+			scheduler.metrics.loopLag.Set(time.Since(planned).Seconds())
+			if !scheduler.isPaused() && checkNextSchedulerTasks(scheduler) {
 				err := executeSchedulerTasks(scheduler)
 				if err != nil {
-					scheduler.running=false
-					scheduler.errors <- errors.New(fmt.Sprintf("Fatal tasks execution error: %v", err))
+					scheduler.halt()
+					scheduler.publishError(errors.New(fmt.Sprintf("Fatal tasks execution error: %v", err)))
 				}
-			} else {
-				fmt.Println("No active Tasks for the scheduler")
-				time.Sleep(5 * time.Second)
+			}
+			planned = time.Now().Add(time.Second)
+			select {
+			case <-ctx.Done():
+			case <-time.After(time.Second):
 			}
 		}
-	}(s)
+	}(s, s.context, s.done)
 	return nil
 }
 
 func (s *scheduler) Wait() {
-main:
-	for s.running {
-		select {
-		case <-time.After(15 * time.Second):
-			if !s.running {
-				break main
-			}
-		}
+	s.stateMutex.Lock()
+	var done = s.done
+	s.stateMutex.Unlock()
+	if done != nil {
+		<-done
 	}
+	// Stopping tasks are cancelled at the end of the stop grace period
+	s.runs.Wait()
+	s.notifications.Wait()
 }
 
+// Stops the scheduler, running tasks have the stop grace period to complete, then they are cancelled
 func (s *scheduler) Stop() error {
+	s.stateMutex.Lock()
 	if !s.running {
+		s.stateMutex.Unlock()
 		return errors.New("scheduler not already running")
 	}
 	s.running = false
+	s.stopping = true
+	var cancel = s.cancel
+	s.stateMutex.Unlock()
+	var completed = make(chan struct{})
+	go func() {
+		s.runs.Wait()
+		close(completed)
+	}()
+	select {
+	case <-completed:
+	case <-time.After(model.SchedulerConfig{StopGracePeriod: s.stopGracePeriod}.GracePeriod()):
+	}
+	if cancel != nil {
+		cancel()
+	}
+	<-completed
+	s.events.Publish(model.Event{Type: model.EventSchedulerStopped, Message: "Scheduler stopped"})
 	return nil
 }
 
+// Clears the state of the runs left by a previous scheduler process and prepares the running tasks limit
+func (s *scheduler) resetRunState() {
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
+	for _, exec := range s.runningTasks {
+		exec.Scheduled = false
+		exec.Running = 0
+		exec.Queued = 0
+	}
+	s.slots = nil
+	if s.maxRunning > 0 {
+		s.slots = make(chan struct{}, s.maxRunning)
+	}
+}
+
+// Retrieves the scheduler context, done when the scheduler stops
+func (s *scheduler) baseContext() context.Context {
+	s.stateMutex.Lock()
+	defer s.stateMutex.Unlock()
+	if s.context == nil {
+		return context.Background()
+	}
+	return s.context
+}
+
+// Marks the execution as running and registers the run cancel function, used by the replace concurrency policy
+func (s *scheduler) startRun(execution *model.Execution, cancel context.CancelFunc) string {
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
+	// Increase number of executions
+	execution.Times++
+	execution.Last = time.Now()
+	execution.Scheduled = false
+	execution.Queued--
+	execution.Running++
+	return s.registerRunLocked(execution.UUID, cancel)
+}
+
+// Registers the run cancel function of the task, used by the replace concurrency policy
+func (s *scheduler) registerRun(id string, cancel context.CancelFunc) string {
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
+	return s.registerRunLocked(id, cancel)
+}
+
+func (s *scheduler) registerRunLocked(id string, cancel context.CancelFunc) string {
+	s.runCounter++
+	var runId = fmt.Sprintf("%d", s.runCounter)
+	if _, ok := s.activeRuns[id]; !ok {
+		s.activeRuns[id] = make(map[string]context.CancelFunc)
+	}
+	s.activeRuns[id][runId] = cancel
+	return runId
+}
+
+// Removes the run cancel function
+func (s *scheduler) endRun(id string, runId string) {
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
+	delete(s.activeRuns[id], runId)
+	if len(s.activeRuns[id]) == 0 {
+		delete(s.activeRuns, id)
+	}
+}
+
+// Creates the context of a task run attempt, done when the command timeout expires or the parent run context is done
+func (s *scheduler) runContext(parent context.Context, cmd model.CommandConfig) (context.Context, context.CancelFunc) {
+	if timeout := cmd.TimeoutDuration(); timeout > 0 {
+		return context.WithTimeout(parent, timeout)
+	}
+	return context.WithCancel(parent)
+}
+
 func (s *scheduler) RunOnce() error {
 	_ = s.loadExecutions()
+	s.resetRunState()
 	if checkNextSchedulerTasks(s) {
+		s.events.Publish(model.Event{Type: model.EventSchedulerStarted, Message: "Scheduler started, running tasks once"})
 		err := executeSchedulerTasks(s)
+		s.runs.Wait()
+		s.notifications.Wait()
+		s.events.Publish(model.Event{Type: model.EventSchedulerStopped, Message: "Scheduler stopped, tasks completed"})
 		if err != nil {
-			s.running=false
+			s.halt()
 			err = errors.New(fmt.Sprintf("Fatal tasks execution error: %v", err))
 		}
 		return err
@@ -97,11 +258,109 @@ func (s *scheduler) RunOnce() error {
 	}
 }
 
+// Suspends the scheduling of new runs, running tasks complete normally. The paused state is saved in the
+// configuration file
+func (s *scheduler) Pause() error {
+	s.Lock()
+	defer s.Unlock()
+	if s.paused {
+		return errors.New("scheduler is already paused")
+	}
+	s.paused = true
+	return s.persistConfig()
+}
+
+// Resumes the scheduling of new runs
+func (s *scheduler) Resume() error {
+	s.Lock()
+	defer s.Unlock()
+	if !s.paused {
+		return errors.New("scheduler is not paused")
+	}
+	s.paused = false
+	return s.persistConfig()
+}
+
+// Reports if the scheduling of new runs is suspended
+func (s *scheduler) isPaused() bool {
+	s.RLock()
+	defer s.RUnlock()
+	return s.paused
+}
+
+func (s *scheduler) Enable(key string) error {
+	return s.setEnabled(key, true)
+}
+
+func (s *scheduler) Disable(key string) error {
+	return s.setEnabled(key, false)
+}
+
+func (s *scheduler) setEnabled(key string, enabled bool) error {
+	ref, cmd, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	if cmd.IsEnabled() == enabled {
+		return nil
+	}
+	cmd.Enabled = &enabled
+	return s.Update(ref.UUID, cmd)
+}
+
+func (s *scheduler) State() model.SchedulerState {
+	switch {
+	case s.isPaused():
+		return model.SchedulerStatePaused
+	case s.IsRunning():
+		return model.SchedulerStateRunning
+	}
+	return model.SchedulerStateStopped
+}
+
+// Runs immediately the task with given unique identifier, out of its time table
+func (s *scheduler) Trigger(id string) error {
+	s.RLock()
+	ref, _, _, found := s.findReference(id)
+	var cmd *model.CommandConfig
+	var err error
+	if found {
+		cmd, err = s.commandOf(ref.UUID)
+	}
+	s.RUnlock()
+	if !found {
+		return errors.New(fmt.Sprintf("Unable to find task with id or name: %s", id))
+	}
+	if err != nil {
+		return err
+	}
+	id = ref.UUID
+	s.execMutex.Lock()
+	var exec = s.ToExecutionWith(ref, *cmd)
+	if exec != nil && !s.IsExecutionStored(exec.UUID) {
+		s.runningTasks = append(s.runningTasks, exec)
+	}
+	s.execMutex.Unlock()
+	if exec == nil {
+		return errors.New(fmt.Sprintf("Unable to retrive or create task execution record for task id: %s", id))
+	}
+	triggerSingleTask(s, exec, id, model.TriggerManual)
+	return nil
+}
+
 func (s *scheduler) Running() []model.Execution {
-	return s.runningTasks
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
+	var out = make([]model.Execution, 0)
+	for _, exec := range s.runningTasks {
+		out = append(out, *exec)
+	}
+	return out
 }
 
 func (s *scheduler) Planned() []model.CommandConfig {
+	s.RLock()
+	defer s.RUnlock()
 	var out = make([]model.CommandConfig, 0)
 	out = append(out, s.cacheValues()...)
 	for _, cfg := range s.commands {
@@ -125,6 +384,12 @@ func (s *scheduler) IsExecutionStored(id string) bool {
 
 func (s *scheduler) ToExecutionWith(ref model.CommandConfigRef, cmd model.CommandConfig) *model.Execution {
 	if exec := filterFirstExecution(s.runningTasks, func(m model.Execution) bool { return m.UUID == ref.UUID }); exec != nil {
+		// Updated command configuration applies from the next run, unchanged configuration is not rewritten
+		// because the runs read it
+		if !reflect.DeepEqual(exec.Command, cmd) {
+			exec.Command = cmd
+		}
+		exec.TimeZone = s.timeZone
 		exec.UpdateNext()
 		return exec
 	} else {
@@ -134,6 +399,7 @@ func (s *scheduler) ToExecutionWith(ref model.CommandConfigRef, cmd model.Comman
 			Map: make(map[string]interface{}),
 			Times: 0,
 			Scheduled: false,
+			TimeZone: s.timeZone,
 		}
 		exec.Reset()
 
@@ -142,7 +408,8 @@ func (s *scheduler) ToExecutionWith(ref model.CommandConfigRef, cmd model.Comman
 }
 
 func (s *scheduler) ToExecution(ref model.CommandConfigRef) *model.Execution {
-	if exec := filterFirstExecution(s.runningTasks, func(m model.Execution) bool { return m.UUID == ref.UUID }); exec == nil {
+	if exec := filterFirstExecution(s.runningTasks, func(m model.Execution) bool { return m.UUID == ref.UUID }); exec != nil {
+		exec.TimeZone = s.timeZone
 		exec.UpdateNext()
 		return exec
 	} else {
@@ -161,6 +428,7 @@ func (s *scheduler) ToExecution(ref model.CommandConfigRef) *model.Execution {
 				Map: make(map[string]interface{}),
 				Times: 0,
 				Scheduled: false,
+				TimeZone: s.timeZone,
 			}
 			exec.Reset()
 			return &exec
@@ -169,10 +437,14 @@ func (s *scheduler) ToExecution(ref model.CommandConfigRef) *model.Execution {
 	return nil
 }
 func (s *scheduler) ExecutedTasks() []model.Execution {
+	s.RLock()
+	defer s.RUnlock()
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
 	var out = make([]model.Execution, 0)
 	for _, ref := range s.commands {
 		exec := s.ToExecution(ref)
-		if exec != nil {
+		if exec != nil && exec.Command.IsEnabled() {
 			exec.UpdateNext()
 			if time.Since(exec.Next).Milliseconds() < 0 {
 				out = append(out, *exec)
@@ -183,6 +455,10 @@ func (s *scheduler) ExecutedTasks() []model.Execution {
 }
 
 func (s *scheduler) NextRunningTasks() []model.Execution {
+	s.RLock()
+	defer s.RUnlock()
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
 	var out = make([]model.Execution, 0)
 	for _, ref := range s.commands {
 		exec := s.ToExecution(ref)
@@ -197,10 +473,23 @@ func (s *scheduler) NextRunningTasks() []model.Execution {
 }
 
 func (s *scheduler) AddToCache(cmd model.CommandConfig) error {
+	s.Lock()
+	defer s.Unlock()
 	var err error
+	if err = validateCommandConfig(cmd, !s.remoteFunctions); err != nil {
+		return err
+	}
+	if err = s.validateName(cmd.Name, ""); err != nil {
+		return err
+	}
+	if err = validateDependencies(s, "", cmd); err != nil {
+		return err
+	}
 	var id = uuid.New().String()
 	var ref = model.CommandConfigRef{
 		UUID:     id,
+		Name:     cmd.Name,
+		Enabled:  cmd.Enabled,
 		Command:  cmd.Command,
 		Created:  time.Now(),
 		Updated:  time.Now(),
@@ -208,6 +497,7 @@ func (s *scheduler) AddToCache(cmd model.CommandConfig) error {
 		LastRun:  time.Now(),
 	}
 	s.cache[id] = cmd
+	s.indexDependencies(id, &cmd)
 	if err != nil {
 		return err
 	}
@@ -216,10 +506,31 @@ func (s *scheduler) AddToCache(cmd model.CommandConfig) error {
 }
 
 func (s *scheduler) AddAndPersist(cmd model.CommandConfig) error {
+	_, err := s.Add(cmd)
+	return err
+}
+
+func (s *scheduler) Add(cmd model.CommandConfig) (model.CommandConfigRef, error) {
+	s.Lock()
+	defer s.Unlock()
 	var err error
+	if err = validateCommandConfig(cmd, !s.remoteFunctions); err != nil {
+		return model.CommandConfigRef{}, err
+	}
+	if err = validatePersistable(cmd); err != nil {
+		return model.CommandConfigRef{}, err
+	}
+	if err = s.validateName(cmd.Name, ""); err != nil {
+		return model.CommandConfigRef{}, err
+	}
+	if err = validateDependencies(s, "", cmd); err != nil {
+		return model.CommandConfigRef{}, err
+	}
 	var id = uuid.New().String()
 	var ref = model.CommandConfigRef{
 		UUID:     id,
+		Name:     cmd.Name,
+		Enabled:  cmd.Enabled,
 		Command:  cmd.Command,
 		Created:  time.Now(),
 		Updated:  time.Now(),
@@ -228,19 +539,41 @@ func (s *scheduler) AddAndPersist(cmd model.CommandConfig) error {
 	}
 	err = s.saveItem(id, cmd)
 	if err != nil {
-		return err
+		return model.CommandConfigRef{}, err
 	}
 	s.commands = append(s.commands, ref)
-	err = s.save()
-	return err
+	s.indexDependencies(id, &cmd)
+	err = s.persistConfig()
+	return ref, err
 }
 
 func (s *scheduler) UpdateToCache(cmd model.CommandConfig, index int) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.updateToCache(cmd, index)
+}
+
+func (s *scheduler) updateToCache(cmd model.CommandConfig, index int) error {
 	var err error
+	if err = validateCommandConfig(cmd, !s.remoteFunctions); err != nil {
+		return err
+	}
 	if index >= 0 && index < len(s.cacheCommands) {
+		if err = s.validateName(cmd.Name, s.cacheCommands[index].UUID); err != nil {
+			return err
+		}
+		if err = validateDependencies(s, s.cacheCommands[index].UUID, cmd); err != nil {
+			return err
+		}
+		if err = validateRename(s, s.cacheCommands[index], cmd.Name); err != nil {
+			return err
+		}
+		s.cacheCommands[index].Name = cmd.Name
+		s.cacheCommands[index].Enabled = cmd.Enabled
 		s.cacheCommands[index].Command = cmd.Command
 		s.cacheCommands[index].Updated = time.Now()
 		s.cache[s.cacheCommands[index].UUID] = cmd
+		s.indexDependencies(s.cacheCommands[index].UUID, &cmd)
 	} else {
 		return errors.New(fmt.Sprintf("Index out of bound: %v, must be 0 <= x < %v ", index, len(s.commands)))
 	}
@@ -248,21 +581,130 @@ func (s *scheduler) UpdateToCache(cmd model.CommandConfig, index int) error {
 }
 
 func (s *scheduler) UpdateAndPersist(cmd model.CommandConfig, index int) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.updateAndPersist(cmd, index)
+}
+
+func (s *scheduler) updateAndPersist(cmd model.CommandConfig, index int) error {
 	var err error
+	if err = validateCommandConfig(cmd, !s.remoteFunctions); err != nil {
+		return err
+	}
+	if err = validatePersistable(cmd); err != nil {
+		return err
+	}
 	if index >= 0 && index < len(s.commands) {
+		if err = s.validateName(cmd.Name, s.commands[index].UUID); err != nil {
+			return err
+		}
+		if err = validateDependencies(s, s.commands[index].UUID, cmd); err != nil {
+			return err
+		}
+		if err = validateRename(s, s.commands[index], cmd.Name); err != nil {
+			return err
+		}
+		s.commands[index].Name = cmd.Name
+		s.commands[index].Enabled = cmd.Enabled
 		s.commands[index].Command = cmd.Command
 		s.commands[index].Updated = time.Now()
 		err = s.saveItem(s.commands[index].UUID, cmd)
 		if err != nil {
 			return err
 		}
-		err = s.save()
+		s.indexDependencies(s.commands[index].UUID, &cmd)
+		err = s.persistConfig()
 	} else {
 		return errors.New(fmt.Sprintf("Index out of bound: %v, must be 0 <= x < %v ", index, len(s.commands)))
 	}
 	return err
 }
 
+// Finds the reference of the task with given unique identifier or name, reporting if it's a cached task and
+// its index in the cached or persisted tasks
+func (s *scheduler) findReference(key string) (model.CommandConfigRef, bool, int, bool) {
+	for idx, ref := range s.cacheCommands {
+		if ref.UUID == key {
+			return ref, true, idx, true
+		}
+	}
+	for idx, ref := range s.commands {
+		if ref.UUID == key {
+			return ref, false, idx, true
+		}
+	}
+	for idx, ref := range s.cacheCommands {
+		if ref.Matches(key) {
+			return ref, true, idx, true
+		}
+	}
+	for idx, ref := range s.commands {
+		if ref.Matches(key) {
+			return ref, false, idx, true
+		}
+	}
+	return model.CommandConfigRef{}, false, -1, false
+}
+
+// Verifies that the name is not used by tasks other than the one with given unique identifier
+func (s *scheduler) validateName(name string, id string) error {
+	if name == "" {
+		return nil
+	}
+	for _, ref := range append(append([]model.CommandConfigRef{}, s.cacheCommands...), s.commands...) {
+		if ref.UUID != id && (ref.Name == name || ref.UUID == name) {
+			return errors.New(fmt.Sprintf("Task name '%s' is already used by task id: %s", name, ref.UUID))
+		}
+	}
+	return nil
+}
+
+func (s *scheduler) Get(key string) (model.CommandConfigRef, model.CommandConfig, error) {
+	s.RLock()
+	defer s.RUnlock()
+	ref, cached, _, found := s.findReference(key)
+	if !found {
+		return ref, model.CommandConfig{}, errors.New(fmt.Sprintf("Unable to find task with id or name: %s", key))
+	}
+	if cached {
+		return ref, *s.cacheValue(ref.UUID), nil
+	}
+	cmd, err := s.loadItem(ref.UUID)
+	if err != nil {
+		return ref, model.CommandConfig{}, err
+	}
+	return ref, *cmd, nil
+}
+
+func (s *scheduler) Update(key string, cmd model.CommandConfig) error {
+	s.Lock()
+	defer s.Unlock()
+	ref, cached, index, found := s.findReference(key)
+	if !found {
+		return errors.New(fmt.Sprintf("Unable to find task with id or name: %s", key))
+	}
+	if cmd.Name == "" {
+		cmd.Name = ref.Name
+	}
+	if cached {
+		return s.updateToCache(cmd, index)
+	}
+	return s.updateAndPersist(cmd, index)
+}
+
+func (s *scheduler) Delete(key string) error {
+	s.Lock()
+	defer s.Unlock()
+	_, cached, index, found := s.findReference(key)
+	if !found {
+		return errors.New(fmt.Sprintf("Unable to find task with id or name: %s", key))
+	}
+	if cached {
+		return s.deleteFromCache(index)
+	}
+	return s.deleteAndPersist(index)
+}
+
 func (s *scheduler) cacheValue(id string) *model.CommandConfig {
 	if v, ok := s.cache[id]; ok {
 		return &v
@@ -288,13 +730,23 @@ func (s *scheduler) cacheContains(id string) bool {
 }
 
 func (s *scheduler) DeleteFromCache(index int) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.deleteFromCache(index)
+}
+
+func (s *scheduler) deleteFromCache(index int) error {
 	var err error
 	if index >= 0 && index < len(s.cacheCommands) {
 		var length = len(s.cacheCommands)
 		var tailEndIndex = length - 1
 		var id = s.cacheCommands[index].UUID
+		if err = validateNoDependents(s, id); err != nil {
+			return err
+		}
 		if s.cacheContains(id) {
 			delete(s.cache, id)
+			s.indexDependencies(id, nil)
 			if index == 0 {
 				// truncate array head
 				s.cacheCommands = s.cacheCommands[1:]
@@ -318,13 +770,23 @@ func (s *scheduler) DeleteFromCache(index int) error {
 }
 
 func (s *scheduler) DeleteAndPersist(index int) error {
+	s.Lock()
+	defer s.Unlock()
+	return s.deleteAndPersist(index)
+}
+
+func (s *scheduler) deleteAndPersist(index int) error {
 	if index >= 0 && index < len(s.commands) {
 		var err error
 		var id = s.commands[index].UUID
+		if err = validateNoDependents(s, id); err != nil {
+			return err
+		}
 		err = s.deleteItem(id)
 		if err != nil {
 			return err
 		}
+		s.indexDependencies(id, nil)
 		var length = len(s.commands)
 		if index == 0 {
 			// truncate array head
@@ -339,7 +801,7 @@ func (s *scheduler) DeleteAndPersist(index int) error {
 			s.commands = chunk1
 			s.commands = append(s.commands, chunk2...)
 		}
-		err = s.save()
+		err = s.persistConfig()
 		if err != nil {
 			return err
 		}
@@ -347,25 +809,36 @@ func (s *scheduler) DeleteAndPersist(index int) error {
 		if err != nil {
 			return err
 		}
-		err = s.saveExecutions()
+		err = s.persistExecutions()
 		return err
 	}
 	return errors.New(fmt.Sprintf("Index out of bound: %v, must be 0 <= x < %v ", index, len(s.commands)))
 }
 
-// save the configuration to the file
+// save the configuration to the store
 func (s *scheduler) save() error {
-	var err error
+	s.Lock()
+	defer s.Unlock()
+	return s.persistConfig()
+}
+
+// Saves the configuration to the store, the caller holds the scheduler lock
+func (s *scheduler) persistConfig() (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
-		s.Unlock()
 	}()
-	s.Lock()
-	err = io.SaveConfig(s.enc, s.file, model.SchedulerConfig{
+	err = s.store.SaveConfig(model.SchedulerConfig{
 		Sync:     s.syncRun,
+		TimeZone: s.timeZone,
 		Commands: s.commands,
+		MaxRunning: s.maxRunning,
+		StopGracePeriod: s.stopGracePeriod,
+		Paused:   s.paused,
+		History:  s.historyConfig,
+		Webhooks: s.webhooks,
+		Mail:     s.mail,
 	})
 	return err
 }
@@ -379,19 +852,31 @@ func (s *scheduler) loadConfig() error {
 		s.Unlock()
 	}()
 	s.Lock()
-	var config = model.SchedulerConfig{}
-	err = io.ReadConfig(s.enc, s.file, &config)
+	var config model.SchedulerConfig
+	config, err = s.store.LoadConfig()
+	err = s.recovered(err)
 	if err == nil {
 		s.syncRun = config.Sync
+		s.timeZone = config.TimeZone
 		s.commands = config.Commands
+		s.maxRunning = config.MaxRunning
+		s.stopGracePeriod = config.StopGracePeriod
+		s.paused = config.Paused
+		s.historyConfig = config.History
+		s.webhooks = config.Webhooks
+		s.mail = config.Mail
+		err = validateWebhooks(s.webhooks)
+		if err == nil {
+			err = s.mail.Validate()
+		}
 	}
 	return err
 }
 
-// Load running tasks cache form his file
+// Load running tasks cache form the store
 func (s *scheduler) loadExecutions() error {
 	var err error
-	var config =make([]model.Execution, 0)
+	var config []model.Execution
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
@@ -399,16 +884,26 @@ func (s *scheduler) loadExecutions() error {
 		s.execMutex.Unlock()
 	}()
 	s.execMutex.Lock()
-	var file = fmt.Sprintf("%s%c%s.gob", s.dir, os.PathSeparator, "executions")
-	err = io.ReadNative(file, &config)
+	config, err = s.store.LoadExecutions()
+	err = s.recovered(err)
 	if err == nil {
-		s.runningTasks = config
+		s.runningTasks = make([]*model.Execution, 0)
+		for idx := range config {
+			s.runningTasks = append(s.runningTasks, &config[idx])
+		}
 	}
 	return err
 }
 
-// Save running tasks cache to his file
+// Save running tasks cache to the store
 func (s *scheduler) saveExecutions() error {
+	s.RLock()
+	defer s.RUnlock()
+	return s.persistExecutions()
+}
+
+// Saves the running tasks cache to the store, the caller holds the scheduler lock
+func (s *scheduler) persistExecutions() error {
 	var err error
 	defer func() {
 		if r := recover(); r != nil {
@@ -417,17 +912,56 @@ func (s *scheduler) saveExecutions() error {
 		s.execMutex.Unlock()
 	}()
 	s.execMutex.Lock()
-	var file = fmt.Sprintf("%s%c%s.gob", s.dir, os.PathSeparator, "executions")
 	var config = make([]model.Execution, 0)
 	for _, rt := range s.runningTasks {
 		if ! s.cacheContains(rt.UUID) {
-			config = append(config, rt)
+			config = append(config, *rt)
 		}
 	}
-	err = io.SaveNative(file, config)
+	err = s.store.SaveExecutions(config)
 	return err
 }
 
+// Load tasks run history from the store
+func (s *scheduler) loadHistory() error {
+	var err error
+	var records []model.RunRecord
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+		s.historyMutex.Unlock()
+	}()
+	s.historyMutex.Lock()
+	records, err = s.store.LoadHistory()
+	err = s.recovered(err)
+	if err == nil {
+		s.history = records
+	}
+	return err
+}
+
+// Add a run record to the tasks history, apply the retention policy and save the history to the store
+func (s *scheduler) addHistory(record model.RunRecord) error {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+		s.historyMutex.Unlock()
+	}()
+	s.historyMutex.Lock()
+	s.history = s.historyConfig.Retain(append(s.history, record))
+	err = s.store.SaveHistory(s.history)
+	return err
+}
+
+func (s *scheduler) History(filter model.HistoryFilter) []model.RunRecord {
+	s.historyMutex.Lock()
+	defer s.historyMutex.Unlock()
+	return filter.Apply(s.history)
+}
+
 // Save running tasks cache to his file
 func (s *scheduler) trimExpiredExecutions() error {
 	var err error
@@ -438,7 +972,7 @@ func (s *scheduler) trimExpiredExecutions() error {
 		s.execMutex.Unlock()
 	}()
 	s.execMutex.Lock()
-	var config = make([]model.Execution, 0)
+	var config = make([]*model.Execution, 0)
 	for _, rt := range s.runningTasks {
 		if ! rt.Expired() {
 			config = append(config, rt)
@@ -458,7 +992,7 @@ func (s *scheduler) removeExecution(id string) error {
 		s.execMutex.Unlock()
 	}()
 	s.execMutex.Lock()
-	var config = make([]model.Execution, 0)
+	var config = make([]*model.Execution, 0)
 	for _, rt := range s.runningTasks {
 		if rt.UUID != id {
 			config = append(config, rt)
@@ -468,58 +1002,59 @@ func (s *scheduler) removeExecution(id string) error {
 	return err
 }
 
-// Load a single command config form his file
+// Load a single command config form the store
 func (s *scheduler) loadItem(id string) (*model.CommandConfig, error) {
-	if _, ok := itemsLock[id]; !ok {
-		itemsLock[s.uuid][id] = &sync.Mutex{}
-	}
 	var err error
 	var config *model.CommandConfig
+	var lock = s.itemLock(id)
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
-		itemsLock[s.uuid][id].Unlock()
+		lock.Unlock()
 	}()
-	itemsLock[s.uuid][id].Lock()
-	var file = fmt.Sprintf("%s%c%s.gob", s.dir, os.PathSeparator, id)
-	err = io.ReadNative(file, &config)
+	lock.Lock()
+	config, err = s.store.LoadItem(id)
+	err = s.recovered(err)
+	if err == nil && config != nil {
+		// Native encoding drops the disabled flag, the reference keeps it
+		for _, ref := range s.commands {
+			if ref.UUID == id {
+				config.Enabled = ref.Enabled
+				break
+			}
+		}
+	}
 	return config, err
 }
 
-// save a single command config to his file
+// save a single command config to the store
 func (s *scheduler) saveItem(id string, config model.CommandConfig) error {
 	var err error
-	if _, ok := itemsLock[s.uuid][id]; !ok {
-		itemsLock[s.uuid][id] = &sync.Mutex{}
-	}
+	var lock = s.itemLock(id)
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
-		itemsLock[s.uuid][id].Unlock()
+		lock.Unlock()
 	}()
-	itemsLock[s.uuid][id].Lock()
-	var file = fmt.Sprintf("%s%c%s.gob", s.dir, os.PathSeparator, id)
-	err = io.SaveNative(file, &config)
+	lock.Lock()
+	err = s.store.SaveItem(id, config)
 	return err
 }
 
-// save a single command config to his file
+// remove a single command config from the store
 func (s *scheduler) deleteItem(id string) error {
 	var err error
-	if _, ok := itemsLock[s.uuid][id]; !ok {
-		itemsLock[s.uuid][id] = &sync.Mutex{}
-	}
+	var lock = s.itemLock(id)
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
-		itemsLock[s.uuid][id].Unlock()
+		lock.Unlock()
 	}()
-	itemsLock[s.uuid][id].Lock()
-	var file = fmt.Sprintf("%s%c%s.%s", s.dir, os.PathSeparator, id, s.enc.String())
-	err = io.DeleteFile(file)
+	lock.Lock()
+	err = s.store.DeleteItem(id)
 	return err
 }
 
@@ -532,85 +1067,144 @@ func (s *scheduler) Load() error {
 		s.Unlock()
 	}()
 	s.Lock()
-	var config = model.SchedulerConfig{}
-	err = io.ReadConfig(s.enc, s.file, &config)
+	var config model.SchedulerConfig
+	config, err = s.store.LoadConfig()
+	err = s.recovered(err)
 	if err == nil {
 		s.syncRun = config.Sync
+		s.timeZone = config.TimeZone
 		s.commands = config.Commands
-		err = s.loadExecutions()
+		s.maxRunning = config.MaxRunning
+		s.stopGracePeriod = config.StopGracePeriod
+		s.paused = config.Paused
+		s.historyConfig = config.History
+		s.webhooks = config.Webhooks
+		s.mail = config.Mail
+		err = validateWebhooks(s.webhooks)
+		if err == nil {
+			err = s.mail.Validate()
+		}
+		if err == nil {
+			err = s.loadExecutions()
+		}
+		if err == nil {
+			err = s.loadHistory()
+		}
+		if err == nil {
+			err = s.loadDeliveries()
+		}
+		if err == nil {
+			s.loadDependencies()
+		}
 	}
 	return err
 }
 
 func (s *scheduler) References() []model.CommandConfigRef {
-	return s.commands
+	s.RLock()
+	defer s.RUnlock()
+	return append(make([]model.CommandConfigRef, 0), s.commands...)
 }
 
 func (s *scheduler) Destroy(saveState bool)  {
-	delete(itemsLock, s.uuid)
 	var err error
 	if s.IsRunning() {
 		err = s.Stop()
 		if err != nil {
-			s.errors <- err
+			s.publishError(err)
 		}
 	}
 	if saveState {
 		err = s.save()
 		if err != nil {
-			s.errors <- err
+			s.publishError(err)
 		}
 		err = s.trimExpiredExecutions()
 		if err != nil {
-			s.errors <- err
+			s.publishError(err)
 		}
 		err = s.saveExecutions()
 		if err != nil {
-			s.errors <- err
+			s.publishError(err)
 		}
 	}
-	close(s.errors)
-	close(s.warnings)
-	s.runningTasks = make([]model.Execution, 0)
+	// Item locks are released once Stop has waited the running tasks and the state is saved
+	itemsMutex.Lock()
+	delete(itemsLock, s.uuid)
+	itemsMutex.Unlock()
+	err = s.store.Close()
+	if err != nil {
+		s.publishError(err)
+	}
+	s.events.Close()
+	close(s.errorsPipe)
+	close(s.warningsPipe)
+	s.Lock()
+	defer s.Unlock()
+	s.execMutex.Lock()
+	defer s.execMutex.Unlock()
+	s.runningTasks = make([]*model.Execution, 0)
 	s.cacheCommands = make([]model.CommandConfigRef, 0)
 	s.commands = make([]model.CommandConfigRef, 0)
 	s.cache  = make(map[string]model.CommandConfig)
+	s.dependsOn = make(map[string][]model.Dependency)
+}
+
+// Create the scheduler component over the given store
+func newScheduler(st store.Store, syncRun bool) *scheduler {
+	var sc = &scheduler{
+		cache:         make(map[string]model.CommandConfig),
+		commands:      make([]model.CommandConfigRef, 0),
+		cacheCommands: make([]model.CommandConfigRef, 0),
+		dependsOn:     make(map[string][]model.Dependency),
+		runningTasks:  make([]*model.Execution, 0),
+		history:       make([]model.RunRecord, 0),
+		deliveries:    make([]model.WebhookDelivery, 0),
+		lastOutcomes:  make(map[string]model.RunOutcome),
+		taskSlots:     make(map[string]chan struct{}),
+		activeRuns:    make(map[string]map[string]context.CancelFunc),
+		syncRun:       syncRun,
+		store:         st,
+		events:        model.NewEventBus(),
+		metrics:       newSchedulerMetrics(),
+		errorsPipe:    make(chan error, model.DefaultEventBuffer),
+		warningsPipe:  make(chan error, model.DefaultEventBuffer),
+		uuid:          uuid.New().String(),
+	}
+	sc.drainPipe(sc.errorsPipe, model.EventError)
+	sc.drainPipe(sc.warningsPipe, model.EventWarning)
+	return sc
+}
+
+// Add the given scheduler config items to the scheduler
+func addCommands(sc *scheduler, commands []model.CommandConfig, errorsList []error) []error {
+	for _, c := range commands {
+		var err = sc.AddAndPersist(c)
+		if err != nil {
+			errorsList = append(errorsList, err)
+		}
+	}
+	return errorsList
 }
 
 // Load an existing scheduler, add the given scheduler config items and save the config file.
 func LoadSchedulerWith(file string, encoding io.Encoding, commands []model.CommandConfig,
 	syncRun bool) (model.Scheduler, []error) {
 	var errorsList = make([]error, 0)
-	var dir, _ = filepath.Split(file)
-	if !io.FileExists(dir) {
-		_ = io.CreateFolder(dir, 0777)
-	}
-	var sc = &scheduler{sync.RWMutex{}, make(map[string]model.CommandConfig), make([]model.CommandConfigRef, 0), make([]model.CommandConfigRef, 0), make([]model.Execution, 0), syncRun, false, dir, file, encoding, make(chan error), make(chan error), sync.Mutex{}, uuid.New().String()}
-	itemsLock[sc.uuid] = make(map[string]*sync.Mutex)
+	var sc = newScheduler(store.NewFileStore(file, encoding), syncRun)
 	if file != "" {
 		var err = sc.Load()
 		if err != nil {
 			errorsList = append(errorsList, err)
 		}
 	}
-	for _, c := range commands {
-		var err = sc.AddAndPersist(c)
-		if err != nil {
-			errorsList = append(errorsList, err)
-		}
-	}
-	return sc, errorsList
+	return sc, addCommands(sc, commands, errorsList)
 }
 
 // Load an existing scheduler and return the component.
 func LoadSchedulerFrom(file string, encoding io.Encoding, syncRun bool) (model.Scheduler, error) {
 	var err error
-	var dir, _ = filepath.Split(file)
-	if !io.FileExists(dir) {
-		_ = io.CreateFolder(dir, 0777)
-	}
-	var sc = &scheduler{sync.RWMutex{}, make(map[string]model.CommandConfig), make([]model.CommandConfigRef, 0), make([]model.CommandConfigRef, 0), make([]model.Execution, 0), syncRun, false, dir, file, encoding, make(chan error), make(chan error), sync.Mutex{}, uuid.New().String()}
-	itemsLock[sc.uuid] = make(map[string]*sync.Mutex)
+	var sc = newScheduler(store.NewFileStore(file, encoding), syncRun)
 	if file != "" {
 		err = sc.Load()
 	}
@@ -620,12 +1214,7 @@ func LoadSchedulerFrom(file string, encoding io.Encoding, syncRun bool) (model.S
 // Create a new empty scheduler and save the config file.
 func NewEmptyScheduler(file string, encoding io.Encoding, syncRun bool) (model.Scheduler, error) {
 	var err error
-	var dir, _ = filepath.Split(file)
-	if !io.FileExists(dir) {
-		_ = io.CreateFolder(dir, 0777)
-	}
-	var sc = &scheduler{sync.RWMutex{}, make(map[string]model.CommandConfig), make([]model.CommandConfigRef, 0), make([]model.CommandConfigRef, 0), make([]model.Execution, 0), syncRun, false, dir, file, encoding, make(chan error), make(chan error), sync.Mutex{}, uuid.New().String()}
-	itemsLock[sc.uuid] = make(map[string]*sync.Mutex)
+	var sc = newScheduler(store.NewFileStore(file, encoding), syncRun)
 	if file != "" {
 		err = sc.save()
 	}
@@ -634,19 +1223,38 @@ func NewEmptyScheduler(file string, encoding io.Encoding, syncRun bool) (model.S
 
 // Create a new scheduler from given command config and save the config file.
 func NewSchedulerWith(file string, encoding io.Encoding, commands []model.CommandConfig,
+	syncRun bool) (model.Scheduler, []error) {
+	var sc = newScheduler(store.NewFileStore(file, encoding), syncRun)
+	return sc, addCommands(sc, commands, make([]error, 0))
+}
+
+// Load an existing scheduler from the given store, add the given scheduler config items and save them.
+func LoadSchedulerWithStore(st store.Store, commands []model.CommandConfig,
 	syncRun bool) (model.Scheduler, []error) {
 	var errorsList = make([]error, 0)
-	var dir, _ = filepath.Split(file)
-	if !io.FileExists(dir) {
-		_ = io.CreateFolder(dir, 0777)
-	}
-	var sc = &scheduler{sync.RWMutex{}, make(map[string]model.CommandConfig), make([]model.CommandConfigRef, 0), make([]model.CommandConfigRef, 0), make([]model.Execution, 0), syncRun, false, dir, file, encoding, make(chan error), make(chan error), sync.Mutex{}, uuid.New().String()}
-	itemsLock[sc.uuid] = make(map[string]*sync.Mutex)
-	for _, c := range commands {
-		var err = sc.AddAndPersist(c)
-		if err != nil {
-			errorsList = append(errorsList, err)
-		}
+	var sc = newScheduler(st, syncRun)
+	var err = sc.Load()
+	if err != nil {
+		errorsList = append(errorsList, err)
 	}
-	return sc, errorsList
+	return sc, addCommands(sc, commands, errorsList)
+}
+
+// Load an existing scheduler from the given store and return the component.
+func LoadSchedulerFromStore(st store.Store, syncRun bool) (model.Scheduler, error) {
+	var sc = newScheduler(st, syncRun)
+	return sc, sc.Load()
+}
+
+// Create a new empty scheduler and save its configuration in the given store.
+func NewEmptySchedulerInStore(st store.Store, syncRun bool) (model.Scheduler, error) {
+	var sc = newScheduler(st, syncRun)
+	return sc, sc.save()
+}
+
+// Create a new scheduler from given command config and save it in the given store.
+func NewSchedulerWithStore(st store.Store, commands []model.CommandConfig,
+	syncRun bool) (model.Scheduler, []error) {
+	var sc = newScheduler(st, syncRun)
+	return sc, addCommands(sc, commands, make([]error, 0))
 }
@@ -1,24 +1,156 @@
 package cron
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"github.com/google/uuid"
 	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/schedule"
 	"github.com/hellgate75/go-cron/utils"
-	"strings"
+	"net/http"
+	"os"
+	"regexp"
 	"sync"
 	"time"
 )
 
 var itemsLock = make(map[string]map[string]*sync.Mutex)
+var itemsMutex sync.Mutex
+
+// Retrieves the lock of the stored item with given unique identifier, creating it on first use
+func (s *scheduler) itemLock(id string) *sync.Mutex {
+	itemsMutex.Lock()
+	defer itemsMutex.Unlock()
+	if _, ok := itemsLock[s.uuid]; !ok {
+		itemsLock[s.uuid] = make(map[string]*sync.Mutex)
+	}
+	if _, ok := itemsLock[s.uuid][id]; !ok {
+		itemsLock[s.uuid][id] = &sync.Mutex{}
+	}
+	return itemsLock[s.uuid][id]
+}
 
 var NodeMap = make(map[string]interface{})
 var ClusterMap = make(map[string]interface{})
 
-func filterFirstExecution(list []model.Execution, match func(model.Execution) bool) *model.Execution {
+func filterFirstExecution(list []*model.Execution, match func(model.Execution) bool) *model.Execution {
 	for _, c := range list {
-		if match(c) {
-			return &c
+		if match(*c) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Verifies command configuration consistency before storing it, the called functions are verified when they
+// are registered in the current process
+func validateCommandConfig(cmd model.CommandConfig, functions bool) error {
+	if err := cmd.Command.Validate(); err != nil {
+		return err
+	}
+	if functions {
+		if err := validateFunctions(cmd.Command); err != nil {
+			return err
+		}
+	}
+	if err := cmd.Process.Validate(); err != nil {
+		return err
+	}
+	if err := processOptions(cmd.Process).Validate(); err != nil {
+		return err
+	}
+	if err := validateShellCommands(cmd.Command, cmd.Process); err != nil {
+		return err
+	}
+	if cmd.Schedule != "" {
+		if err := schedule.Validate(cmd.Schedule); err != nil {
+			return err
+		}
+	}
+	if cmd.TimeZone != "" {
+		if _, err := utils.LoadLocation(cmd.TimeZone); err != nil {
+			return errors.New(fmt.Sprintf("Invalid time zone '%s': %v", cmd.TimeZone, err))
+		}
+	}
+	if cmd.Timeout != "" {
+		if d, err := time.ParseDuration(cmd.Timeout); err != nil || d <= 0 {
+			return errors.New(fmt.Sprintf("Invalid timeout '%s', it must be a positive duration (e.g.: 30s, 5m)", cmd.Timeout))
+		}
+	}
+	if err := validateRetryConfig(cmd.Retry); err != nil {
+		return err
+	}
+	if err := validateWebhooks(cmd.Webhooks); err != nil {
+		return err
+	}
+	if cmd.Mail != nil {
+		if err := cmd.Mail.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateHooks(cmd, functions); err != nil {
+		return err
+	}
+	if !cmd.Concurrency.Valid() {
+		return errors.New(fmt.Sprintf("Invalid concurrency policy '%s' (available: allow, forbid, queue, replace)", cmd.Concurrency))
+	}
+	return nil
+}
+
+// Verifies that the command can be stored in the native files, go commands can be cached only
+func validatePersistable(cmd model.CommandConfig) error {
+	if !cmd.Command.Persistable() {
+		return errors.New("Go function and ComputableValue commands cannot be persisted, add them to the scheduler cache")
+	}
+	for _, hook := range cmd.Hooks.All() {
+		if !hook.Command.Persistable() {
+			return errors.New(fmt.Sprintf("Go function and ComputableValue %s hooks cannot be persisted, add the task to the scheduler cache", hook.Hook))
+		}
+	}
+	return nil
+}
+
+// Verifies that the shell commands, also in composite command steps, can be turned in process arguments
+func validateShellCommands(command model.CommandSpec, options model.ProcessOptions) error {
+	switch command.Kind {
+	case model.CommandKindShell:
+		if _, err := options.ShellArgs(command.Shell); err != nil {
+			return err
+		}
+	case model.CommandKindComposite:
+		if command.Composite != nil {
+			for idx, step := range command.Composite.Steps {
+				if err := validateShellCommands(step, options); err != nil {
+					return errors.New(fmt.Sprintf("Invalid composite command step %d: %v", idx+1, err))
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Verifies retry policy consistency
+func validateRetryConfig(retry model.RetryConfig) error {
+	if retry.MaxAttempts < 0 {
+		return errors.New(fmt.Sprintf("Invalid retry max attempts %v, it must not be negative", retry.MaxAttempts))
+	}
+	if retry.Backoff != "" && retry.Backoff != model.BackoffFixed && retry.Backoff != model.BackoffExponential {
+		return errors.New(fmt.Sprintf("Invalid retry backoff '%s' (available: fixed, exponential)", retry.Backoff))
+	}
+	for _, value := range []string{retry.Delay, retry.MaxDelay} {
+		if value != "" {
+			if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+				return errors.New(fmt.Sprintf("Invalid retry delay '%s', it must be a positive duration (e.g.: 30s, 5m)", value))
+			}
+		}
+	}
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		return errors.New(fmt.Sprintf("Invalid retry jitter %v, it must be between 0 and 1", retry.Jitter))
+	}
+	if retry.ErrorMatch != "" {
+		if _, err := regexp.Compile(retry.ErrorMatch); err != nil {
+			return errors.New(fmt.Sprintf("Invalid retry error match '%s': %v", retry.ErrorMatch, err))
 		}
 	}
 	return nil
@@ -37,6 +169,10 @@ func sendCommands(c chan model.CommandConfigRef, scheduler0 *scheduler) {
 
 func checkNextSchedulerTasks(scheduler0 *scheduler) bool {
 	var execAtLEastOnce bool
+	scheduler0.RLock()
+	defer scheduler0.RUnlock()
+	scheduler0.execMutex.Lock()
+	defer scheduler0.execMutex.Unlock()
 	for _, ref := range scheduler0.cacheCommands {
 		if cmd, ok := scheduler0.cache[ref.UUID]; ok {
 			exec := scheduler0.ToExecutionWith(ref, cmd)
@@ -56,105 +192,290 @@ func checkNextSchedulerTasks(scheduler0 *scheduler) bool {
 	return execAtLEastOnce
 }
 
-func createExecutionContextFrom(execution *model.Execution, scheduler *scheduler) model.ExecutionContext {
-	var refs = make([]model.CommandConfigRef, 0)
-	refs = append(refs, scheduler.cacheCommands...)
-	refs = append(refs, scheduler.commands...)
-	return model.ExecutionContext{
+func createExecutionContextFrom(ctx context.Context, execution *model.Execution, scheduler *scheduler) model.ExecutionContext {
+	scheduler.RLock()
+	var refs = scheduler.allReferences()
+	scheduler.RUnlock()
+	var context = model.ExecutionContext{
 		Configuration: &model.SchedulerConfig{
 			Commands:    refs,
 			Sync: scheduler.syncRun,
+			TimeZone: scheduler.timeZone,
+			StopGracePeriod: scheduler.stopGracePeriod,
 		},
 		CommandInfo: &execution.Command,
 		ContextMap: &execution.Map,
 		StaticMap: &NodeMap,
 		GlobalMap: &ClusterMap,
-		ErrorsPipe: scheduler.errors,
-		WarningsPipe: scheduler.warnings,
+		ErrorsPipe: scheduler.errorsPipe,
+		WarningsPipe: scheduler.warningsPipe,
+		Context: ctx,
+	}
+	if run, ok := ctx.Value(hookRunKey{}).(hookRun); ok {
+		context.Hook = run.hook
+		context.Run = &run.record
 	}
+	return context
 }
 
-func runTextArrayCommand(scheduler *scheduler, id string, cmdArr []string) {
-	out, err := utils.ExecuteCommandArgs(cmdArr...)
-	if err != nil {
-		scheduler.errors <- err
-	} else {
-		scheduler.warnings <- errors.New(fmt.Sprintf("Execution of command id : %s, completed, output: %s", id, out))
+// Converts the command process options in the process runner ones, building the process environment
+func processOptions(options model.ProcessOptions) utils.ProcessOptions {
+	return utils.ProcessOptions{
+		Dir:              options.Dir,
+		Env:              options.Environment(os.Environ()),
+		Stdin:            options.Stdin,
+		StdoutLimit:      options.StdoutLimit,
+		StderrLimit:      options.StderrLimit,
+		SuccessExitCodes: options.SuccessExitCodes,
+		User:             options.User,
+		Group:            options.Group,
+		Umask:            options.Umask,
+		Nice:             options.Nice,
+		Limits: utils.ResourceLimits{
+			CPUSeconds:   options.Limits.CPUSeconds,
+			AddressSpace: options.Limits.AddressSpace,
+			OpenFiles:    options.Limits.OpenFiles,
+			Processes:    options.Limits.Processes,
+		},
 	}
 }
 
-func runTextCommand(scheduler *scheduler, id string, cmd string) {
-	out, err := utils.ExecuteCommand(cmd)
+func runTextArrayCommand(ctx context.Context, scheduler *scheduler, id string, cmdArr []string, options model.ProcessOptions) (utils.CommandResult, error) {
+	return utils.RunProcess(ctx, processOptions(options), cmdArr...)
+}
+
+func runTextCommand(ctx context.Context, scheduler *scheduler, id string, cmd string, options model.ProcessOptions) (utils.CommandResult, error) {
+	args, err := options.ShellArgs(cmd)
 	if err != nil {
-		scheduler.errors <- err
-	} else {
-		scheduler.warnings <- errors.New(fmt.Sprintf("Execution of command id : %s, completed, output: %s", id, out))
+		return utils.CommandResult{ExitCode: -1}, err
 	}
+	return utils.RunProcess(ctx, processOptions(options), args...)
 }
 
-func runFunctionCommand(scheduler *scheduler, id string, execution *model.Execution, function func(model.ExecutionContext) error) {
-	var context = createExecutionContextFrom(execution, scheduler)
-	err := function(context)
-	if err != nil {
-		scheduler.errors <- err
-	} else {
-		scheduler.warnings <- errors.New(fmt.Sprintf("Execution of command id : %s, completed, type: func(model.ExecutionContext) error", id))
+func runHttpCommand(ctx context.Context, scheduler *scheduler, id string, request model.HttpRequest) (utils.CommandResult, error) {
+	var header = make(http.Header)
+	for _, h := range request.Headers {
+		header.Add(h.Name, h.Value)
+	}
+	return utils.RunHttpRequest(ctx, request.RequestMethod(), request.URL, header, request.Body)
+}
+
+func runFunctionCommand(ctx context.Context, scheduler *scheduler, id string, execution *model.Execution, function func(model.ExecutionContext) error) (utils.CommandResult, error) {
+	var context = createExecutionContextFrom(ctx, execution, scheduler)
+	err := runUntilDone(ctx, func() error {
+		return function(context)
+	})
+	return functionResult(err), err
+}
+
+func runComputableCommand(ctx context.Context, scheduler *scheduler, id string, execution *model.Execution, computable model.ComputableValue) (utils.CommandResult, error) {
+	var context = createExecutionContextFrom(ctx, execution, scheduler)
+	err := runUntilDone(ctx, func() error {
+		return computable.Compute(context)
+	})
+	return functionResult(err), err
+}
+
+// Runs the Go function and waits for it, or until the context is done. Go functions cannot be killed, so
+// when the context is done the function is left behind and the context error is returned
+func runUntilDone(ctx context.Context, function func() error) error {
+	var done = make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- errors.New(fmt.Sprintf("%v", r))
+			}
+		}()
+		done <- function()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
 	}
 }
 
-func runComputableCommand(scheduler *scheduler, id string, execution *model.Execution, computable model.ComputableValue) {
-	var context = createExecutionContextFrom(execution, scheduler)
-	err := computable.Compute(context)
+// Go functions have no process exit code, it's reported as 0 for success and 1 for failure
+func functionResult(err error) utils.CommandResult {
 	if err != nil {
-		scheduler.errors <- err
-	} else {
-		scheduler.warnings <- errors.New(fmt.Sprintf("Execution of command id : %s, completed, type: model.ComputableValue", id))
+		return utils.CommandResult{ExitCode: 1}
 	}
+	return utils.CommandResult{ExitCode: 0}
 }
 
-func executeSingleTask(scheduler *scheduler, execution *model.Execution, id string) {
+// Create a new run history record for the given task
+func newRunRecord(id string, trigger model.TriggerSource) model.RunRecord {
+	host, _ := os.Hostname()
+	return model.RunRecord{
+		RunID:   uuid.New().String(),
+		UUID:    id,
+		Trigger: trigger,
+		Host:    host,
+		Start:   time.Now(),
+	}
+}
+
+// Executes a run of the task, retrying the failed attempts accordingly to the command retry policy
+func executeTaskWithRetry(ctx context.Context, scheduler *scheduler, execution *model.Execution, id string, trigger model.TriggerSource) {
+	var retry = execution.Command.Retry
+	var record model.RunRecord
+	var previous = scheduler.previousOutcome(id)
+	defer func() {
+		// The final run outcome drives the downstream tasks and the notifications
+		runDownstreamTasks(scheduler, id, record)
+		scheduler.notifyRun(execution, id, previous, record)
+	}()
+	for attempt := 1; attempt <= retry.Attempts(); attempt++ {
+		var attemptCtx, cancel = scheduler.runContext(ctx, execution.Command)
+		record = executeSingleTask(attemptCtx, scheduler, execution, id, trigger, attempt)
+		cancel()
+		if attempt == retry.Attempts() || !retry.ShouldRetry(record) {
+			return
+		}
+		var delay = retry.DelayAfter(attempt)
+		var event = taskEvent(model.EventTaskRetrying, execution, id, "Execution of command id : %s, attempt %d of %d failed, retrying in %s", id, attempt, retry.Attempts(), delay)
+		event.RunID = record.RunID
+		event.Attempt = attempt
+		event.Next = time.Now().Add(delay)
+		scheduler.events.Publish(event)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			event = taskEvent(model.EventTaskSkipped, execution, id, "Execution of command id : %s, retry cancelled", id)
+			event.RunID = record.RunID
+			event.Attempt = attempt + 1
+			scheduler.events.Publish(event)
+			return
+		}
+		trigger = model.TriggerRetry
+	}
+}
+
+func executeSingleTask(ctx context.Context, scheduler *scheduler, execution *model.Execution, id string, trigger model.TriggerSource, attempt int) (record model.RunRecord) {
+	record = newRunRecord(id, trigger)
+	record.Attempt = attempt
+	var result = utils.CommandResult{ExitCode: -1}
+	var err error
+	var kindOfCommand = execution.Command.Command.Kind
 	defer func() {
-		var err error
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
-		if err != nil {
-			scheduler.errors <- err
+		// History is recorded before reporting, so it's kept even when nobody reads the channels
+		record.Complete(result.Stdout, result.Stderr, result.ExitCode, err, scheduler.historyConfig.Output())
+		record.Hooks = runHooks(scheduler, execution, id, record)
+		if errH := scheduler.addHistory(record); errH != nil {
+			var event = taskEvent(model.EventError, execution, id, "Unable to save history of command id : %s, error: %v", id, errH)
+			event.RunID = record.RunID
+			event.Error = errH.Error()
+			scheduler.events.Publish(event)
+		}
+		var event model.Event
+		switch record.Outcome {
+		case model.OutcomeTimeout:
+			event = taskEvent(model.EventTaskFailed, execution, id, "Execution of command id : %s, timed out after %s", id, execution.Command.TimeoutDuration())
+		case model.OutcomeCancelled:
+			event = taskEvent(model.EventTaskFailed, execution, id, "Execution of command id : %s, cancelled", id)
+		case model.OutcomeFailure:
+			event = taskEvent(model.EventTaskFailed, execution, id, "Execution of command id : %s, failed, exit code: %v, error: %v", id, result.ExitCode, err)
+		default:
+			event = taskEvent(model.EventTaskSucceeded, execution, id, "Execution of command id : %s, completed, type: %s, output: %s", id, kindOfCommand, result.Stdout)
 		}
+		scheduler.events.Publish(runEvent(event, record))
+		scheduler.metrics.observeRun(id, execution.Command.Name, record)
 	}()
-	// Increase number of executions
-	execution.Times++
-	var typeOfCommand = fmt.Sprintf("%T", execution.Command.Command)
-	switch typeOfCommand {
-	case "string":
-		cmd := fmt.Sprintf("%v", execution.Command.Command)
-		runTextCommand(scheduler, id, cmd)
-	case "[]string":
-		var cmdArr = execution.Command.Command.([]string)
-		runTextArrayCommand(scheduler, id, cmdArr)
-	default:
-		if strings.Contains(typeOfCommand, "model.ComputableValue") {
-			var computable = execution.Command.Command.(model.ComputableValue)
-			runComputableCommand(scheduler, id, execution, computable)
-		} else if strings.Contains(typeOfCommand, "func") {
-			// Try available command
-			var function = execution.Command.Command.(func(model.ExecutionContext) error)
-			runFunctionCommand(scheduler, id, execution, function)
-		} else if strings.Contains(typeOfCommand, "[]") {
-			// Slice of something ...
-			// We hope model/ComputableValue or func
-			//TODO: Implement reflection to seek into the array and collect data, if check of types matches with
-			// on one of following: string, []string, model.ComputationValue or func(model.ExecutionContext) error
-			// in this case I run the matching elements in the array, as in the other cases
-			scheduler.errors <- errors.New(fmt.Sprintf("Unable to execute command of type %s for command id %s, slice of objects execution not implemented yet", typeOfCommand, id))
-		} else {
-			// Unknown type
-			scheduler.errors <- errors.New(fmt.Sprintf("Unable to execute command of type %s", typeOfCommand))
+	scheduler.events.Publish(runEvent(taskEvent(model.EventTaskStarted, execution, id, "Execution of command id : %s, started, attempt: %d", id, attempt), record))
+	result, record.Steps, err = runCommandSpec(ctx, scheduler, id, execution, execution.Command.Command)
+	return record
+}
+
+// Runs a command spec, reporting the steps results of composite commands
+func runCommandSpec(ctx context.Context, scheduler *scheduler, id string, execution *model.Execution, command model.CommandSpec) (utils.CommandResult, []model.StepResult, error) {
+	var result = utils.CommandResult{ExitCode: -1}
+	var err = command.Validate()
+	if err != nil {
+		return result, nil, err
+	}
+	switch command.Kind {
+	case model.CommandKindShell:
+		result, err = runTextCommand(ctx, scheduler, id, command.Shell, commandProcessOptions(ctx, execution))
+	case model.CommandKindExec:
+		result, err = runTextArrayCommand(ctx, scheduler, id, command.Args, commandProcessOptions(ctx, execution))
+	case model.CommandKindHttp:
+		result, err = runHttpCommand(ctx, scheduler, id, *command.Http)
+	case model.CommandKindFunc:
+		result, err = runFuncCommand(ctx, scheduler, id, execution, *command.Func)
+	case model.CommandKindComposite:
+		return runCompositeCommand(ctx, scheduler, id, execution, *command.Composite)
+	case model.CommandKindGo:
+		switch value := command.Value().(type) {
+		case model.ComputableValue:
+			result, err = runComputableCommand(ctx, scheduler, id, execution, value)
+		case func(model.ExecutionContext) error:
+			result, err = runFunctionCommand(ctx, scheduler, id, execution, value)
 		}
 	}
+	return result, nil, err
 }
 
-func scheduleSingleTask(schedule *scheduler, execution *model.Execution, ref *model.CommandConfigRef) error {
+// Applies the command concurrency policy to a due run, while previous runs are executing, and reports if the
+// run must be scheduled or it has been skipped. Caller must hold the executions lock
+func applyConcurrencyPolicy(schedule *scheduler, execution *model.Execution) (bool, bool) {
+	if execution.Running == 0 {
+		return true, false
+	}
+	switch execution.Command.Policy() {
+	case model.ConcurrencyForbid:
+		// The skipped fire time counts as last execution, so the time table moves forward
+		execution.Skipped++
+		execution.Last = time.Now()
+		return false, true
+	case model.ConcurrencyReplace:
+		for _, cancel := range schedule.activeRuns[execution.UUID] {
+			cancel()
+		}
+	}
+	return true, false
+}
+
+// Waits for the task turn, accordingly to the scheduler running tasks limit and the command concurrency policy,
+// and returns the function that releases it, or an error when the scheduler stops while waiting
+func acquireRunSlot(schedule *scheduler, execution *model.Execution) (func(), error) {
+	var ctx = schedule.baseContext()
+	var slots = make([]chan struct{}, 0)
+	if execution.Command.Policy() == model.ConcurrencyQueue {
+		schedule.execMutex.Lock()
+		if _, ok := schedule.taskSlots[execution.UUID]; !ok {
+			schedule.taskSlots[execution.UUID] = make(chan struct{}, 1)
+		}
+		slots = append(slots, schedule.taskSlots[execution.UUID])
+		schedule.execMutex.Unlock()
+	}
+	if schedule.slots != nil {
+		slots = append(slots, schedule.slots)
+	}
+	var release = func(acquired []chan struct{}) {
+		for _, slot := range acquired {
+			<-slot
+		}
+	}
+	schedule.metrics.queueDepth.Add(1)
+	defer schedule.metrics.queueDepth.Add(-1)
+	for idx, slot := range slots {
+		select {
+		case slot <- struct{}{}:
+		case <-ctx.Done():
+			release(slots[:idx])
+			return nil, ctx.Err()
+		}
+	}
+	return func() {
+		release(slots)
+	}, nil
+}
+
+func scheduleSingleTask(schedule *scheduler, execution *model.Execution, id string) error {
 	var err error
 	defer func() {
 		if r := recover(); r != nil {
@@ -162,92 +483,186 @@ func scheduleSingleTask(schedule *scheduler, execution *model.Execution, ref *mo
 		}
 	}()
 
+	var needScheduling, skipped bool
+	schedule.execMutex.Lock()
 	if execution.NeedScheduling() {
+		needScheduling, skipped = applyConcurrencyPolicy(schedule, execution)
+	}
+	if needScheduling {
+		execution.Scheduled = true
+		execution.Queued++
+	}
+	schedule.execMutex.Unlock()
+	if skipped {
+		schedule.events.Publish(taskEvent(model.EventTaskSkipped, execution, id, "Execution of command id : %s, skipped, previous run still executing", id))
+		_ = schedule.saveExecutions()
+	}
+	if needScheduling {
+		var event = taskEvent(model.EventTaskScheduled, execution, id, "Execution of command id : %s, scheduled at %s", id, execution.Next.Format(time.RFC3339))
+		event.Next = execution.Next
+		event.Trigger = model.TriggerSchedule
+		schedule.events.Publish(event)
+	}
+	if needScheduling {
+		schedule.runs.Add(1)
 		go func(schedule *scheduler, execution *model.Execution, id string) {
+			var started bool
 			defer func() {
 				if r := recover(); r != nil {
-					schedule.errors <- errors.New(fmt.Sprintf("%v", r))
+					schedule.publishError(errors.New(fmt.Sprintf("%v", r)))
+				}
+				schedule.execMutex.Lock()
+				if started {
+					execution.Running--
 				} else {
-					schedule.warnings <- errors.New(fmt.Sprintf("Scheduler tasks %s completed!!", id))
+					execution.Queued--
+					execution.Scheduled = false
 				}
-				execution.Scheduled = false
+				schedule.execMutex.Unlock()
 				//Save with scheduler execution state for non cached tasks
 				_ = schedule.saveExecutions()
+				schedule.runs.Done()
 			}()
-			time.Sleep(time.Since(execution.Next))
-			executeSingleTask(schedule, execution, id)
-		}(schedule, execution, ref.UUID)
-		execution.Scheduled = true
+			time.Sleep(time.Until(execution.Next))
+			release, errS := acquireRunSlot(schedule, execution)
+			if errS == nil && schedule.isStopping() {
+				release()
+				errS = errors.New("scheduler is stopping")
+			}
+			if errS != nil {
+				schedule.events.Publish(taskEvent(model.EventTaskSkipped, execution, id, "Execution of command id : %s, not started, scheduler is stopping", id))
+				return
+			}
+			defer release()
+			var ctx, cancel = context.WithCancel(schedule.baseContext())
+			defer cancel()
+			var runId = schedule.startRun(execution, cancel)
+			defer schedule.endRun(id, runId)
+			started = true
+			_ = schedule.saveExecutions()
+			executeTaskWithRetry(ctx, schedule, execution, id, model.TriggerSchedule)
+		}(schedule, execution, id)
 		//Save with scheduler execution state for non cached tasks
 		_ = schedule.saveExecutions()
 	}
-	fmt.Printf("%v", execution)
 	return err
 }
 
+// Runs the task out of its time table, on demand or when its upstream tasks complete. Manual runs don't change
+// the regular time table bookkeeping of the task (Times, Last and Next)
+func triggerSingleTask(schedule *scheduler, execution *model.Execution, id string, trigger model.TriggerSource) {
+	var workflow = trigger == model.TriggerDependency
+	if workflow {
+		schedule.execMutex.Lock()
+		_, skipped := applyConcurrencyPolicy(schedule, execution)
+		schedule.execMutex.Unlock()
+		if skipped {
+			schedule.events.Publish(taskEvent(model.EventTaskSkipped, execution, id, "Execution of command id : %s, skipped, previous run still executing", id))
+			return
+		}
+	}
+	var event = taskEvent(model.EventTaskTriggered, execution, id, "Execution of command id : %s, triggered, source: %s", id, trigger)
+	if workflow {
+		event.Message = fmt.Sprintf("Execution of command id : %s, upstream tasks completed, starting run", id)
+	}
+	event.Trigger = trigger
+	schedule.events.Publish(event)
+	schedule.runs.Add(1)
+	go func() {
+		var started bool
+		defer func() {
+			if r := recover(); r != nil {
+				schedule.publishError(errors.New(fmt.Sprintf("%v", r)))
+			}
+			if started {
+				schedule.execMutex.Lock()
+				execution.Running--
+				schedule.execMutex.Unlock()
+			}
+			//Save with scheduler execution state for non cached tasks
+			_ = schedule.saveExecutions()
+			schedule.runs.Done()
+		}()
+		// Manual and workflow runs follow the scheduler running tasks limit, as scheduled runs
+		release, errS := acquireRunSlot(schedule, execution)
+		if errS == nil && schedule.isStopping() {
+			release()
+			errS = errors.New("scheduler is stopping")
+		}
+		if errS != nil {
+			schedule.events.Publish(taskEvent(model.EventTaskSkipped, execution, id, "Execution of command id : %s, not started, scheduler is stopping", id))
+			return
+		}
+		defer release()
+		var ctx, cancel = context.WithCancel(schedule.baseContext())
+		defer cancel()
+		schedule.execMutex.Lock()
+		// Workflow runs don't consume the repeat budget and don't shift the period time table
+		if workflow {
+			execution.WorkflowRuns++
+			execution.LastWorkflowRun = time.Now()
+		} else {
+			execution.Triggered++
+			execution.LastTriggered = time.Now()
+		}
+		execution.Running++
+		schedule.execMutex.Unlock()
+		started = true
+		_ = schedule.saveExecutions()
+		var runId = schedule.registerRun(id, cancel)
+		defer schedule.endRun(id, runId)
+		executeTaskWithRetry(ctx, schedule, execution, id, trigger)
+	}()
+}
+
 func executeSchedulerTasks(scheduler0 *scheduler) error {
 	var err error
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
-			scheduler0.errors <- errors.New(fmt.Sprintf("Stopping scheduler due to error: %v", err))
-			scheduler0.running = false
-		} else {
-			scheduler0.warnings <- errors.New(fmt.Sprintf(fmt.Sprint("Scheduler tasks completed ..."), err))
+			scheduler0.publishError(errors.New(fmt.Sprintf("Stopping scheduler due to error: %v", err)))
+			scheduler0.halt()
 		}
 	}()
-	var channel = make(chan model.CommandConfigRef)
-	sendCommands(channel, scheduler0)
-	pool := sync.WaitGroup{}
-tasksCycle:
-	for {
-		select {
-		case <- time.After(10 * time.Second):
-			break tasksCycle
-		case v := <- channel:
-			go func(scheduler1 *scheduler, ref model.CommandConfigRef, pool *sync.WaitGroup) {
-				var err error
-				cmd := scheduler1.cacheValue(ref.UUID)
-				if cmd == nil {
-					cmd, err = scheduler1.loadItem(ref.UUID)
-					if err != nil {
-						scheduler1.errors <- err
-						return
-					}
-				}
-				if cmd != nil {
-					var exec = scheduler1.ToExecutionWith(ref, *cmd)
-					if exec != nil {
-						if ! scheduler1.IsExecutionStored(exec.UUID) {
-							scheduler1.runningTasks = append(scheduler1.runningTasks, *exec)
-						}
-						if exec.NeedScheduling() {
-							pool.Add(1)
-							defer func() {
-								if r := recover(); r != nil {
-									scheduler1.errors <- errors.New(fmt.Sprintf("%v", r))
-								}
-								pool.Done()
-							}()
-							err = scheduleSingleTask(scheduler1, exec, &ref)
-							if err != nil {
-								scheduler1.errors <- err
-							}
-						}
-						// I do not track unscheduled tasks at the moment
-						//} else {
-						//	scheduler1.warnings <- errors.New(fmt.Sprintf("Task for id: %s already scheduled", ref.UUID))
-						//}
-					} else {
-						scheduler1.errors <- errors.New(fmt.Sprintf("Unable to retrive or create task execution record for task id: %s", ref.UUID))
-					}
-				} else {
-					scheduler1.errors <- errors.New(fmt.Sprintf("Unable to retrive command task with id: %s", ref.UUID))
-				}
-			}(scheduler0, v, &pool)
+	for _, exec := range prepareExecutions(scheduler0) {
+		errT := scheduleSingleTask(scheduler0, exec, exec.UUID)
+		if errT != nil {
+			scheduler0.publishError(errT)
 		}
 	}
-	pool.Wait()
 	return err
 }
 
+// Retrieves the executions of all tasks, creating the missing ones, holding the scheduler lock so the tasks
+// can't change meanwhile
+func prepareExecutions(scheduler0 *scheduler) []*model.Execution {
+	scheduler0.RLock()
+	defer scheduler0.RUnlock()
+	var out = make([]*model.Execution, 0)
+	for _, ref := range scheduler0.allReferences() {
+		cmd, errT := scheduler0.commandOf(ref.UUID)
+		if errT != nil {
+			scheduler0.publishError(errT)
+			continue
+		}
+		if cmd == nil {
+			scheduler0.publishError(errors.New(fmt.Sprintf("Unable to retrive command task with id: %s", ref.UUID)))
+			continue
+		}
+		scheduler0.execMutex.Lock()
+		var exec = scheduler0.ToExecutionWith(ref, *cmd)
+		if exec != nil && ! scheduler0.IsExecutionStored(exec.UUID) {
+			scheduler0.runningTasks = append(scheduler0.runningTasks, exec)
+		}
+		if exec != nil {
+			syncDependencies(scheduler0, exec)
+		}
+		scheduler0.execMutex.Unlock()
+		if exec == nil {
+			scheduler0.publishError(errors.New(fmt.Sprintf("Unable to retrive or create task execution record for task id: %s", ref.UUID)))
+			continue
+		}
+		out = append(out, exec)
+	}
+	return out
+}
@@ -0,0 +1,335 @@
+package cron
+
+import (
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func shellTask(name string, shell string) model.CommandConfig {
+	return model.CommandConfig{Name: name, Command: model.CommandSpec{Kind: model.CommandKindShell, Shell: shell}}
+}
+
+func TestStopWhileRunWaitsForSlot(t *testing.T) {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	s.maxRunning = 1
+	s.stopGracePeriod = "100ms"
+	for _, cmd := range []model.CommandConfig{shellTask("first", "sleep 5"), shellTask("second", "true")} {
+		if err := s.AddToCache(cmd); err != nil {
+			t.Fatalf("Unable to add task: %v", err)
+		}
+	}
+	var subscription = s.Subscribe(0)
+	defer subscription.Close()
+	if err := s.Start(); err != nil {
+		t.Fatalf("Unable to start the scheduler: %v", err)
+	}
+	if err := s.Start(); err == nil {
+		t.Errorf("Expected error starting a running scheduler")
+	}
+	if err := s.Trigger("first"); err != nil {
+		t.Fatalf("Unable to trigger first task: %v", err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	if err := s.Trigger("second"); err != nil {
+		t.Fatalf("Unable to trigger second task: %v", err)
+	}
+	// Status readers run while the scheduler stops
+	var wg sync.WaitGroup
+	var stopped = make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stopped:
+				return
+			default:
+				_ = s.IsRunning()
+				_ = s.State()
+			}
+		}
+	}()
+	if err := s.Stop(); err != nil {
+		t.Fatalf("Unable to stop the scheduler: %v", err)
+	}
+	s.Wait()
+	close(stopped)
+	wg.Wait()
+	if s.IsRunning() || s.State() != model.SchedulerStateStopped {
+		t.Errorf("Expected stopped scheduler, found state: %s", s.State())
+	}
+	if err := s.Stop(); err == nil {
+		t.Errorf("Expected error stopping a stopped scheduler")
+	}
+	var skipped bool
+	for !skipped {
+		select {
+		case event := <-subscription.Events():
+			skipped = event.Type == model.EventTaskSkipped && event.Name == "second"
+		default:
+			t.Fatalf("Expected the waiting run of second task to be skipped")
+		}
+	}
+}
+
+func TestTimeZoneValidation(t *testing.T) {
+	var cases = []struct {
+		timeZone string
+		error    bool
+	}{
+		{"", false},
+		{"UTC", false},
+		{"Europe/Rome", false},
+		{"Mars/Olympus", true},
+		{"+02:00", true},
+	}
+	for _, c := range cases {
+		var cmd = shellTask("task", "true")
+		cmd.TimeZone = c.timeZone
+		cmd.Period = "1h"
+		var err = validateCommandConfig(cmd, false)
+		if c.error != (err != nil) {
+			t.Errorf("Time zone %q: expected error %v, found: %v", c.timeZone, c.error, err)
+		}
+	}
+	var s = newScheduler(store.NewMemoryStore(), false)
+	for timeZone, expected := range map[string]string{"": time.Local.String(), "Asia/Tokyo": "Asia/Tokyo", "Mars/Olympus": time.Local.String()} {
+		s.timeZone = timeZone
+		if loc := s.Location(); loc.String() != expected {
+			t.Errorf("Scheduler time zone %q: expected location %s, found %s", timeZone, expected, loc)
+		}
+	}
+}
+
+func TestValidateRetryConfig(t *testing.T) {
+	var cases = []struct {
+		name  string
+		retry model.RetryConfig
+		error bool
+	}{
+		{"no retry", model.RetryConfig{}, false},
+		{"complete", model.RetryConfig{MaxAttempts: 3, Backoff: model.BackoffExponential, Delay: "1s", MaxDelay: "1m",
+			Jitter: 0.2, ExitCodes: []int{1}, ErrorMatch: "refused"}, false},
+		{"negative attempts", model.RetryConfig{MaxAttempts: -1}, true},
+		{"unknown backoff", model.RetryConfig{Backoff: "linear"}, true},
+		{"invalid delay", model.RetryConfig{Delay: "10"}, true},
+		{"zero max delay", model.RetryConfig{MaxDelay: "0s"}, true},
+		{"negative jitter", model.RetryConfig{Jitter: -0.1}, true},
+		{"jitter over 1", model.RetryConfig{Jitter: 1.5}, true},
+		{"invalid error match", model.RetryConfig{ErrorMatch: "(["}, true},
+	}
+	for _, c := range cases {
+		var err = validateRetryConfig(c.retry)
+		if c.error != (err != nil) {
+			t.Errorf("%s: expected error %v, found: %v", c.name, c.error, err)
+		}
+	}
+}
+
+func TestFindReference(t *testing.T) {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	if err := s.AddToCache(shellTask("cached", "true")); err != nil {
+		t.Fatalf("Unable to add cached task: %v", err)
+	}
+	var persisted = shellTask("persisted", "true")
+	persisted.Period = "1h"
+	if _, err := s.Add(persisted); err != nil {
+		t.Fatalf("Unable to add persisted task: %v", err)
+	}
+	var cachedId, persistedId = taskUUID(t, s, "cached"), taskUUID(t, s, "persisted")
+	// The unique identifier wins over a task named as another task identifier
+	s.commands = append(s.commands, model.CommandConfigRef{UUID: "legacy", Name: cachedId})
+	var cases = []struct {
+		key    string
+		uuid   string
+		cached bool
+		found  bool
+	}{
+		{cachedId, cachedId, true, true},
+		{"cached", cachedId, true, true},
+		{persistedId, persistedId, false, true},
+		{"persisted", persistedId, false, true},
+		{"legacy", "legacy", false, true},
+		{"", "", false, false},
+		{"missing", "", false, false},
+		{"Cached", "", false, false},
+	}
+	for _, c := range cases {
+		s.RLock()
+		ref, cached, idx, found := s.findReference(c.key)
+		s.RUnlock()
+		if found != c.found || ref.UUID != c.uuid || cached != c.cached || (found != (idx >= 0)) {
+			t.Errorf("Key %q: expected task %q (cached %v, found %v), found %q (cached %v, found %v, index %d)",
+				c.key, c.uuid, c.cached, c.found, ref.UUID, cached, found, idx)
+		}
+	}
+}
+
+func TestValidateTaskName(t *testing.T) {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	for _, name := range []string{"first", "second"} {
+		if err := s.AddToCache(shellTask(name, "true")); err != nil {
+			t.Fatalf("Unable to add task %s: %v", name, err)
+		}
+	}
+	var first = taskUUID(t, s, "first")
+	var cases = []struct {
+		name  string
+		id    string
+		error bool
+	}{
+		{"", "", false},
+		{"third", "", false},
+		{"first", "", true},
+		{"first", first, false},
+		{"second", first, true},
+		{first, "", true},
+		{first, first, false},
+	}
+	for _, c := range cases {
+		s.RLock()
+		var err = s.validateName(c.name, c.id)
+		s.RUnlock()
+		if c.error != (err != nil) {
+			t.Errorf("Name %q of task %q: expected error %v, found: %v", c.name, c.id, c.error, err)
+		}
+	}
+	if err := s.AddToCache(shellTask("first", "true")); err == nil {
+		t.Errorf("Expected error adding a task with a used name")
+	}
+	if err := s.Delete("second"); err != nil {
+		t.Errorf("Unable to delete a task by name: %v", err)
+	}
+	if err := s.Delete(first); err != nil {
+		t.Errorf("Unable to delete a task by unique identifier: %v", err)
+	}
+	if len(s.References()) != 0 {
+		t.Errorf("Expected no tasks, found %d", len(s.References()))
+	}
+}
+
+func TestPauseAndEnablePersistence(t *testing.T) {
+	var stores = map[string]func() store.Store{
+		"memory": func() store.Store {
+			return store.NewMemoryStore()
+		},
+		// Native item files can't hold the disabled flag, it's kept in the configuration file
+		"file": func() store.Store {
+			return store.NewFileStore(filepath.Join(t.TempDir(), "config.json"), io.EncodingJson)
+		},
+	}
+	for name, newStore := range stores {
+		var st = newStore()
+		var s = newScheduler(st, false)
+		for _, task := range []string{"first", "second"} {
+			var cmd = shellTask(task, "true")
+			cmd.Period = "1h"
+			if _, err := s.Add(cmd); err != nil {
+				t.Fatalf("%s: unable to add task %s: %v", name, task, err)
+			}
+		}
+		var steps = []struct {
+			action  func() error
+			error   bool
+			paused  bool
+			enabled map[string]bool
+		}{
+			{s.Pause, false, true, map[string]bool{"first": true, "second": true}},
+			{s.Pause, true, true, map[string]bool{"first": true, "second": true}},
+			{func() error { return s.Disable("first") }, false, true, map[string]bool{"first": false, "second": true}},
+			{func() error { return s.Disable("first") }, false, true, map[string]bool{"first": false, "second": true}},
+			{s.Resume, false, false, map[string]bool{"first": false, "second": true}},
+			{s.Resume, true, false, map[string]bool{"first": false, "second": true}},
+			{func() error { return s.Disable(taskUUID(t, s, "second")) }, false, false, map[string]bool{"first": false, "second": false}},
+			{func() error { return s.Enable("first") }, false, false, map[string]bool{"first": true, "second": false}},
+			{func() error { return s.Enable("missing") }, true, false, map[string]bool{"first": true, "second": false}},
+		}
+		for idx, step := range steps {
+			if err := step.action(); step.error != (err != nil) {
+				t.Errorf("%s, step %d: expected error %v, found: %v", name, idx, step.error, err)
+			}
+			// The state is read again from the store by a new scheduler
+			var loaded = newScheduler(st, false)
+			if err := loaded.Load(); err != nil {
+				t.Fatalf("%s, step %d: unable to load the scheduler: %v", name, idx, err)
+			}
+			if paused := loaded.State() == model.SchedulerStatePaused; paused != step.paused {
+				t.Errorf("%s, step %d: expected paused %v, found state %s", name, idx, step.paused, loaded.State())
+			}
+			for task, enabled := range step.enabled {
+				_, cmd, err := loaded.Get(task)
+				if err != nil || cmd.IsEnabled() != enabled {
+					t.Errorf("%s, step %d: expected task %s enabled %v, found %v, error: %v", name, idx, task, enabled, cmd.IsEnabled(), err)
+				}
+			}
+		}
+	}
+}
+
+func TestTriggerState(t *testing.T) {
+	var cases = []struct {
+		name     string
+		shell    string
+		enabled  bool
+		triggers int
+		success  bool
+	}{
+		{"success", "true", true, 1, true},
+		{"failure", "exit 3", true, 1, false},
+		{"repeated", "true", true, 3, true},
+		// Disabled tasks can still be triggered on demand
+		{"disabled", "true", false, 2, true},
+	}
+	for _, c := range cases {
+		var s = newScheduler(store.NewMemoryStore(), false)
+		var cmd = shellTask(c.name, c.shell)
+		cmd.Period = "1h"
+		cmd.Enabled = &c.enabled
+		ref, err := s.Add(cmd)
+		if err != nil {
+			t.Fatalf("%s: unable to add task: %v", c.name, err)
+		}
+		var before = time.Now()
+		for i := 0; i < c.triggers; i++ {
+			if err = s.Trigger(c.name); err != nil {
+				t.Fatalf("%s: unable to trigger the task: %v", c.name, err)
+			}
+			s.runs.Wait()
+		}
+		var found bool
+		for _, exec := range s.Running() {
+			if exec.UUID != ref.UUID {
+				continue
+			}
+			found = true
+			if exec.Triggered != c.triggers || exec.LastTriggered.Before(before) {
+				t.Errorf("%s: expected %d manual runs after %v, found %d at %v", c.name, c.triggers, before, exec.Triggered, exec.LastTriggered)
+			}
+			// Manual runs don't change the time table bookkeeping
+			if exec.Times != 0 || !exec.Last.IsZero() || exec.WorkflowRuns != 0 {
+				t.Errorf("%s: unexpected time table change, found %+v", c.name, exec)
+			}
+		}
+		if !found {
+			t.Errorf("%s: expected execution record for task %s", c.name, ref.UUID)
+		}
+		var records = s.History(model.HistoryFilter{UUID: ref.UUID})
+		if len(records) != c.triggers {
+			t.Errorf("%s: expected %d history records, found %d", c.name, c.triggers, len(records))
+		}
+		for _, record := range records {
+			if record.Trigger != model.TriggerManual || record.Success != c.success {
+				t.Errorf("%s: expected manual run with success %v, found %s with success %v", c.name, c.success, record.Trigger, record.Success)
+			}
+		}
+	}
+	var s = newScheduler(store.NewMemoryStore(), false)
+	if err := s.Trigger("missing"); err == nil {
+		t.Errorf("Expected error triggering a missing task")
+	}
+}
@@ -0,0 +1,227 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/model"
+	"strings"
+)
+
+// Retrieves the configuration of the cached or persisted task with given unique identifier
+func (s *scheduler) commandOf(id string) (*model.CommandConfig, error) {
+	if cmd := s.cacheValue(id); cmd != nil {
+		return cmd, nil
+	}
+	return s.loadItem(id)
+}
+
+// Collects the references of the cached and persisted tasks
+func (s *scheduler) allReferences() []model.CommandConfigRef {
+	var refs = make([]model.CommandConfigRef, 0)
+	refs = append(refs, s.cacheCommands...)
+	refs = append(refs, s.commands...)
+	return refs
+}
+
+// Records the upstream dependencies of the task with given unique identifier, a nil or independent command
+// removes them. It must be called holding the scheduler lock
+func (s *scheduler) indexDependencies(id string, cmd *model.CommandConfig) {
+	if cmd == nil || len(cmd.DependsOn) == 0 {
+		delete(s.dependsOn, id)
+		return
+	}
+	s.dependsOn[id] = append(make([]model.Dependency, 0), cmd.DependsOn...)
+}
+
+// Builds the upstream dependencies index of the cached and persisted tasks, loading each configuration once.
+// It must be called holding the scheduler lock
+func (s *scheduler) loadDependencies() {
+	s.dependsOn = make(map[string][]model.Dependency)
+	for _, ref := range s.allReferences() {
+		if cmd, err := s.commandOf(ref.UUID); err == nil {
+			s.indexDependencies(ref.UUID, cmd)
+		}
+	}
+}
+
+// Checks if the dependencies list refers to the task with given unique identifier
+func (s *scheduler) dependsOnTask(dependencies []model.Dependency, id string) bool {
+	for _, d := range dependencies {
+		if up, _, _, found := s.findReference(d.Task); found && up.UUID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Verifies that the command dependencies refer to existing tasks and that, replacing the configuration of the
+// task with given unique identifier (empty for a new task), the tasks dependencies graph has no cycles
+func validateDependencies(s *scheduler, id string, cmd model.CommandConfig) error {
+	var graph = make(map[string][]string)
+	var node = id
+	if node == "" {
+		node = "<new task>"
+	}
+	for _, d := range cmd.DependsOn {
+		if !d.On.Valid() {
+			return errors.New(fmt.Sprintf("Invalid dependency condition '%s' (available: success, failure, completion)", d.On))
+		}
+		ref, _, _, found := s.findReference(d.Task)
+		if !found {
+			return errors.New(fmt.Sprintf("Unable to find upstream task with id or name: %s", d.Task))
+		}
+		graph[node] = append(graph[node], ref.UUID)
+	}
+	if len(graph[node]) == 0 {
+		return nil
+	}
+	for _, ref := range s.allReferences() {
+		if ref.UUID == id {
+			continue
+		}
+		for _, d := range s.dependsOn[ref.UUID] {
+			if up, _, _, found := s.findReference(d.Task); found {
+				graph[ref.UUID] = append(graph[ref.UUID], up.UUID)
+			}
+		}
+	}
+	if cycle := findCycle(graph, node); len(cycle) > 0 {
+		return errors.New(fmt.Sprintf("Dependency cycle between tasks: %s", strings.Join(cycle, " -> ")))
+	}
+	return nil
+}
+
+// Finds a cycle in the graph reachable from the given node, returning the nodes path
+func findCycle(graph map[string][]string, start string) []string {
+	var visited = make(map[string]bool)
+	var path = make([]string, 0)
+	var onPath = make(map[string]int)
+	var visit func(node string) []string
+	visit = func(node string) []string {
+		if idx, ok := onPath[node]; ok {
+			return append(append([]string{}, path[idx:]...), node)
+		}
+		if visited[node] {
+			return nil
+		}
+		visited[node] = true
+		onPath[node] = len(path)
+		path = append(path, node)
+		for _, next := range graph[node] {
+			if cycle := visit(next); cycle != nil {
+				return cycle
+			}
+		}
+		path = path[:len(path)-1]
+		delete(onPath, node)
+		return nil
+	}
+	return visit(start)
+}
+
+// Verifies that no other task depends on the task with given unique identifier
+func validateNoDependents(s *scheduler, id string) error {
+	for _, ref := range s.allReferences() {
+		if ref.UUID == id {
+			continue
+		}
+		if s.dependsOnTask(s.dependsOn[ref.UUID], id) {
+			return errors.New(fmt.Sprintf("Task id: %s is upstream of task id: %s, remove the dependency first", id, ref.UUID))
+		}
+	}
+	return nil
+}
+
+// Verifies that, renaming the task with given reference, no other task refers to its current name as upstream,
+// so the dependents are never orphaned or bound to a task taking the old name later
+func validateRename(s *scheduler, ref model.CommandConfigRef, name string) error {
+	if ref.Name == "" || ref.Name == name {
+		return nil
+	}
+	for _, other := range s.allReferences() {
+		if other.UUID == ref.UUID {
+			continue
+		}
+		for _, d := range s.dependsOn[other.UUID] {
+			if d.Task == ref.Name {
+				return errors.New(fmt.Sprintf("Task '%s' is upstream of task id: %s by name, update the dependency before renaming it", ref.Name, other.UUID))
+			}
+		}
+	}
+	return nil
+}
+
+// Aligns the execution dependencies state to the command dependencies, keeping the state of the unchanged ones.
+// It must be called holding the executions lock
+func syncDependencies(s *scheduler, execution *model.Execution) {
+	var states = make([]model.DependencyState, 0)
+	for _, d := range execution.Command.DependsOn {
+		var state = model.DependencyState{
+			Task: d.Task,
+			On:   d.Condition(),
+		}
+		if ref, _, _, found := s.findReference(d.Task); found {
+			state.UUID = ref.UUID
+		}
+		for _, old := range execution.Dependencies {
+			if old.UUID == state.UUID && old.On == state.On {
+				state.Outcome = old.Outcome
+				state.Finished = old.Finished
+				state.Satisfied = d.SatisfiedBy(old.Outcome)
+			}
+		}
+		states = append(states, state)
+	}
+	execution.Dependencies = states
+}
+
+// Records the final run of the task with given unique identifier in its downstream tasks workflow state, and
+// runs the downstream tasks whose dependencies are all satisfied. The downstream tasks are found in the
+// dependencies index, only the ones without execution state load their configuration
+func runDownstreamTasks(schedule *scheduler, id string, record model.RunRecord) {
+	if schedule.isStopping() {
+		// Runs cancelled by the scheduler stop don't complete the workflow
+		return
+	}
+	var ready = make([]*model.Execution, 0)
+	schedule.RLock()
+	for _, ref := range schedule.allReferences() {
+		if (ref.Enabled != nil && !*ref.Enabled) || !schedule.dependsOnTask(schedule.dependsOn[ref.UUID], id) {
+			continue
+		}
+		schedule.execMutex.Lock()
+		var exec = schedule.ToExecution(ref)
+		if exec == nil {
+			schedule.execMutex.Unlock()
+			continue
+		}
+		if !schedule.IsExecutionStored(exec.UUID) {
+			schedule.runningTasks = append(schedule.runningTasks, exec)
+		}
+		syncDependencies(schedule, exec)
+		var upstream bool
+		for idx, d := range exec.Command.DependsOn {
+			if exec.Dependencies[idx].UUID == id {
+				upstream = true
+				exec.Dependencies[idx].Outcome = record.Outcome
+				exec.Dependencies[idx].Finished = record.End
+				exec.Dependencies[idx].Satisfied = d.SatisfiedBy(record.Outcome)
+			}
+		}
+		// A paused scheduler keeps the satisfied dependencies until the next upstream run
+		if upstream && exec.DependenciesSatisfied() && !schedule.paused {
+			// A new workflow run starts, waiting for the next upstream runs
+			for idx := range exec.Dependencies {
+				exec.Dependencies[idx].Outcome = ""
+				exec.Dependencies[idx].Satisfied = false
+			}
+			ready = append(ready, exec)
+		}
+		schedule.execMutex.Unlock()
+	}
+	schedule.RUnlock()
+	for _, exec := range ready {
+		triggerSingleTask(schedule, exec, exec.UUID, model.TriggerDependency)
+	}
+	_ = schedule.saveExecutions()
+}
@@ -0,0 +1,200 @@
+package cron
+
+import (
+	"github.com/hellgate75/go-cron/model"
+	"github.com/hellgate75/go-cron/store"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dependentTask(name string, dependsOn ...model.Dependency) model.CommandConfig {
+	var cmd = shellTask(name, "true")
+	cmd.DependsOn = dependsOn
+	return cmd
+}
+
+// Creates a scheduler with the workflow A -> B -> C
+func newWorkflowScheduler(t *testing.T) *scheduler {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	for _, cmd := range []model.CommandConfig{
+		shellTask("A", "true"),
+		dependentTask("B", model.Dependency{Task: "A"}),
+		dependentTask("C", model.Dependency{Task: "B"}),
+	} {
+		if err := s.AddToCache(cmd); err != nil {
+			t.Fatalf("Unable to add task %s: %v", cmd.Name, err)
+		}
+	}
+	return s
+}
+
+func taskUUID(t *testing.T, s *scheduler, name string) string {
+	ref, _, err := s.Get(name)
+	if err != nil {
+		t.Fatalf("Unable to find task %s: %v", name, err)
+	}
+	return ref.UUID
+}
+
+func TestFindCycle(t *testing.T) {
+	var cases = []struct {
+		name     string
+		graph    map[string][]string
+		start    string
+		expected string
+	}{
+		{"no edges", map[string][]string{}, "a", ""},
+		{"chain", map[string][]string{"a": {"b"}, "b": {"c"}}, "a", ""},
+		{"diamond", map[string][]string{"a": {"b", "c"}, "b": {"d"}, "c": {"d"}}, "a", ""},
+		{"self loop", map[string][]string{"a": {"a"}}, "a", "a -> a"},
+		{"loop", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, "a", "a -> b -> c -> a"},
+		{"loop not through start", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"b"}}, "a", "b -> c -> b"},
+		{"unreachable loop", map[string][]string{"a": {"b"}, "c": {"d"}, "d": {"c"}}, "a", ""},
+	}
+	for _, c := range cases {
+		var cycle = strings.Join(findCycle(c.graph, c.start), " -> ")
+		if cycle != c.expected {
+			t.Errorf("%s: expected cycle %q, found %q", c.name, c.expected, cycle)
+		}
+	}
+}
+
+func TestValidateDependencies(t *testing.T) {
+	var cases = []struct {
+		name  string
+		key   string
+		cmd   model.CommandConfig
+		error string
+	}{
+		{"new independent task", "", shellTask("D", "true"), ""},
+		{"new downstream task", "", dependentTask("D", model.Dependency{Task: "C", On: model.DependOnFailure}), ""},
+		{"upstream by unique identifier", "", dependentTask("D", model.Dependency{Task: "<A>"}), ""},
+		{"missing upstream", "", dependentTask("D", model.Dependency{Task: "missing"}), "Unable to find upstream task"},
+		{"invalid condition", "", dependentTask("D", model.Dependency{Task: "A", On: "always"}), "Invalid dependency condition"},
+		{"self dependency", "A", dependentTask("A", model.Dependency{Task: "A"}), "Dependency cycle"},
+		{"indirect cycle", "A", dependentTask("A", model.Dependency{Task: "C"}), "Dependency cycle"},
+		{"reversed edge", "C", dependentTask("C", model.Dependency{Task: "A"}), ""},
+	}
+	for _, c := range cases {
+		var s = newWorkflowScheduler(t)
+		for idx, d := range c.cmd.DependsOn {
+			if d.Task == "<A>" {
+				c.cmd.DependsOn[idx].Task = taskUUID(t, s, "A")
+			}
+		}
+		var err error
+		if c.key == "" {
+			err = s.AddToCache(c.cmd)
+		} else {
+			err = s.Update(c.key, c.cmd)
+		}
+		if c.error == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		} else if c.error != "" && (err == nil || !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected error containing %q, found: %v", c.name, c.error, err)
+		}
+	}
+}
+
+func TestValidateNoDependents(t *testing.T) {
+	var s = newWorkflowScheduler(t)
+	var steps = []struct {
+		key   string
+		error bool
+	}{
+		{"A", true},
+		{"B", true},
+		{"C", false},
+		{"A", true},
+		{"B", false},
+		{"A", false},
+	}
+	for idx, step := range steps {
+		var err = s.Delete(step.key)
+		if step.error != (err != nil) {
+			t.Errorf("Step %d, deleting %s: expected error %v, found: %v", idx, step.key, step.error, err)
+		}
+	}
+	if len(s.References()) != 0 {
+		t.Errorf("Expected no tasks, found %d", len(s.References()))
+	}
+}
+
+func TestRenameUpstreamTask(t *testing.T) {
+	var s = newWorkflowScheduler(t)
+	var a, b = taskUUID(t, s, "A"), taskUUID(t, s, "B")
+	// D refers to A by unique identifier
+	if err := s.AddToCache(dependentTask("D", model.Dependency{Task: a})); err != nil {
+		t.Fatalf("Unable to add task D: %v", err)
+	}
+	var steps = []struct {
+		key   string
+		cmd   model.CommandConfig
+		error bool
+	}{
+		// B refers to A by name
+		{"A", shellTask("A2", "true"), true},
+		{"C", dependentTask("C2", model.Dependency{Task: "B"}), false},
+		{"D", dependentTask("D2", model.Dependency{Task: a}), false},
+		// C2 refers to B by name
+		{"B", dependentTask("B2", model.Dependency{Task: "A"}), true},
+		{"C2", dependentTask("C2", model.Dependency{Task: b}), false},
+		{"B", dependentTask("B2", model.Dependency{Task: "A"}), false},
+		{"B2", dependentTask("B2", model.Dependency{Task: a}), false},
+		{"A", shellTask("A2", "true"), false},
+	}
+	for idx, step := range steps {
+		var err = s.Update(step.key, step.cmd)
+		if step.error != (err != nil) {
+			t.Errorf("Step %d, updating %s as %s: expected error %v, found: %v", idx, step.key, step.cmd.Name, step.error, err)
+		}
+	}
+}
+
+func TestDownstreamFanOut(t *testing.T) {
+	var s = newScheduler(store.NewMemoryStore(), false)
+	var repeated = dependentTask("repeated", model.Dependency{Task: "A", On: model.DependOnCompletion})
+	repeated.Repeat = 1
+	for _, cmd := range []model.CommandConfig{
+		shellTask("A", "true"),
+		shellTask("B", "true"),
+		dependentTask("onSuccess", model.Dependency{Task: "A"}),
+		dependentTask("onFailure", model.Dependency{Task: "A", On: model.DependOnFailure}),
+		repeated,
+		dependentTask("both", model.Dependency{Task: "A"}, model.Dependency{Task: "B"}),
+	} {
+		if err := s.AddToCache(cmd); err != nil {
+			t.Fatalf("Unable to add task %s: %v", cmd.Name, err)
+		}
+	}
+	var runs = []struct {
+		upstream string
+		outcome  model.RunOutcome
+		expected map[string]int
+	}{
+		{"A", model.OutcomeSuccess, map[string]int{"onSuccess": 1, "repeated": 1}},
+		{"A", model.OutcomeFailure, map[string]int{"onSuccess": 1, "onFailure": 1, "repeated": 2}},
+		// Workflow runs don't consume the repeat budget
+		{"A", model.OutcomeSuccess, map[string]int{"onSuccess": 2, "onFailure": 1, "repeated": 3}},
+		// Both tasks completed since the last run of the downstream task
+		{"B", model.OutcomeSuccess, map[string]int{"onSuccess": 2, "onFailure": 1, "repeated": 3, "both": 1}},
+	}
+	for idx, run := range runs {
+		runDownstreamTasks(s, taskUUID(t, s, run.upstream), model.RunRecord{Outcome: run.outcome, End: time.Now()})
+		s.runs.Wait()
+		for _, name := range []string{"onSuccess", "onFailure", "repeated", "both"} {
+			var id = taskUUID(t, s, name)
+			var runs = len(s.History(model.HistoryFilter{UUID: id, Trigger: model.TriggerDependency}))
+			if runs != run.expected[name] {
+				t.Errorf("Run %d, %s completed: expected %d runs of %s, found %d", idx, run.upstream, run.expected[name], name, runs)
+			}
+			for _, exec := range s.Running() {
+				if exec.UUID == id && (exec.WorkflowRuns != run.expected[name] || exec.Times != 0 || !exec.Last.IsZero()) {
+					t.Errorf("Run %d: unexpected %s counters, workflow runs: %d, times: %d, last: %v", idx, name,
+						exec.WorkflowRuns, exec.Times, exec.Last)
+				}
+			}
+		}
+	}
+}
@@ -76,6 +76,6 @@ func DecodeGobValue(out interface{}, in []byte) error  {
 		}
 	}()
 	var buff = bytes.NewBuffer(in)
-	err = gob.NewDecoder(buff).Decode(in)
+	err = gob.NewDecoder(buff).Decode(out)
 	return err
 }
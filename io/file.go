@@ -1,6 +1,7 @@
 package io
 
 import (
+	"bytes"
 	"encoding/gob"
 	"encoding/json"
 	"encoding/xml"
@@ -10,6 +11,7 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
 )
@@ -55,6 +57,7 @@ func loadFileBytes(file string) ([]byte, error) {
 	}()
 	f, err := os.Open(file)
 	if err == nil {
+		defer f.Close()
 		data, err = ioutil.ReadAll(f)
 	}
 	return data, err
@@ -67,10 +70,76 @@ func saveFileBytes(file string, data []byte, perm os.FileMode) error {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
 	}()
-	err = ioutil.WriteFile(file, data, perm)
+	err = WriteFileAtomic(file, data, perm)
 	return err
 }
 
+// Permissions of the scheduler files and folders, the configuration can hold credentials
+const (
+	FileMode   os.FileMode = 0600
+	FolderMode os.FileMode = 0700
+)
+
+// Writes the file atomically: data are written and synced to a temporary file in the same folder, that then
+// replaces the file. The replaced file is kept as backup, and an existing file keeps its owner permissions, while
+// the group and others permissions are always removed
+func WriteFileAtomic(file string, data []byte, perm os.FileMode) error {
+	return writeFile(file, data, perm, true)
+}
+
+func writeFile(file string, data []byte, perm os.FileMode, backup bool) error {
+	var dir, name = filepath.Split(file)
+	if dir == "" {
+		dir = "."
+	}
+	if info, err := os.Stat(file); err == nil {
+		perm = info.Mode().Perm()
+	}
+	// Files written by older versions can be readable by other users
+	perm &^= 0077
+	temp, err := ioutil.TempFile(dir, "."+name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = temp.Write(data)
+	if err == nil {
+		err = temp.Sync()
+	}
+	if errC := temp.Close(); err == nil {
+		err = errC
+	}
+	if err == nil {
+		err = os.Chmod(temp.Name(), perm)
+	}
+	if err == nil && backup {
+		// Hard link, so the file is never missing. Without a previous file there is no backup
+		_ = os.Remove(BackupFile(file))
+		_ = os.Link(file, BackupFile(file))
+	}
+	if err == nil {
+		err = os.Rename(temp.Name(), file)
+	}
+	if err != nil {
+		_ = os.Remove(temp.Name())
+		return err
+	}
+	SyncFolder(dir)
+	return nil
+}
+
+// Retrieves the backup of the given file, the previous version of the file written atomically
+func BackupFile(file string) string {
+	return file + ".bak"
+}
+
+// Flushes the folder entries, so the renamed files survive a crash. Not all the systems support it
+func SyncFolder(dir string) {
+	if f, err := os.Open(dir); err == nil {
+		_ = f.Sync()
+		_ = f.Close()
+	}
+}
+
 func getReaderFrom(file string) io.Reader {
 	if fi, err := os.Stat(file); err == nil && !fi.IsDir() {
 		f, errF := os.Open(file)
@@ -91,10 +160,9 @@ func getWriterFrom(file string, perm os.FileMode) io.Writer {
 	return nil
 }
 
-// Load Configuration from given file
+// Load Configuration from given file. A corrupted file is restored from its backup, see readRecover
 func ReadConfig(enc Encoding, file string, config interface{}) error {
 	var err error
-	var data = make([]byte, 0)
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
@@ -102,47 +170,50 @@ func ReadConfig(enc Encoding, file string, config interface{}) error {
 	}()
 	switch enc {
 	case EncodingJson:
-		data, err = loadFileBytes(file)
-		if err == nil {
-			err = json.Unmarshal(data, &config)
-		}
+		err = readRecover(file, config, func(data []byte, out interface{}) error {
+			return json.Unmarshal(data, &out)
+		})
 	case EncodingXml:
-		data, err = loadFileBytes(file)
-		if err == nil {
-			err = xml.Unmarshal(data, &config)
-		}
+		err = readRecover(file, config, func(data []byte, out interface{}) error {
+			return xml.Unmarshal(data, &out)
+		})
 	case EncodingYaml:
-		data, err = loadFileBytes(file)
-		if err == nil {
-			err = yaml.Unmarshal(data, &config)
-		}
+		err = readRecover(file, config, func(data []byte, out interface{}) error {
+			// out is already a pointer, yaml cannot decode through the interface pointer
+			return yaml.Unmarshal(data, out)
+		})
 	default:
 		err = errors.New(fmt.Sprintf("Unknown encoding format: %v", enc))
 	}
 	return err
 }
 
-// Load Configuration from given file
-func ReadNative(file string, config interface{}) error {
-	var err error
+// Load Configuration from given file, config must be a pointer. A corrupted file is restored from its backup,
+// see readRecover
+func ReadNative(file string, config interface{}) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
 	}()
-	err = gob.NewDecoder(getReaderFrom(file)).Decode(&config)
+	err = readRecover(file, config, func(data []byte, out interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(out)
+	})
 	return err
 }
 
-// Load Configuration from given file
-func SaveNative(file string, config interface{}) error {
-	var err error
+// Save Configuration to given file
+func SaveNative(file string, config interface{}) (err error) {
 	defer func() {
 		if r := recover(); r != nil {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
 	}()
-	err = gob.NewEncoder(getWriterFrom(file, 0777)).Encode(&config)
+	var buffer bytes.Buffer
+	err = gob.NewEncoder(&buffer).Encode(config)
+	if err == nil {
+		err = WriteFileAtomic(file, buffer.Bytes(), FileMode)
+	}
 	return err
 }
 
@@ -159,17 +230,17 @@ func SaveConfig(enc Encoding, file string, config interface{}) error {
 	case EncodingJson:
 		data, err = json.Marshal(&config)
 		if err == nil {
-			err = saveFileBytes(file, data, 0777)
+			err = saveFileBytes(file, data, FileMode)
 		}
 	case EncodingXml:
 		data, err = xml.Marshal(&config)
 		if err == nil {
-			err = saveFileBytes(file, data, 0777)
+			err = saveFileBytes(file, data, FileMode)
 		}
 	case EncodingYaml:
 		data, err = yaml.Marshal(&config)
 		if err == nil {
-			err = saveFileBytes(file, data, 0777)
+			err = saveFileBytes(file, data, FileMode)
 		}
 	default:
 		err = errors.New(fmt.Sprintf("Unknown encoding format: %v", enc))
@@ -400,7 +471,10 @@ func decomposeElement(in interface{}) (headerSet, valuesSet, error) {
 		var e = reflect.Indirect(reflect.ValueOf(&in)).Elem()
 		var typeOfT = e.Type()
 		for i := 0; i < e.NumField(); i++ {
-			f := e.Field(i)
+			if typeOfT.Field(i).PkgPath != "" {
+				// Unexported field
+				continue
+			}
 			name := typeOfT.Field(i).Name
 			fName := formatColumn(name)
 			var value interface{}
@@ -411,7 +485,6 @@ func decomposeElement(in interface{}) (headerSet, valuesSet, error) {
 			if value != nil {
 				fValue = fmt.Sprintf("%v", value)
 			}
-			vType := f.Type()
 			var length = len(fValue)
 			if length > 0 && len(fName) > len(fValue) {
 				length = len(fName)
@@ -432,7 +505,7 @@ func decomposeElement(in interface{}) (headerSet, valuesSet, error) {
 				Value: fValue,
 				SubValues: make([]*valueItem, 0),
 			}
-			if vType.Kind() == reflect.Struct || vType.Kind() == reflect.Interface {
+			if isCompositeValue(value) {
 				var hs headerSet
 				var vs valuesSet
 				hs, vs, err = decomposeElement(value)
@@ -459,6 +532,18 @@ func decomposeElement(in interface{}) (headerSet, valuesSet, error) {
 }
 
 
+// Verifies if the value is a structure to be decomposed in sub-columns, structures printable
+// as text (e.g.: time.Time) are reported as a single column
+func isCompositeValue(value interface{}) bool {
+	if value == nil || reflect.TypeOf(value).Kind() != reflect.Struct {
+		return false
+	}
+	if _, ok := value.(fmt.Stringer); ok {
+		return false
+	}
+	return true
+}
+
 type headerElem struct {
 	Name		string
 	Value		string
@@ -0,0 +1,230 @@
+package io
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testConfig struct {
+	Name  string `json:"name" yaml:"name" xml:"name"`
+	Count int    `json:"count" yaml:"count" xml:"count"`
+}
+
+func fileMode(t *testing.T, file string) os.FileMode {
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("Unable to read file %s: %v", file, err)
+	}
+	return info.Mode().Perm()
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	var dir = t.TempDir()
+	var file = filepath.Join(dir, "config.json")
+	if err := WriteFileAtomic(file, []byte("first"), 0644); err != nil {
+		t.Fatalf("Unable to write the file: %v", err)
+	}
+	if mode := fileMode(t, file); mode != 0600 {
+		t.Errorf("Expected new file mode 0600, found %#o", mode)
+	}
+	if _, err := os.Stat(BackupFile(file)); !os.IsNotExist(err) {
+		t.Errorf("Expected no backup without a previous file, found: %v", err)
+	}
+	if err := WriteFileAtomic(file, []byte("second"), FileMode); err != nil {
+		t.Fatalf("Unable to replace the file: %v", err)
+	}
+	if data, err := os.ReadFile(file); err != nil || string(data) != "second" {
+		t.Errorf("Expected file content 'second', found %q, error: %v", data, err)
+	}
+	if data, err := os.ReadFile(BackupFile(file)); err != nil || string(data) != "first" {
+		t.Errorf("Expected backup content 'first', found %q, error: %v", data, err)
+	}
+	// No temporary file is left in the folder
+	if files, _ := filepath.Glob(filepath.Join(dir, ".*.tmp")); len(files) != 0 {
+		t.Errorf("Expected no temporary files, found %v", files)
+	}
+}
+
+func TestWriteFileMode(t *testing.T) {
+	var cases = []struct {
+		name     string
+		existing os.FileMode
+		perm     os.FileMode
+		expected os.FileMode
+	}{
+		{"new file", 0, 0666, 0600},
+		{"new private file", 0, 0400, 0400},
+		{"existing readable by others", 0644, 0600, 0600},
+		{"existing writable by group", 0660, 0600, 0600},
+		{"existing owner permissions kept", 0400, 0600, 0400},
+		{"existing executable", 0755, 0600, 0700},
+	}
+	for _, c := range cases {
+		var file = filepath.Join(t.TempDir(), "file")
+		if c.existing != 0 {
+			if err := os.WriteFile(file, []byte("old"), 0600); err != nil {
+				t.Fatalf("%s: unable to write the file: %v", c.name, err)
+			}
+			if err := os.Chmod(file, c.existing); err != nil {
+				t.Fatalf("%s: unable to change the file mode: %v", c.name, err)
+			}
+		}
+		if err := WriteFileAtomic(file, []byte("new"), c.perm); err != nil {
+			t.Errorf("%s: unable to write the file: %v", c.name, err)
+			continue
+		}
+		if mode := fileMode(t, file); mode != c.expected {
+			t.Errorf("%s: expected mode %#o, found %#o", c.name, c.expected, mode)
+		}
+	}
+}
+
+func TestReadRecover(t *testing.T) {
+	var valid = []byte(`{"name": "backup", "count": 2}`)
+	var cases = []struct {
+		name      string
+		content   []byte
+		backup    []byte
+		recovered bool
+		corrupted bool
+	}{
+		{"valid file", valid, nil, false, false},
+		{"empty file", []byte{}, valid, true, false},
+		{"blank file", []byte(" \n"), valid, true, false},
+		{"truncated file", valid[:12], valid, true, false},
+		{"no backup", valid[:12], nil, false, true},
+		{"corrupted backup", []byte{}, []byte(`{"name": `), false, true},
+		{"empty backup", valid[:12], []byte{}, false, true},
+	}
+	for _, c := range cases {
+		var file = filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(file, c.content, FileMode); err != nil {
+			t.Fatalf("%s: unable to write the file: %v", c.name, err)
+		}
+		if c.backup != nil {
+			if err := os.WriteFile(BackupFile(file), c.backup, FileMode); err != nil {
+				t.Fatalf("%s: unable to write the backup: %v", c.name, err)
+			}
+		}
+		var config testConfig
+		var err = ReadConfig(EncodingJson, file, &config)
+		var recovered *RecoveredError
+		var corrupted *CorruptedError
+		if c.recovered != errors.As(err, &recovered) || c.corrupted != errors.As(err, &corrupted) {
+			t.Errorf("%s: expected recovered %v and corrupted %v, found: %v", c.name, c.recovered, c.corrupted, err)
+			continue
+		}
+		if c.corrupted {
+			if config != (testConfig{}) || corrupted.File != file {
+				t.Errorf("%s: expected reset value of file %s, found %+v of file %s", c.name, file, config, corrupted.File)
+			}
+			continue
+		}
+		if config.Name != "backup" || config.Count != 2 {
+			t.Errorf("%s: unexpected value %+v", c.name, config)
+		}
+		if c.recovered {
+			// The damaged file has been replaced with the backup
+			if data, err := os.ReadFile(file); err != nil || string(data) != string(valid) {
+				t.Errorf("%s: expected restored file, found %q, error: %v", c.name, data, err)
+			}
+			if err = ReadConfig(EncodingJson, file, &config); err != nil {
+				t.Errorf("%s: unexpected error reading the restored file: %v", c.name, err)
+			}
+		}
+	}
+}
+
+func TestReadNativeRecover(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "item.gob")
+	for _, count := range []int{1, 2} {
+		if err := SaveNative(file, testConfig{Name: "item", Count: count}); err != nil {
+			t.Fatalf("Unable to save the native file: %v", err)
+		}
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Unable to read the native file: %v", err)
+	}
+	if err = os.WriteFile(file, data[:len(data)/2], FileMode); err != nil {
+		t.Fatalf("Unable to truncate the native file: %v", err)
+	}
+	var config testConfig
+	err = ReadNative(file, &config)
+	var recovered *RecoveredError
+	if !errors.As(err, &recovered) || config.Count != 1 {
+		t.Errorf("Expected value restored from the previous version, found %+v, error: %v", config, err)
+	}
+}
+
+func TestResetCorrupted(t *testing.T) {
+	var file = filepath.Join(t.TempDir(), "history.gob")
+	if err := os.WriteFile(file, []byte("garbage"), FileMode); err != nil {
+		t.Fatalf("Unable to write the file: %v", err)
+	}
+	var out []testConfig
+	var err = ReadNative(file, &out)
+	var corrupted *CorruptedError
+	if !errors.As(err, &corrupted) {
+		t.Fatalf("Expected corrupted error, found: %v", err)
+	}
+	err = ResetCorrupted(corrupted)
+	var recovered *RecoveredError
+	if !errors.As(err, &recovered) || !strings.Contains(recovered.Recovery, ".corrupt") {
+		t.Errorf("Expected file moved aside, found: %v", err)
+	}
+	if _, err = os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("Expected corrupted file removed, found: %v", err)
+	}
+	if data, err := os.ReadFile(file + ".corrupt"); err != nil || string(data) != "garbage" {
+		t.Errorf("Expected corrupted content kept aside, found %q, error: %v", data, err)
+	}
+	// The file can't be moved twice
+	if err = ResetCorrupted(corrupted); err != corrupted {
+		t.Errorf("Expected the corrupted error, found: %v", err)
+	}
+}
+
+func TestLockFolder(t *testing.T) {
+	var dir = t.TempDir()
+	lock, err := LockFolder(dir, time.Second)
+	if err != nil {
+		t.Fatalf("Unable to lock the folder: %v", err)
+	}
+	var start = time.Now()
+	if second, err := LockFolder(dir, 200*time.Millisecond); err == nil {
+		_ = second.Unlock()
+		t.Fatalf("Expected locked folder error")
+	} else if !strings.Contains(err.Error(), "locked by another process") {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 200*time.Millisecond {
+		t.Errorf("Expected to wait the lock for 200ms, waited %v", elapsed)
+	}
+	// The lock is acquired when released during the wait
+	var released = make(chan error, 1)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		released <- lock.Unlock()
+	}()
+	second, err := LockFolder(dir, 2*time.Second)
+	if err != nil {
+		t.Fatalf("Expected lock acquired after the release, found: %v", err)
+	}
+	if err = <-released; err != nil {
+		t.Errorf("Unable to unlock the folder: %v", err)
+	}
+	if err = second.Unlock(); err != nil {
+		t.Errorf("Unable to unlock the folder: %v", err)
+	}
+	if err = second.Unlock(); err != nil {
+		t.Errorf("Expected no error unlocking twice, found: %v", err)
+	}
+	if _, err = LockFolder(filepath.Join(dir, "missing"), 0); err == nil {
+		t.Errorf("Expected error locking a missing folder")
+	}
+}
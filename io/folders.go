@@ -11,7 +11,7 @@ func GetDefaultConfigFile(enc Encoding) (string, error) {
 	var err error
 	var dir = fmt.Sprintf("%s%c%s", HomeFolder(), os.PathSeparator, ".go-cron")
 	if !FileExists(dir) {
-		err = CreateFolder(dir, 0777)
+		err = CreateFolder(dir, FolderMode)
 		if err != nil {
 			return dir, err
 		}
@@ -0,0 +1,57 @@
+package io
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Interval between the attempts to acquire a locked folder
+const lockRetryInterval = 50 * time.Millisecond
+
+// Advisory lock of a folder, shared by the processes using the files in the folder
+type FolderLock struct {
+	file *os.File
+}
+
+// Acquires the exclusive lock of the folder, waiting at most the given time for the other processes to
+// release it. The lock is released by Unlock or when the process ends
+func LockFolder(dir string, wait time.Duration) (*FolderLock, error) {
+	if dir == "" {
+		dir = "."
+	}
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	var deadline = time.Now().Add(wait)
+	for {
+		locked, err := lockFile(f)
+		if err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+		if locked {
+			return &FolderLock{file: f}, nil
+		}
+		if time.Now().After(deadline) {
+			_ = f.Close()
+			return nil, errors.New(fmt.Sprintf("Folder %s is locked by another process", dir))
+		}
+		time.Sleep(lockRetryInterval)
+	}
+}
+
+// Releases the folder lock
+func (l *FolderLock) Unlock() error {
+	if l.file == nil {
+		return nil
+	}
+	var err = unlockFile(l.file)
+	if errC := l.file.Close(); err == nil {
+		err = errC
+	}
+	l.file = nil
+	return err
+}
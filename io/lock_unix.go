@@ -0,0 +1,22 @@
+//go:build !windows
+// +build !windows
+
+package io
+
+import (
+	"os"
+	"syscall"
+)
+
+// Tries to acquire the exclusive flock of the file, false when another process holds it
+func lockFile(f *os.File) (bool, error) {
+	var err = syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if err == syscall.EWOULDBLOCK {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func unlockFile(f *os.File) error {
+	return syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+}
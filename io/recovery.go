@@ -0,0 +1,91 @@
+package io
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+)
+
+// Reports a file whose content can't be decoded, and that has no valid backup
+type CorruptedError struct {
+	File  string
+	Cause error
+}
+
+func (e *CorruptedError) Error() string {
+	return fmt.Sprintf("File %s is corrupted: %v", e.File, e.Cause)
+}
+
+func (e *CorruptedError) Unwrap() error {
+	return e.Cause
+}
+
+// Reports a corrupted file that has been recovered, e.g.: restored from its backup. The read value is valid
+type RecoveredError struct {
+	File  string
+	Cause error
+	// Describes the recovery action
+	Recovery string
+}
+
+func (e *RecoveredError) Error() string {
+	return fmt.Sprintf("File %s is corrupted (%v), %s", e.File, e.Cause, e.Recovery)
+}
+
+// Reads and decodes the file. When the content is empty or can't be decoded, the value is decoded from the
+// file backup, that replaces the damaged file, and a RecoveredError is returned. Without a valid backup a
+// CorruptedError is returned
+func readRecover(file string, out interface{}, decode func(data []byte, out interface{}) error) error {
+	data, err := loadFileBytes(file)
+	if err != nil {
+		return err
+	}
+	var cause = decodeBytes(data, out, decode)
+	if cause == nil {
+		return nil
+	}
+	backup, err := loadFileBytes(BackupFile(file))
+	if err == nil {
+		resetValue(out)
+		if err = decodeBytes(backup, out, decode); err == nil {
+			// Keep the backup, the restored file is the same data
+			_ = writeFile(file, backup, FileMode, false)
+			return &RecoveredError{File: file, Cause: cause, Recovery: "restored from its backup"}
+		}
+	}
+	resetValue(out)
+	return &CorruptedError{File: file, Cause: cause}
+}
+
+func decodeBytes(data []byte, out interface{}, decode func(data []byte, out interface{}) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	if len(bytes.TrimSpace(data)) == 0 {
+		// Left by a crash while the file was written in place
+		return errors.New("empty content")
+	}
+	return decode(data, out)
+}
+
+// Resets the value referred by the pointer, discarding a partial decoding
+func resetValue(out interface{}) {
+	var v = reflect.ValueOf(out)
+	if v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().CanSet() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+}
+
+// Moves the corrupted file aside, with the corrupt extension, so its data start again empty, and returns the
+// RecoveredError describing it. It returns the given error when the file can't be moved
+func ResetCorrupted(corrupted *CorruptedError) error {
+	var moved = corrupted.File + ".corrupt"
+	if err := os.Rename(corrupted.File, moved); err != nil {
+		return corrupted
+	}
+	return &RecoveredError{File: corrupted.File, Cause: corrupted.Cause, Recovery: fmt.Sprintf("moved to %s and reset", moved)}
+}
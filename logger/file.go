@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Log file writer, rotating the file when it exceeds the maximum size or age. Rotated files are renamed with
+// the rotation time suffix (e.g.: go-cron.log.20200102-150405)
+type RotatingFile struct {
+	sync.Mutex
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+	file       *os.File
+	size       int64
+	opened     time.Time
+	closed     bool
+}
+
+// Opens the log file for append, rotating it when it exceeds the maximum size in bytes or when it has been
+// written for longer than the maximum age (0 for no limit). Only the last maxBackups rotated files are kept
+// (0 to keep all of them)
+func NewRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	if maxSize < 0 || maxAge < 0 || maxBackups < 0 {
+		return nil, errors.New("Log file maximum size, age and backups must not be negative")
+	}
+	var f = &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to create log folder: %v", err))
+	}
+	// Files last written before the maximum age are rotated at startup
+	if info, err := os.Stat(path); err == nil && maxAge > 0 && time.Since(info.ModTime()) > maxAge {
+		if err := f.rotate(); err != nil {
+			_ = f.Close()
+			return nil, err
+		}
+	} else if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to open log file %s: %v", f.path, err))
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = time.Now()
+	return nil
+}
+
+// Renames the current file with the rotation time suffix, opens a new one and removes the oldest backups. When
+// the file can't be renamed it's opened again, so the writes go on in the current file
+func (f *RotatingFile) rotate() error {
+	if f.file != nil {
+		_ = f.file.Close()
+		f.file = nil
+	}
+	if _, err := os.Stat(f.path); err == nil {
+		var backup = f.path + "." + time.Now().Format("20060102-150405")
+		for idx := 1; ; idx++ {
+			if _, err := os.Stat(backup); os.IsNotExist(err) {
+				break
+			}
+			backup = fmt.Sprintf("%s.%s.%d", f.path, time.Now().Format("20060102-150405"), idx)
+		}
+		if err := os.Rename(f.path, backup); err != nil {
+			if errO := f.open(); errO != nil {
+				return errO
+			}
+			return errors.New(fmt.Sprintf("Unable to rotate log file %s: %v", f.path, err))
+		}
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	f.removeBackups()
+	return nil
+}
+
+func (f *RotatingFile) removeBackups() {
+	if f.maxBackups == 0 {
+		return
+	}
+	backups, err := filepath.Glob(f.path + ".*")
+	if err != nil || len(backups) <= f.maxBackups {
+		return
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		infoI, errI := os.Stat(backups[i])
+		infoJ, errJ := os.Stat(backups[j])
+		if errI != nil || errJ != nil {
+			return backups[i] < backups[j]
+		}
+		return infoI.ModTime().Before(infoJ.ModTime())
+	})
+	for _, backup := range backups[:len(backups)-f.maxBackups] {
+		_ = os.Remove(backup)
+	}
+}
+
+// Writes the data in the log file, rotating it when needed. A failed rotation is tried again by the next writes,
+// while the data are written in the current file, or in the file opened again when it has been closed
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.Lock()
+	defer f.Unlock()
+	if f.closed {
+		return 0, errors.New("Log file is closed")
+	}
+	if f.file == nil {
+		if err := f.open(); err != nil {
+			return 0, err
+		}
+	}
+	if (f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize) || (f.maxAge > 0 && time.Since(f.opened) > f.maxAge) {
+		if err := f.rotate(); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+func (f *RotatingFile) Close() error {
+	f.Lock()
+	defer f.Unlock()
+	f.closed = true
+	if f.file == nil {
+		return nil
+	}
+	var err = f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,154 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeLines(t *testing.T, f *RotatingFile, lines ...string) {
+	for _, line := range lines {
+		if _, err := f.Write([]byte(line + "\n")); err != nil {
+			t.Fatalf("Unable to write line %s: %v", line, err)
+		}
+	}
+}
+
+func readText(t *testing.T, file string) string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatalf("Unable to read file %s: %v", file, err)
+	}
+	return string(data)
+}
+
+// Retrieves the content of the rotated files, from the oldest to the newest
+func backups(t *testing.T, path string) []string {
+	files, err := filepath.Glob(path + ".*")
+	if err != nil {
+		t.Fatalf("Unable to list the rotated files: %v", err)
+	}
+	sort.Slice(files, func(i, j int) bool {
+		infoI, _ := os.Stat(files[i])
+		infoJ, _ := os.Stat(files[j])
+		return infoI.ModTime().Before(infoJ.ModTime())
+	})
+	var out = make([]string, 0)
+	for _, file := range files {
+		out = append(out, readText(t, file))
+	}
+	return out
+}
+
+func TestRotatingFileSize(t *testing.T) {
+	var cases = []struct {
+		name       string
+		maxSize    int64
+		maxBackups int
+		lines      []string
+		current    string
+		backups    []string
+	}{
+		{"no limit", 0, 0, []string{"first", "second"}, "first\nsecond\n", []string{}},
+		{"under limit", 100, 0, []string{"first", "second"}, "first\nsecond\n", []string{}},
+		{"rotated", 14, 0, []string{"first", "second", "third"}, "third\n", []string{"first\nsecond\n"}},
+		{"line over limit", 4, 0, []string{"first", "second"}, "second\n", []string{"first\n"}},
+		{"all backups kept", 6, 0, []string{"l1", "l2", "l3", "l4", "l5", "l6"}, "l5\nl6\n",
+			[]string{"l1\nl2\n", "l3\nl4\n"}},
+		{"oldest backups removed", 3, 2, []string{"l1", "l2", "l3", "l4", "l5"}, "l5\n",
+			[]string{"l3\n", "l4\n"}},
+	}
+	for _, c := range cases {
+		var path = filepath.Join(t.TempDir(), "logs", "go-cron.log")
+		f, err := NewRotatingFile(path, c.maxSize, 0, c.maxBackups)
+		if err != nil {
+			t.Fatalf("%s: unable to open the log file: %v", c.name, err)
+		}
+		for _, line := range c.lines {
+			writeLines(t, f, line)
+			// Rotated files are ordered by modification time
+			time.Sleep(5 * time.Millisecond)
+		}
+		if err = f.Close(); err != nil {
+			t.Errorf("%s: unable to close the log file: %v", c.name, err)
+		}
+		if current := readText(t, path); current != c.current {
+			t.Errorf("%s: expected current file %q, found %q", c.name, c.current, current)
+		}
+		if found := backups(t, path); strings.Join(found, "|") != strings.Join(c.backups, "|") {
+			t.Errorf("%s: expected rotated files %q, found %q", c.name, c.backups, found)
+		}
+	}
+}
+
+func TestRotatingFileAge(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "go-cron.log")
+	if err := os.WriteFile(path, []byte("old\n"), 0644); err != nil {
+		t.Fatalf("Unable to write the log file: %v", err)
+	}
+	var old = time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(path, old, old); err != nil {
+		t.Fatalf("Unable to change the log file time: %v", err)
+	}
+	// Files last written before the maximum age are rotated when opened
+	f, err := NewRotatingFile(path, 0, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("Unable to open the log file: %v", err)
+	}
+	if found := backups(t, path); len(found) != 1 || found[0] != "old\n" {
+		t.Errorf("Expected old file rotated at startup, found %q", found)
+	}
+	_ = f.Close()
+
+	f, err = NewRotatingFile(path, 0, 50*time.Millisecond, 0)
+	if err != nil {
+		t.Fatalf("Unable to open the log file: %v", err)
+	}
+	defer f.Close()
+	writeLines(t, f, "first")
+	time.Sleep(100 * time.Millisecond)
+	writeLines(t, f, "second")
+	if current := readText(t, path); current != "second\n" {
+		t.Errorf("Expected file rotated after the maximum age, found %q", current)
+	}
+	if found := backups(t, path); len(found) != 2 || found[1] != "first\n" {
+		t.Errorf("Expected 2 rotated files, found %q", found)
+	}
+}
+
+func TestRotatingFileFailedRotation(t *testing.T) {
+	var dir = filepath.Join(t.TempDir(), "logs")
+	var path = filepath.Join(dir, "go-cron.log")
+	f, err := NewRotatingFile(path, 8, 0, 0)
+	if err != nil {
+		t.Fatalf("Unable to open the log file: %v", err)
+	}
+	writeLines(t, f, "first")
+	// The rotation can't open the new file while the folder is missing
+	if err = os.RemoveAll(dir); err != nil {
+		t.Fatalf("Unable to remove the log folder: %v", err)
+	}
+	if _, err = f.Write([]byte("lost\n")); err == nil {
+		t.Fatalf("Expected error writing without the log folder")
+	}
+	if err = os.MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("Unable to create the log folder: %v", err)
+	}
+	// The next writes open the file again
+	writeLines(t, f, "second", "third")
+	if current := readText(t, path); current != "third\n" {
+		t.Errorf("Expected writes to go on after the failed rotation, found %q", current)
+	}
+	if err = f.Close(); err != nil {
+		t.Errorf("Unable to close the log file: %v", err)
+	}
+	if _, err = f.Write([]byte("closed\n")); err == nil {
+		t.Errorf("Expected error writing a closed log file")
+	}
+	if _, err = NewRotatingFile(path, -1, 0, 0); err == nil {
+		t.Errorf("Expected error with a negative maximum size")
+	}
+}
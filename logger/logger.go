@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Log entry severity
+type Level int
+
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// Available level names, from the lowest to the highest severity
+var LevelList = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < LevelDebug || l > LevelError {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return LevelList[l]
+}
+
+// Parses a level name (debug, info, warn or error)
+func ParseLevel(name string) (Level, error) {
+	for idx, n := range LevelList {
+		if strings.ToLower(strings.TrimSpace(name)) == n {
+			return Level(idx), nil
+		}
+	}
+	if strings.ToLower(strings.TrimSpace(name)) == "warning" {
+		return LevelWarn, nil
+	}
+	return LevelInfo, errors.New(fmt.Sprintf("Invalid log level '%s' (available: %s)", name, strings.Join(LevelList, ", ")))
+}
+
+// Log entries output format
+type Format string
+
+const (
+	// Time, level, message and fields on a single line: [time LEVEL] message key=value
+	FormatText = Format("text")
+	// Message and fields only, without time and level
+	FormatPlain = Format("plain")
+	// One JSON object per line, with time, level, msg and the fields
+	FormatJson = Format("json")
+	// One logfmt line per entry: time=... level=... msg=... key=value
+	FormatLogfmt = Format("logfmt")
+)
+
+// Available output format names
+var FormatList = []string{string(FormatText), string(FormatPlain), string(FormatJson), string(FormatLogfmt)}
+
+// Parses an output format name (text, plain, json or logfmt)
+func ParseFormat(name string) (Format, error) {
+	for _, f := range FormatList {
+		if strings.ToLower(strings.TrimSpace(name)) == f {
+			return Format(f), nil
+		}
+	}
+	return FormatText, errors.New(fmt.Sprintf("Invalid log format '%s' (available: %s)", name, strings.Join(FormatList, ", ")))
+}
+
+// Log entry context values (e.g.: task unique identifier and run id)
+type Fields map[string]interface{}
+
+// Describes a log entry
+type Entry struct {
+	Time    time.Time
+	Level   Level
+	Message string
+	Fields  Fields
+}
+
+// Receives the log entries, implementations must be safe for concurrent use
+type Logger interface {
+	Log(entry Entry)
+}
+
+// Writes the log entry with given level, message and fields
+func Log(l Logger, level Level, message string, fields Fields) {
+	if l != nil {
+		l.Log(Entry{Time: time.Now(), Level: level, Message: message, Fields: fields})
+	}
+}
+
+func Debug(l Logger, message string, fields Fields) {
+	Log(l, LevelDebug, message, fields)
+}
+
+func Info(l Logger, message string, fields Fields) {
+	Log(l, LevelInfo, message, fields)
+}
+
+func Warn(l Logger, message string, fields Fields) {
+	Log(l, LevelWarn, message, fields)
+}
+
+func Error(l Logger, message string, fields Fields) {
+	Log(l, LevelError, message, fields)
+}
+
+// Writes the entries with at least the given level, in the given format, one per line
+type writerLogger struct {
+	sync.Mutex
+	out    io.Writer
+	level  Level
+	format Format
+}
+
+// Creates a logger writing on the given output the entries with at least the given level
+func New(out io.Writer, level Level, format Format) Logger {
+	return &writerLogger{out: out, level: level, format: format}
+}
+
+func (w *writerLogger) Log(entry Entry) {
+	if entry.Level < w.level {
+		return
+	}
+	var line = Encode(entry, w.format)
+	w.Lock()
+	defer w.Unlock()
+	_, _ = w.out.Write([]byte(line + "\n"))
+}
+
+// Sends the entries to all the given loggers
+type multiLogger []Logger
+
+// Creates a logger sending the entries to all the given loggers
+func Multi(loggers ...Logger) Logger {
+	return multiLogger(loggers)
+}
+
+func (m multiLogger) Log(entry Entry) {
+	for _, l := range m {
+		l.Log(entry)
+	}
+}
+
+// Discards all the entries
+type nopLogger struct{}
+
+// Creates a logger discarding all the entries
+func Nop() Logger {
+	return nopLogger{}
+}
+
+func (nopLogger) Log(entry Entry) {
+}
+
+// Encodes the entry as single line in the given format
+func Encode(entry Entry, format Format) string {
+	var keys = make([]string, 0)
+	for key := range entry.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	switch format {
+	case FormatJson:
+		sb.WriteString("{")
+		writeJson(&sb, "time", entry.Time.Format(time.RFC3339Nano))
+		sb.WriteString(",")
+		writeJson(&sb, "level", entry.Level.String())
+		sb.WriteString(",")
+		writeJson(&sb, "msg", entry.Message)
+		for _, key := range keys {
+			sb.WriteString(",")
+			writeJson(&sb, key, entry.Fields[key])
+		}
+		sb.WriteString("}")
+	case FormatLogfmt:
+		sb.WriteString("time=" + entry.Time.Format(time.RFC3339Nano))
+		sb.WriteString(" level=" + entry.Level.String())
+		sb.WriteString(" msg=" + logfmtValue(entry.Message))
+		for _, key := range keys {
+			sb.WriteString(" " + key + "=" + logfmtValue(entry.Fields[key]))
+		}
+	default:
+		if format != FormatPlain {
+			sb.WriteString(fmt.Sprintf("[%s %s] ", entry.Time.Format(time.RFC3339), strings.ToUpper(entry.Level.String())))
+		}
+		sb.WriteString(entry.Message)
+		for _, key := range keys {
+			sb.WriteString(" " + key + "=" + logfmtValue(entry.Fields[key]))
+		}
+	}
+	return sb.String()
+}
+
+func writeJson(sb *strings.Builder, key string, value interface{}) {
+	keyData, _ := json.Marshal(key)
+	if err, ok := value.(error); ok {
+		value = err.Error()
+	}
+	valueData, err := json.Marshal(value)
+	if err != nil {
+		valueData, _ = json.Marshal(fmt.Sprintf("%v", value))
+	}
+	sb.Write(keyData)
+	sb.WriteString(":")
+	sb.Write(valueData)
+}
+
+// Formats a logfmt value, quoted when it contains spaces, quotes or equal signs
+func logfmtValue(value interface{}) string {
+	var text = fmt.Sprintf("%v", value)
+	if text == "" || strings.ContainsAny(text, " \t\r\n\"=\\") {
+		return strconv.Quote(text)
+	}
+	return text
+}
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	var entry = Entry{
+		Time:    time.Date(2020, 1, 2, 15, 4, 5, 600, time.UTC),
+		Level:   LevelWarn,
+		Message: "Task failed",
+		Fields:  Fields{"uuid": "abc", "attempt": 2, "error": errors.New("exit status 1"), "empty": ""},
+	}
+	var cases = []struct {
+		format   Format
+		expected string
+	}{
+		{FormatText, `[2020-01-02T15:04:05Z WARN] Task failed attempt=2 empty="" error="exit status 1" uuid=abc`},
+		{FormatPlain, `Task failed attempt=2 empty="" error="exit status 1" uuid=abc`},
+		{FormatJson, `{"time":"2020-01-02T15:04:05.0000006Z","level":"warn","msg":"Task failed","attempt":2,"empty":"","error":"exit status 1","uuid":"abc"}`},
+		{FormatLogfmt, `time=2020-01-02T15:04:05.0000006Z level=warn msg="Task failed" attempt=2 empty="" error="exit status 1" uuid=abc`},
+	}
+	for _, c := range cases {
+		if line := Encode(entry, c.format); line != c.expected {
+			t.Errorf("%s: expected %s, found %s", c.format, c.expected, line)
+		}
+	}
+	// JSON lines are valid documents also with quotes and new lines
+	var line = Encode(Entry{Message: "say \"hi\"\nbye", Fields: Fields{"a=b": "c d"}}, FormatJson)
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(line), &doc); err != nil || doc["msg"] != "say \"hi\"\nbye" || doc["a=b"] != "c d" {
+		t.Errorf("Unexpected JSON line %s, error: %v", line, err)
+	}
+	if line = Encode(Entry{Message: "multi\nline", Fields: Fields{"path": `C:\tmp`}}, FormatLogfmt); !strings.Contains(line, `msg="multi\nline" path="C:\\tmp"`) {
+		t.Errorf("Unexpected logfmt line %s", line)
+	}
+}
+
+func TestParseLevelAndFormat(t *testing.T) {
+	var levels = map[string]Level{"debug": LevelDebug, " INFO ": LevelInfo, "warn": LevelWarn, "warning": LevelWarn, "Error": LevelError}
+	for name, expected := range levels {
+		if level, err := ParseLevel(name); err != nil || level != expected {
+			t.Errorf("Level %q: expected %s, found %s, error: %v", name, expected, level, err)
+		}
+	}
+	if _, err := ParseLevel("trace"); err == nil {
+		t.Errorf("Expected error parsing an unknown level")
+	}
+	if Level(7).String() != "level(7)" {
+		t.Errorf("Unexpected unknown level name %s", Level(7))
+	}
+	for _, name := range FormatList {
+		if format, err := ParseFormat(strings.ToUpper(name)); err != nil || string(format) != name {
+			t.Errorf("Format %q: unexpected %s, error: %v", name, format, err)
+		}
+	}
+	if _, err := ParseFormat("xml"); err == nil {
+		t.Errorf("Expected error parsing an unknown format")
+	}
+}
+
+func TestLoggerLevel(t *testing.T) {
+	var warn, all bytes.Buffer
+	var l = Multi(New(&warn, LevelWarn, FormatPlain), New(&all, LevelDebug, FormatPlain), Nop())
+	Debug(l, "debug", nil)
+	Info(l, "info", nil)
+	Warn(l, "warn", nil)
+	Error(l, "error", Fields{"code": 1})
+	if warn.String() != "warn\nerror code=1\n" {
+		t.Errorf("Unexpected warn logger output %q", warn.String())
+	}
+	if all.String() != "debug\ninfo\nwarn\nerror code=1\n" {
+		t.Errorf("Unexpected debug logger output %q", all.String())
+	}
+	// A nil logger discards the entries
+	Info(nil, "discarded", nil)
+}
@@ -21,6 +21,8 @@ func init() {
 }
 
 func main() {
+	// Commands with umask, niceness or resource limits are started through this executable
+	utils.RunProcessLauncher()
 	if len(Args) == 0 {
 		fmt.Printf("Missing command argument, available commands: %v\n", cron.Commands)
 		cron.PrintHelp(cron.DefaultParser(command))
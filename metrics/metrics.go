@@ -0,0 +1,221 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Metric family type
+type Type string
+
+const (
+	TypeCounter   = Type("counter")
+	TypeGauge     = Type("gauge")
+	TypeHistogram = Type("histogram")
+)
+
+// Default histogram buckets, in seconds, from 50 milliseconds to 1 hour
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 300, 900, 3600}
+
+// Collects metric families and writes them in the Prometheus text exposition format
+type Registry struct {
+	sync.Mutex
+	families []*Vec
+}
+
+// Creates a registry without metric families
+func NewRegistry() *Registry {
+	return &Registry{families: make([]*Vec, 0)}
+}
+
+// Metric family, with one series for each label values combination
+type Vec struct {
+	sync.Mutex
+	name    string
+	help    string
+	kind    Type
+	labels  []string
+	buckets []float64
+	series  map[string]*series
+}
+
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	count       uint64
+}
+
+// Registers a counter family with given label names
+func (r *Registry) Counter(name string, help string, labels ...string) *Vec {
+	return r.register(&Vec{name: name, help: help, kind: TypeCounter, labels: labels})
+}
+
+// Registers a gauge family with given label names
+func (r *Registry) Gauge(name string, help string, labels ...string) *Vec {
+	return r.register(&Vec{name: name, help: help, kind: TypeGauge, labels: labels})
+}
+
+// Registers a histogram family with given upper bounds (DefaultBuckets when empty) and label names
+func (r *Registry) Histogram(name string, help string, buckets []float64, labels ...string) *Vec {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return r.register(&Vec{name: name, help: help, kind: TypeHistogram, labels: labels, buckets: buckets})
+}
+
+func (r *Registry) register(v *Vec) *Vec {
+	v.series = make(map[string]*series)
+	r.Lock()
+	defer r.Unlock()
+	r.families = append(r.families, v)
+	return v
+}
+
+// Retrieves the series of the label values, creating it when missing. Caller must hold the family lock
+func (v *Vec) with(labelValues []string) *series {
+	var values = make([]string, len(v.labels))
+	copy(values, labelValues)
+	var key = strings.Join(values, "\xff")
+	s, ok := v.series[key]
+	if !ok {
+		s = &series{labelValues: values}
+		if v.kind == TypeHistogram {
+			s.counts = make([]uint64, len(v.buckets))
+		}
+		v.series[key] = s
+	}
+	return s
+}
+
+// Increases a counter or a gauge by the given value
+func (v *Vec) Add(value float64, labelValues ...string) {
+	v.Lock()
+	defer v.Unlock()
+	v.with(labelValues).value += value
+}
+
+// Increases a counter or a gauge by one
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+// Sets the value of a gauge
+func (v *Vec) Set(value float64, labelValues ...string) {
+	v.Lock()
+	defer v.Unlock()
+	v.with(labelValues).value = value
+}
+
+// Records a histogram observation
+func (v *Vec) Observe(value float64, labelValues ...string) {
+	v.Lock()
+	defer v.Unlock()
+	var s = v.with(labelValues)
+	for idx, bound := range v.buckets {
+		if value <= bound {
+			s.counts[idx]++
+		}
+	}
+	s.count++
+	s.value += value
+}
+
+// Removes the series of the label values
+func (v *Vec) Delete(labelValues ...string) {
+	v.Lock()
+	defer v.Unlock()
+	var values = make([]string, len(v.labels))
+	copy(values, labelValues)
+	delete(v.series, strings.Join(values, "\xff"))
+}
+
+// Removes all the series, gauges collected on demand are reset before setting the current values
+func (v *Vec) Reset() {
+	v.Lock()
+	defer v.Unlock()
+	v.series = make(map[string]*series)
+}
+
+// Writes all the metric families in the Prometheus text exposition format, series are sorted by label values
+func (r *Registry) WriteText(w io.Writer) error {
+	r.Lock()
+	var families = append([]*Vec{}, r.families...)
+	r.Unlock()
+	var out = bufio.NewWriter(w)
+	for _, v := range families {
+		v.write(out)
+	}
+	return out.Flush()
+}
+
+func (v *Vec) write(out *bufio.Writer) {
+	v.Lock()
+	defer v.Unlock()
+	_, _ = fmt.Fprintf(out, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	_, _ = fmt.Fprintf(out, "# TYPE %s %s\n", v.name, v.kind)
+	var keys = make([]string, 0)
+	for key := range v.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var s = v.series[key]
+		if v.kind != TypeHistogram {
+			_, _ = fmt.Fprintf(out, "%s%s %s\n", v.name, v.labelText(s.labelValues, "", 0), formatValue(s.value))
+			continue
+		}
+		for idx, bound := range v.buckets {
+			_, _ = fmt.Fprintf(out, "%s_bucket%s %d\n", v.name, v.labelText(s.labelValues, "le", bound), s.counts[idx])
+		}
+		_, _ = fmt.Fprintf(out, "%s_bucket%s %d\n", v.name, v.labelText(s.labelValues, "le", math.Inf(1)), s.count)
+		_, _ = fmt.Fprintf(out, "%s_sum%s %s\n", v.name, v.labelText(s.labelValues, "", 0), formatValue(s.value))
+		_, _ = fmt.Fprintf(out, "%s_count%s %d\n", v.name, v.labelText(s.labelValues, "", 0), s.count)
+	}
+}
+
+// Formats the series labels, with the extra label (e.g.: histogram le) when its name is not empty
+func (v *Vec) labelText(values []string, extra string, extraValue float64) string {
+	var pairs = make([]string, 0)
+	for idx, label := range v.labels {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", label, escapeLabel(values[idx])))
+	}
+	if extra != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"", extra, formatValue(extraValue)))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+func escapeHelp(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n").Replace(text)
+}
+
+func escapeLabel(text string) string {
+	return strings.NewReplacer("\\", "\\\\", "\n", "\\n", "\"", "\\\"").Replace(text)
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestWriteText(t *testing.T) {
+	var r = NewRegistry()
+	var runs = r.Counter("runs_total", "Completed runs.", "task")
+	var running = r.Gauge("running", "Running\nruns.", "task")
+	var duration = r.Histogram("duration_seconds", "Runs duration.", []float64{1, 0.5}, "task")
+	var lag = r.Gauge("lag_seconds", "Loop lag.")
+	runs.Inc("b")
+	runs.Add(2, "a")
+	running.Set(1, "quote\"back\\slash\nline")
+	duration.Observe(0.25, "a")
+	duration.Observe(0.75, "a")
+	duration.Observe(3, "a")
+	lag.Set(0.125)
+
+	var out bytes.Buffer
+	if err := r.WriteText(&out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var expected = `# HELP runs_total Completed runs.
+# TYPE runs_total counter
+runs_total{task="a"} 2
+runs_total{task="b"} 1
+# HELP running Running\nruns.
+# TYPE running gauge
+running{task="quote\"back\\slash\nline"} 1
+# HELP duration_seconds Runs duration.
+# TYPE duration_seconds histogram
+duration_seconds_bucket{task="a",le="0.5"} 1
+duration_seconds_bucket{task="a",le="1"} 2
+duration_seconds_bucket{task="a",le="+Inf"} 3
+duration_seconds_sum{task="a"} 4
+duration_seconds_count{task="a"} 3
+# HELP lag_seconds Loop lag.
+# TYPE lag_seconds gauge
+lag_seconds 0.125
+`
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition text:\n%s\nexpected:\n%s", out.String(), expected)
+	}
+}
+
+func TestResetAndDelete(t *testing.T) {
+	var r = NewRegistry()
+	var gauge = r.Gauge("next", "Next run.", "task", "name")
+	gauge.Set(10, "a", "first")
+	gauge.Set(20, "b", "second")
+	gauge.Delete("a", "first")
+	var out bytes.Buffer
+	_ = r.WriteText(&out)
+	var expected = "# HELP next Next run.\n# TYPE next gauge\nnext{task=\"b\",name=\"second\"} 20\n"
+	if out.String() != expected {
+		t.Errorf("Unexpected exposition text after delete:\n%s", out.String())
+	}
+	gauge.Reset()
+	out.Reset()
+	_ = r.WriteText(&out)
+	if out.String() != "# HELP next Next run.\n# TYPE next gauge\n" {
+		t.Errorf("Unexpected exposition text after reset:\n%s", out.String())
+	}
+}
@@ -0,0 +1,413 @@
+package model
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// Describes the type of a command spec, and which of the spec payloads is used
+type CommandKind string
+
+const (
+	// Command line, split in process arguments on spaces
+	CommandKindShell = CommandKind("shell")
+	// Process arguments list, first one is the executable
+	CommandKindExec = CommandKind("exec")
+	// HTTP request
+	CommandKindHttp = CommandKind("http")
+	// Go function registered by name in the scheduler process
+	CommandKindFunc = CommandKind("func")
+	// Composite command, made of steps
+	CommandKindComposite = CommandKind("composite")
+	// Go function or ComputableValue, kept in memory only (cached tasks)
+	CommandKindGo = CommandKind("go")
+)
+
+// Header of an HTTP request command
+type HttpHeader struct {
+	Name			string					`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	Value			string					`yaml:"value,omitempty" json:"value,omitempty" xml:"value,omitempty"`
+}
+
+// Describes the HTTP request sent by an http command, the run succeeds when the response status is 2xx
+type HttpRequest struct {
+	// Request method (default: GET)
+	Method			string					`yaml:"method,omitempty" json:"method,omitempty" xml:"method,omitempty"`
+	// Request absolute URL, http or https
+	URL				string					`yaml:"url,omitempty" json:"url,omitempty" xml:"url,omitempty"`
+	Headers			[]HttpHeader			`yaml:"headers,omitempty" json:"headers,omitempty" xml:"header,omitempty"`
+	Body			string					`yaml:"body,omitempty" json:"body,omitempty" xml:"body,omitempty"`
+}
+
+// Retrieves the request method, or the default one when not declared
+func (r HttpRequest) RequestMethod() string {
+	if r.Method == "" {
+		return "GET"
+	}
+	return strings.ToUpper(r.Method)
+}
+
+// Describes the call of a Go function registered by name
+type FuncCall struct {
+	// Registered function name
+	Name			string					`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	// Arguments passed to the function factory and in the execution context
+	Args			FuncArgs				`yaml:"args,omitempty" json:"args,omitempty" xml:"args,omitempty"`
+}
+
+// Typed command run by the scheduler. The kind declares which payload field describes the command
+type CommandSpec struct {
+	// Command type: shell, exec, http, func, composite or go
+	Kind			CommandKind				`yaml:"kind,omitempty" json:"kind,omitempty" xml:"kind,omitempty"`
+	// Command line of shell commands
+	Shell			string					`yaml:"shell,omitempty" json:"shell,omitempty" xml:"shell,omitempty"`
+	// Process arguments of exec commands
+	Args			[]string				`yaml:"args,omitempty" json:"args,omitempty" xml:"arg,omitempty"`
+	// Request of http commands
+	Http			*HttpRequest			`yaml:"http,omitempty" json:"http,omitempty" xml:"http,omitempty"`
+	// Registered function of func commands
+	Func			*FuncCall				`yaml:"func,omitempty" json:"func,omitempty" xml:"func,omitempty"`
+	// Steps of composite commands
+	Composite		*CompositeCommand		`yaml:"composite,omitempty" json:"composite,omitempty" xml:"composite,omitempty"`
+	// Function or ComputableValue of go commands, it's not persisted
+	value			CommandValue
+}
+
+// Creates a shell command, from the command line
+func NewShellCommand(line string) CommandSpec {
+	return CommandSpec{Kind: CommandKindShell, Shell: line}
+}
+
+// Creates an exec command, from the process arguments
+func NewExecCommand(args ...string) CommandSpec {
+	return CommandSpec{Kind: CommandKindExec, Args: args}
+}
+
+// Creates an http command, from the request
+func NewHttpCommand(request HttpRequest) CommandSpec {
+	return CommandSpec{Kind: CommandKindHttp, Http: &request}
+}
+
+// Creates a func command, calling the Go function registered with given name, with given arguments
+func NewFuncCommand(name string, args FuncArgs) CommandSpec {
+	return CommandSpec{Kind: CommandKindFunc, Func: &FuncCall{Name: name, Args: args}}
+}
+
+// Creates a composite command
+func NewCompositeCommand(composite CompositeCommand) CommandSpec {
+	return CommandSpec{Kind: CommandKindComposite, Composite: &composite}
+}
+
+// Creates a go command from a func(ExecutionContext) error or a ComputableValue, it can be added to
+// the scheduler cache only, because it cannot be persisted
+func NewGoCommand(value CommandValue) CommandSpec {
+	return CommandSpec{Kind: CommandKindGo, value: value}
+}
+
+// Retrieves the function or ComputableValue of go commands
+func (c CommandSpec) Value() CommandValue {
+	return c.value
+}
+
+// Checks if the spec declares no command
+func (c CommandSpec) IsEmpty() bool {
+	return c.Kind == "" && c.Shell == "" && len(c.Args) == 0 && c.Http == nil && c.Func == nil &&
+		c.Composite == nil && c.value == nil
+}
+
+// Checks if the command can be persisted, go commands live in the scheduler process only
+func (c CommandSpec) Persistable() bool {
+	if c.Kind == CommandKindGo {
+		return false
+	}
+	if c.Kind == CommandKindComposite && c.Composite != nil {
+		for _, step := range c.Composite.Steps {
+			if !step.Persistable() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Verifies the command kind and the consistency of its payload
+func (c CommandSpec) Validate() error {
+	switch c.Kind {
+	case CommandKindShell:
+		if strings.TrimSpace(c.Shell) == "" {
+			return errors.New("Shell command line must not be empty")
+		}
+	case CommandKindExec:
+		if len(c.Args) == 0 || c.Args[0] == "" {
+			return errors.New("Exec command must declare the executable as first argument")
+		}
+	case CommandKindHttp:
+		if c.Http == nil {
+			return errors.New("Http command must declare the request")
+		}
+		u, err := url.Parse(c.Http.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New(fmt.Sprintf("Invalid http command url '%s', it must be an absolute http or https url", c.Http.URL))
+		}
+		if strings.ContainsAny(c.Http.Method, " \t\r\n") {
+			return errors.New(fmt.Sprintf("Invalid http command method '%s'", c.Http.Method))
+		}
+		for _, h := range c.Http.Headers {
+			if strings.TrimSpace(h.Name) == "" {
+				return errors.New("Http command headers must have a name")
+			}
+		}
+	case CommandKindFunc:
+		if c.Func == nil || c.Func.Name == "" {
+			return errors.New("Func command must declare the registered function name")
+		}
+	case CommandKindComposite:
+		if c.Composite == nil {
+			return errors.New("Composite command has no steps")
+		}
+		return c.Composite.Validate()
+	case CommandKindGo:
+		switch c.value.(type) {
+		case func(ExecutionContext) error, ComputableValue:
+		default:
+			return errors.New(fmt.Sprintf("Go command must be a func(ExecutionContext) error or a ComputableValue, found: %T", c.value))
+		}
+	case "":
+		return errors.New("Missing command")
+	default:
+		return errors.New(fmt.Sprintf("Unknown command kind '%s' (available: shell, exec, http, func, composite, go)", c.Kind))
+	}
+	return nil
+}
+
+// Describes the command in reports and run records
+func (c CommandSpec) String() string {
+	switch c.Kind {
+	case CommandKindShell:
+		return c.Shell
+	case CommandKindExec:
+		return strings.Join(c.Args, " ")
+	case CommandKindHttp:
+		if c.Http != nil {
+			return fmt.Sprintf("%s %s", c.Http.RequestMethod(), c.Http.URL)
+		}
+	case CommandKindFunc:
+		if c.Func != nil && !c.Func.Args.IsEmpty() {
+			return fmt.Sprintf("func %s %s", c.Func.Name, c.Func.Args)
+		} else if c.Func != nil {
+			return fmt.Sprintf("func %s", c.Func.Name)
+		}
+	case CommandKindComposite:
+		if c.Composite != nil {
+			var steps = make([]string, 0)
+			for _, step := range c.Composite.Steps {
+				steps = append(steps, step.String())
+			}
+			return fmt.Sprintf("%s(%s)", c.Composite.RunMode(), strings.Join(steps, "; "))
+		}
+	case CommandKindGo:
+		return fmt.Sprintf("go %T", c.value)
+	}
+	return string(c.Kind)
+}
+
+// Sets the kind of specs declaring only a payload
+func (c *CommandSpec) inferKind() {
+	if c.Kind != "" {
+		return
+	}
+	switch {
+	case c.Shell != "":
+		c.Kind = CommandKindShell
+	case len(c.Args) > 0:
+		c.Kind = CommandKindExec
+	case c.Http != nil:
+		c.Kind = CommandKindHttp
+	case c.Func != nil:
+		c.Kind = CommandKindFunc
+	case c.Composite != nil:
+		c.Kind = CommandKindComposite
+	}
+}
+
+// Spec fields, decoded without the custom decoders
+type commandSpecFields CommandSpec
+
+// Decodes the command spec from its typed object form, or from the untyped forms used before the typed specs:
+// command strings are shell commands, lists of strings are exec commands, other lists are composite command
+// steps and objects with the steps field are composite commands
+func (c *CommandSpec) decode(unmarshal func(interface{}) error) error {
+	var line string
+	if err := unmarshal(&line); err == nil {
+		*c = CommandSpec{}
+		if line != "" {
+			*c = NewShellCommand(line)
+		}
+		return nil
+	}
+	var list []interface{}
+	if err := unmarshal(&list); err == nil {
+		var args []string
+		if err = unmarshal(&args); err == nil && len(args) > 0 {
+			*c = NewExecCommand(args...)
+			return nil
+		}
+		var steps []CommandSpec
+		if err = unmarshal(&steps); err != nil {
+			return err
+		}
+		*c = NewCompositeCommand(CompositeCommand{Steps: steps})
+		return nil
+	}
+	var fields commandSpecFields
+	if err := unmarshal(&fields); err != nil {
+		return err
+	}
+	*c = CommandSpec(fields)
+	if c.IsEmpty() {
+		var composite CompositeCommand
+		if err := unmarshal(&composite); err == nil && len(composite.Steps) > 0 {
+			*c = NewCompositeCommand(composite)
+		}
+	}
+	c.inferKind()
+	return nil
+}
+
+func (c *CommandSpec) UnmarshalJSON(data []byte) error {
+	if strings.TrimSpace(string(data)) == "null" {
+		*c = CommandSpec{}
+		return nil
+	}
+	return c.decode(func(out interface{}) error {
+		return json.Unmarshal(data, out)
+	})
+}
+
+func (c *CommandSpec) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	return c.decode(unmarshal)
+}
+
+// Decodes the command spec from its typed element form, or from the text element used for command
+// strings before the typed specs
+func (c *CommandSpec) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var form struct {
+		commandSpecFields
+		Text			string					`xml:",chardata"`
+	}
+	if err := d.DecodeElement(&form, &start); err != nil {
+		return err
+	}
+	*c = CommandSpec(form.commandSpecFields)
+	if c.IsEmpty() && strings.TrimSpace(form.Text) != "" {
+		*c = NewShellCommand(strings.TrimSpace(form.Text))
+	}
+	c.inferKind()
+	return nil
+}
+
+// Converts a command value in the command spec: command strings are shell commands, lists of strings are exec
+// commands, other lists are composite command steps, objects are decoded as command specs, Go functions and
+// ComputableValue are go commands
+func CommandOf(c CommandValue) (CommandSpec, error) {
+	switch v := c.(type) {
+	case nil:
+		return CommandSpec{}, nil
+	case CommandSpec:
+		return v, nil
+	case *CommandSpec:
+		if v == nil {
+			return CommandSpec{}, nil
+		}
+		return *v, nil
+	case string:
+		if v == "" {
+			return CommandSpec{}, nil
+		}
+		return NewShellCommand(v), nil
+	case []string:
+		return NewExecCommand(v...), nil
+	case func(ExecutionContext) error, ComputableValue:
+		return NewGoCommand(v), nil
+	case CompositeCommand:
+		return NewCompositeCommand(v), nil
+	case *CompositeCommand:
+		if v == nil {
+			return CommandSpec{}, nil
+		}
+		return NewCompositeCommand(*v), nil
+	case LegacyCompositeCommand:
+		return v.Migrate()
+	case map[string]interface{}, map[interface{}]interface{}:
+		var spec CommandSpec
+		data, err := json.Marshal(stringKeys(v))
+		if err == nil {
+			err = json.Unmarshal(data, &spec)
+		}
+		return spec, err
+	}
+	if values, ok := sliceValues(c); ok {
+		var args = make([]string, 0)
+		for _, value := range values {
+			if arg, isString := value.(string); isString {
+				args = append(args, arg)
+			}
+		}
+		if len(args) > 0 && len(args) == len(values) {
+			return NewExecCommand(args...), nil
+		}
+		var composite CompositeCommand
+		for idx, value := range values {
+			step, err := CommandOf(value)
+			if err != nil {
+				return CommandSpec{}, errors.New(fmt.Sprintf("Invalid composite command step %d: %v", idx+1, err))
+			}
+			composite.Steps = append(composite.Steps, step)
+		}
+		return NewCompositeCommand(composite), nil
+	}
+	return CommandSpec{}, errors.New(fmt.Sprintf("Unsupported command type: %T", c))
+}
+
+// Converts the maps decoded from yaml, with interface keys, in maps with string keys
+func stringKeys(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[interface{}]interface{}:
+		var out = make(map[string]interface{})
+		for key, item := range value {
+			out[fmt.Sprintf("%v", key)] = stringKeys(item)
+		}
+		return out
+	case map[string]interface{}:
+		var out = make(map[string]interface{})
+		for key, item := range value {
+			out[key] = stringKeys(item)
+		}
+		return out
+	case []interface{}:
+		var out = make([]interface{}, 0)
+		for _, item := range value {
+			out = append(out, stringKeys(item))
+		}
+		return out
+	}
+	return v
+}
+
+// Retrieves the values of any slice or array, but byte slices
+func sliceValues(c CommandValue) ([]CommandValue, bool) {
+	var v = reflect.ValueOf(c)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	var values = make([]CommandValue, 0)
+	for i := 0; i < v.Len(); i++ {
+		values = append(values, v.Index(i).Interface())
+	}
+	return values, true
+}
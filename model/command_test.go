@@ -0,0 +1,219 @@
+package model
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"encoding/xml"
+	"gopkg.in/yaml.v2"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type codec struct {
+	name      string
+	marshal   func(interface{}) ([]byte, error)
+	unmarshal func([]byte, interface{}) error
+}
+
+var codecs = []codec{
+	{"json", json.Marshal, json.Unmarshal},
+	{"yaml", yaml.Marshal, yaml.Unmarshal},
+	{"xml", xml.Marshal, xml.Unmarshal},
+	{"gob", func(in interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(in)
+		return buf.Bytes(), err
+	}, func(data []byte, out interface{}) error {
+		return gob.NewDecoder(bytes.NewReader(data)).Decode(out)
+	}},
+}
+
+func testCommands(t *testing.T) map[CommandKind]CommandSpec {
+	args, err := NewFuncArgs(map[string]interface{}{"path": "/tmp", "retain": 3})
+	if err != nil {
+		t.Fatalf("Unable to create function arguments: %v", err)
+	}
+	return map[CommandKind]CommandSpec{
+		CommandKindShell: NewShellCommand("tar -czf /tmp/backup.tgz /data"),
+		CommandKindExec:  NewExecCommand("/usr/bin/report", "--daily", "a b"),
+		CommandKindHttp: NewHttpCommand(HttpRequest{Method: "POST", URL: "https://example.com/hook",
+			Headers: []HttpHeader{{Name: "Content-Type", Value: "application/json"}}, Body: `{"ok":true}`}),
+		CommandKindFunc: NewFuncCommand("cleanup", args),
+		CommandKindComposite: NewCompositeCommand(CompositeCommand{Mode: CompositeParallel, ContinueOnError: true,
+			Steps: []CommandSpec{
+				NewShellCommand("echo first"),
+				NewExecCommand("echo", "second"),
+				NewCompositeCommand(CompositeCommand{Steps: []CommandSpec{NewFuncCommand("nested", nil)}}),
+			}}),
+	}
+}
+
+func TestCommandSpecRoundTrip(t *testing.T) {
+	for kind, command := range testCommands(t) {
+		if err := command.Validate(); err != nil {
+			t.Errorf("%s: unexpected validation error: %v", kind, err)
+		}
+		for _, c := range codecs {
+			var in = CommandConfig{Name: "task", Period: "1m", Command: command}
+			data, err := c.marshal(in)
+			if err != nil {
+				t.Errorf("%s/%s: unable to encode: %v", kind, c.name, err)
+				continue
+			}
+			var out CommandConfig
+			if err = c.unmarshal(data, &out); err != nil {
+				t.Errorf("%s/%s: unable to decode: %v", kind, c.name, err)
+				continue
+			}
+			if !reflect.DeepEqual(out, in) {
+				t.Errorf("%s/%s: expected %+v, found %+v", kind, c.name, in.Command, out.Command)
+			}
+		}
+	}
+}
+
+func TestCommandSpecUntypedDecoding(t *testing.T) {
+	var composite = NewCompositeCommand(CompositeCommand{Mode: CompositeParallel, Steps: []CommandSpec{
+		NewShellCommand("echo first"),
+		NewExecCommand("echo", "second"),
+	}})
+	var cases = []struct {
+		name     string
+		format   string
+		data     string
+		expected CommandSpec
+	}{
+		{"json string", "json", `{"command": "ls -l"}`, NewShellCommand("ls -l")},
+		{"json list", "json", `{"command": ["ls", "-l"]}`, NewExecCommand("ls", "-l")},
+		{"json steps", "json", `{"command": ["ls", ["echo", "second"]]}`, NewCompositeCommand(CompositeCommand{Steps: []CommandSpec{
+			NewShellCommand("ls"), NewExecCommand("echo", "second")}})},
+		{"json composite", "json", `{"command": {"mode": "parallel", "steps": ["echo first", ["echo", "second"]]}}`, composite},
+		{"json kind inferred", "json", `{"command": {"shell": "ls -l"}}`, NewShellCommand("ls -l")},
+		{"json empty", "json", `{"command": ""}`, CommandSpec{}},
+		{"yaml string", "yaml", "command: ls -l\n", NewShellCommand("ls -l")},
+		{"yaml list", "yaml", "command:\n- ls\n- -l\n", NewExecCommand("ls", "-l")},
+		{"yaml composite", "yaml", "command:\n  mode: parallel\n  steps:\n  - echo first\n  - [echo, second]\n", composite},
+		{"yaml kind inferred", "yaml", "command:\n  args: [ls, -l]\n", NewExecCommand("ls", "-l")},
+		{"xml text", "xml", "<CommandConfig><command> ls -l </command></CommandConfig>", NewShellCommand("ls -l")},
+		{"xml kind inferred", "xml", "<CommandConfig><command><arg>ls</arg><arg>-l</arg></command></CommandConfig>", NewExecCommand("ls", "-l")},
+	}
+	for _, c := range cases {
+		var out CommandConfig
+		var err error
+		switch c.format {
+		case "json":
+			err = json.Unmarshal([]byte(c.data), &out)
+		case "yaml":
+			err = yaml.Unmarshal([]byte(c.data), &out)
+		case "xml":
+			err = xml.Unmarshal([]byte(c.data), &out)
+		}
+		if err != nil {
+			t.Errorf("%s: unable to decode: %v", c.name, err)
+		} else if !reflect.DeepEqual(out.Command, c.expected) {
+			t.Errorf("%s: expected %+v, found %+v", c.name, c.expected, out.Command)
+		}
+	}
+}
+
+func TestCommandOf(t *testing.T) {
+	var cases = []struct {
+		name     string
+		value    CommandValue
+		expected CommandSpec
+		error    bool
+	}{
+		{"nil", nil, CommandSpec{}, false},
+		{"string", "ls -l", NewShellCommand("ls -l"), false},
+		{"strings", []string{"ls", "-l"}, NewExecCommand("ls", "-l"), false},
+		{"interface strings", []interface{}{"ls", "-l"}, NewExecCommand("ls", "-l"), false},
+		{"steps", []interface{}{"ls", []string{"echo", "2"}}, NewCompositeCommand(CompositeCommand{Steps: []CommandSpec{
+			NewShellCommand("ls"), NewExecCommand("echo", "2")}}), false},
+		{"spec", NewShellCommand("ls"), NewShellCommand("ls"), false},
+		{"map", map[interface{}]interface{}{"kind": "http", "http": map[interface{}]interface{}{"url": "http://localhost"}},
+			NewHttpCommand(HttpRequest{URL: "http://localhost"}), false},
+		{"legacy composite", LegacyCompositeCommand{Mode: CompositeParallel, Steps: []CommandValue{"ls",
+			LegacyCompositeCommand{Steps: []CommandValue{[]string{"echo", "2"}}}}},
+			NewCompositeCommand(CompositeCommand{Mode: CompositeParallel, Steps: []CommandSpec{NewShellCommand("ls"),
+				NewCompositeCommand(CompositeCommand{Steps: []CommandSpec{NewExecCommand("echo", "2")}})}}), false},
+		{"unsupported", 42, CommandSpec{}, true},
+		{"unsupported step", []interface{}{"ls", 42}, CommandSpec{}, true},
+	}
+	for _, c := range cases {
+		spec, err := CommandOf(c.value)
+		if c.error != (err != nil) {
+			t.Errorf("%s: expected error %v, found: %v", c.name, c.error, err)
+		} else if !c.error && !reflect.DeepEqual(spec, c.expected) {
+			t.Errorf("%s: expected %+v, found %+v", c.name, c.expected, spec)
+		}
+	}
+}
+
+func TestCommandSpecValidate(t *testing.T) {
+	var cases = []struct {
+		name  string
+		spec  CommandSpec
+		error string
+	}{
+		{"missing", CommandSpec{}, "Missing command"},
+		{"unknown kind", CommandSpec{Kind: "ftp"}, "Unknown command kind"},
+		{"empty shell", NewShellCommand(" "), "must not be empty"},
+		{"empty exec", NewExecCommand(""), "executable"},
+		{"relative url", NewHttpCommand(HttpRequest{URL: "/hook"}), "absolute http"},
+		{"invalid method", NewHttpCommand(HttpRequest{URL: "http://localhost", Method: "GET X"}), "method"},
+		{"unnamed func", NewFuncCommand("", nil), "function name"},
+		{"go string", NewGoCommand("ls"), "Go command"},
+		{"go func", NewGoCommand(func(ExecutionContext) error { return nil }), ""},
+	}
+	for _, c := range cases {
+		var err = c.spec.Validate()
+		if c.error == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		} else if c.error != "" && (err == nil || !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected error containing %q, found: %v", c.name, c.error, err)
+		}
+	}
+	if NewGoCommand(func(ExecutionContext) error { return nil }).Persistable() {
+		t.Errorf("Go commands must not be persistable")
+	}
+}
+
+func TestFuncArgs(t *testing.T) {
+	var cases = []struct {
+		name  string
+		value interface{}
+		json  string
+		empty bool
+	}{
+		{"nil", nil, "null", true},
+		{"map", map[string]interface{}{"b": 2, "a": "x"}, `{"a":"x","b":2}`, false},
+		{"yaml map", map[interface{}]interface{}{"a": []interface{}{1, "y"}}, `{"a":[1,"y"]}`, false},
+		{"number", 5, "5", false},
+	}
+	for _, c := range cases {
+		args, err := NewFuncArgs(c.value)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+			continue
+		}
+		data, _ := args.MarshalJSON()
+		if string(data) != c.json || args.IsEmpty() != c.empty {
+			t.Errorf("%s: expected %s (empty %v), found %s (empty %v)", c.name, c.json, c.empty, data, args.IsEmpty())
+		}
+	}
+	var args FuncArgs
+	if err := args.UnmarshalJSON([]byte("{invalid")); err == nil {
+		t.Errorf("Expected error decoding invalid function arguments")
+	}
+	if err := xml.Unmarshal([]byte(`<args>{"a": 1}</args>`), &args); err != nil {
+		t.Fatalf("Unable to decode xml function arguments: %v", err)
+	}
+	var out struct {
+		A int `json:"a"`
+	}
+	if err := args.Decode(&out); err != nil || out.A != 1 {
+		t.Errorf("Expected decoded argument 1, found %d, error: %v", out.A, err)
+	}
+}
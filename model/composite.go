@@ -0,0 +1,72 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Describes how the steps of a composite command run
+type CompositeMode string
+
+const (
+	// Steps run one after the other, in the declared order
+	CompositeSequential = CompositeMode("sequential")
+	// Steps run all together
+	CompositeParallel = CompositeMode("parallel")
+)
+
+// Command composed by a list of steps, each step is a command spec, also a nested composite command
+type CompositeCommand struct {
+	// Steps of the command
+	Steps			[]CommandSpec			`yaml:"steps,omitempty" json:"steps,omitempty" xml:"step,omitempty"`
+	// Steps run mode: sequential or parallel (default: sequential)
+	Mode			CompositeMode			`yaml:"mode,omitempty" json:"mode,omitempty" xml:"mode,omitempty"`
+	// Run all the steps even when some of them fail, by default the first failure stops the command
+	ContinueOnError	bool					`yaml:"continueOnError,omitempty" json:"continueOnError,omitempty" xml:"continue-on-error,omitempty"`
+}
+
+// Checks if the steps run all together
+func (c CompositeCommand) Parallel() bool {
+	return c.Mode == CompositeParallel
+}
+
+// Retrieves the steps run mode, or the default one when not declared
+func (c CompositeCommand) RunMode() CompositeMode {
+	if c.Mode == "" {
+		return CompositeSequential
+	}
+	return c.Mode
+}
+
+// Verifies the run mode and the steps of the command
+func (c CompositeCommand) Validate() error {
+	if c.Mode != "" && c.Mode != CompositeSequential && c.Mode != CompositeParallel {
+		return errors.New(fmt.Sprintf("Invalid composite command mode '%s' (available: sequential, parallel)", c.Mode))
+	}
+	if len(c.Steps) == 0 {
+		return errors.New("Composite command has no steps")
+	}
+	for idx, step := range c.Steps {
+		if err := step.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("Invalid composite command step %d: %v", idx+1, err))
+		}
+	}
+	return nil
+}
+
+// Describes the result of a composite command step, in the run record
+type StepResult struct {
+	// Step index in the composite command, starting from 1
+	Step			int						`yaml:"step,omitempty" json:"step,omitempty" xml:"step,omitempty"`
+	Command			string					`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+	Start			time.Time				`yaml:"start,omitempty" json:"start,omitempty" xml:"start,omitempty"`
+	Duration		time.Duration			`yaml:"duration,omitempty" json:"duration,omitempty" xml:"duration,omitempty"`
+	Outcome			RunOutcome				`yaml:"outcome,omitempty" json:"outcome,omitempty" xml:"outcome,omitempty"`
+	ExitCode		int						`yaml:"exitCode,omitempty" json:"exitCode,omitempty" xml:"exit-code,omitempty"`
+	Error			string					`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+	Stdout			string					`yaml:"stdout,omitempty" json:"stdout,omitempty" xml:"stdout,omitempty"`
+	Stderr			string					`yaml:"stderr,omitempty" json:"stderr,omitempty" xml:"stderr,omitempty"`
+	// Results of the steps of a nested composite command
+	Steps			[]StepResult			`yaml:"steps,omitempty" json:"steps,omitempty" xml:"steps>step,omitempty"`
+}
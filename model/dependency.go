@@ -0,0 +1,72 @@
+package model
+
+import (
+	"time"
+)
+
+// Describes which upstream task outcome fires a downstream task
+type DependencyCondition string
+
+const (
+	// Upstream run completed successfully
+	DependOnSuccess = DependencyCondition("success")
+	// Upstream run failed, timed out or has been cancelled
+	DependOnFailure = DependencyCondition("failure")
+	// Upstream run completed, with any outcome
+	DependOnCompletion = DependencyCondition("completion")
+)
+
+// Checks if the condition is a known one, empty condition is the default one
+func (c DependencyCondition) Valid() bool {
+	switch c {
+	case "", DependOnSuccess, DependOnFailure, DependOnCompletion:
+		return true
+	}
+	return false
+}
+
+// Declares an upstream task of a command
+type Dependency struct {
+	// Upstream task unique identifier or name
+	Task			string					`yaml:"task,omitempty" json:"task,omitempty" xml:"task,omitempty"`
+	// Upstream run outcome that fires the command: success, failure or completion (default: success)
+	On				DependencyCondition		`yaml:"on,omitempty" json:"on,omitempty" xml:"on,omitempty"`
+}
+
+// Retrieves the dependency condition, or the default one when not declared
+func (d Dependency) Condition() DependencyCondition {
+	if d.On == "" {
+		return DependOnSuccess
+	}
+	return d.On
+}
+
+// Verifies if the upstream run outcome satisfies the dependency
+func (d Dependency) SatisfiedBy(outcome RunOutcome) bool {
+	if outcome == "" {
+		return false
+	}
+	switch d.Condition() {
+	case DependOnFailure:
+		return outcome != OutcomeSuccess
+	case DependOnCompletion:
+		return true
+	}
+	return outcome == OutcomeSuccess
+}
+
+// Describes the state of a command dependency in the current workflow run
+type DependencyState struct {
+	// Upstream task unique identifier or name, as declared
+	Task			string					`yaml:"task,omitempty" json:"task,omitempty" xml:"task,omitempty"`
+	// Upstream task unique identifier
+	UUID			string					`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+	// Upstream run outcome that fires the command
+	On				DependencyCondition		`yaml:"on,omitempty" json:"on,omitempty" xml:"on,omitempty"`
+	// Outcome of the last upstream run, since the last command run, empty when upstream has not run yet
+	Outcome			RunOutcome				`yaml:"outcome,omitempty" json:"outcome,omitempty" xml:"outcome,omitempty"`
+	// End time of the last upstream run
+	Finished		time.Time				`yaml:"finished,omitempty" json:"finished,omitempty" xml:"finished,omitempty"`
+	// Dependency satisfied by the last upstream run
+	Satisfied		bool					`yaml:"satisfied,omitempty" json:"satisfied,omitempty" xml:"satisfied,omitempty"`
+}
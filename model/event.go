@@ -0,0 +1,207 @@
+package model
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Describes what happened in the scheduler
+type EventType string
+
+const (
+	// Scheduler started scheduling the tasks
+	EventSchedulerStarted = EventType("schedulerStarted")
+	// Scheduler stopped, after the running tasks completion or cancellation
+	EventSchedulerStopped = EventType("schedulerStopped")
+	// Task run planned at the Next time
+	EventTaskScheduled = EventType("taskScheduled")
+	// Task run triggered out of its time table, on demand or by its upstream tasks
+	EventTaskTriggered = EventType("taskTriggered")
+	// Task run attempt started
+	EventTaskStarted = EventType("taskStarted")
+	// Task run attempt completed without errors
+	EventTaskSucceeded = EventType("taskSucceeded")
+	// Task run attempt failed, timed out or has been cancelled
+	EventTaskFailed = EventType("taskFailed")
+	// Task failed run attempt retried, accordingly to the command retry policy
+	EventTaskRetrying = EventType("taskRetrying")
+	// Task run not started, because of the concurrency policy or the scheduler stop
+	EventTaskSkipped = EventType("taskSkipped")
+	// Scheduler or task error not related to a run outcome
+	EventError = EventType("error")
+	// Scheduler or task warning not related to a run outcome
+	EventWarning = EventType("warning")
+)
+
+// Event severity levels
+const (
+	EventLevelDebug   = "debug"
+	EventLevelInfo    = "info"
+	EventLevelWarning = "warning"
+	EventLevelError   = "error"
+)
+
+// Retrieves the severity level of the events of this type
+func (t EventType) Level() string {
+	switch t {
+	case EventTaskScheduled, EventTaskStarted:
+		return EventLevelDebug
+	case EventTaskFailed, EventError:
+		return EventLevelError
+	case EventTaskRetrying, EventTaskSkipped, EventWarning:
+		return EventLevelWarning
+	}
+	return EventLevelInfo
+}
+
+// Describes a scheduler event, with the task and run details when related to a task
+type Event struct {
+	Type		EventType				`yaml:"type,omitempty" json:"type,omitempty" xml:"type,omitempty"`
+	Level		string					`yaml:"level,omitempty" json:"level,omitempty" xml:"level,omitempty"`
+	Time		time.Time				`yaml:"time,omitempty" json:"time,omitempty" xml:"time,omitempty"`
+	// Human readable event description
+	Message		string					`yaml:"message,omitempty" json:"message,omitempty" xml:"message,omitempty"`
+	// Task unique identifier and name
+	UUID		string					`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+	Name		string					`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	RunID		string					`yaml:"runId,omitempty" json:"runId,omitempty" xml:"run-id,omitempty"`
+	Trigger		TriggerSource			`yaml:"trigger,omitempty" json:"trigger,omitempty" xml:"trigger,omitempty"`
+	Attempt		int						`yaml:"attempt,omitempty" json:"attempt,omitempty" xml:"attempt,omitempty"`
+	// Planned run time of scheduled tasks
+	Next		time.Time				`yaml:"next,omitempty" json:"next,omitempty" xml:"next,omitempty"`
+	Outcome		RunOutcome				`yaml:"outcome,omitempty" json:"outcome,omitempty" xml:"outcome,omitempty"`
+	ExitCode	int						`yaml:"exitCode,omitempty" json:"exitCode,omitempty" xml:"exit-code,omitempty"`
+	Duration	time.Duration			`yaml:"duration,omitempty" json:"duration,omitempty" xml:"duration,omitempty"`
+	Error		string					`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+}
+
+// Default number of events buffered for each subscriber
+const DefaultEventBuffer = 256
+
+// Receives the scheduler events in a bounded buffer: when the buffer is full the events are dropped and counted,
+// so slow subscribers never block the scheduler
+type Subscription struct {
+	// First field, to be 64-bit aligned for the atomic operations
+	dropped	uint64
+	events	chan Event
+	done	chan struct{}
+	bus		*EventBus
+	filter	func(Event) bool
+	once	sync.Once
+}
+
+// Retrieves the events channel, closed when the subscription or the scheduler is closed
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Retrieves a channel closed when the subscription is closed
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Retrieves the number of events dropped because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+// Stops receiving the events and closes the events channel
+func (s *Subscription) Close() {
+	if s.bus != nil {
+		s.bus.unsubscribe(s)
+	}
+}
+
+func (s *Subscription) close() {
+	s.once.Do(func() {
+		close(s.events)
+		close(s.done)
+	})
+}
+
+// Delivers the published events to all the subscribers, without blocking the publisher
+type EventBus struct {
+	// First field, to be 64-bit aligned for the atomic operations
+	dropped		uint64
+	sync.RWMutex
+	subscribers	map[*Subscription]bool
+	closed		bool
+}
+
+// Creates an event bus without subscribers
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*Subscription]bool)}
+}
+
+// Adds a subscriber with given buffer size (DefaultEventBuffer when not positive). Subscriptions of a closed
+// bus receive no event
+func (b *EventBus) Subscribe(buffer int) *Subscription {
+	return b.SubscribeFilter(buffer, nil)
+}
+
+// Adds a subscriber receiving only the events accepted by the filter (all events when nil), so the other
+// events don't fill its buffer
+func (b *EventBus) SubscribeFilter(buffer int, filter func(Event) bool) *Subscription {
+	if buffer <= 0 {
+		buffer = DefaultEventBuffer
+	}
+	var s = &Subscription{events: make(chan Event, buffer), done: make(chan struct{}), bus: b, filter: filter}
+	b.Lock()
+	defer b.Unlock()
+	if b.closed {
+		s.close()
+		return s
+	}
+	b.subscribers[s] = true
+	return s
+}
+
+func (b *EventBus) unsubscribe(s *Subscription) {
+	b.Lock()
+	defer b.Unlock()
+	if b.subscribers[s] {
+		delete(b.subscribers, s)
+		s.close()
+	}
+}
+
+// Sends the event to all the subscribers, subscribers with a full buffer lose it. Time and level are set when
+// not declared
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Level == "" {
+		event.Level = event.Type.Level()
+	}
+	b.RLock()
+	defer b.RUnlock()
+	for s := range b.subscribers {
+		if s.filter != nil && !s.filter(event) {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+			atomic.AddUint64(&b.dropped, 1)
+		}
+	}
+}
+
+// Retrieves the number of events dropped by all the subscribers
+func (b *EventBus) Dropped() uint64 {
+	return atomic.LoadUint64(&b.dropped)
+}
+
+// Closes all the subscriptions, further events are discarded
+func (b *EventBus) Close() {
+	b.Lock()
+	defer b.Unlock()
+	b.closed = true
+	for s := range b.subscribers {
+		delete(b.subscribers, s)
+		s.close()
+	}
+}
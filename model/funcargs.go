@@ -0,0 +1,97 @@
+package model
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Arguments of a registered function call, kept as JSON document. They are encoded as JSON value in json
+// files, as YAML value in yaml files and as JSON text in xml files
+type FuncArgs []byte
+
+// Creates the function arguments from any JSON encodable value
+func NewFuncArgs(value interface{}) (FuncArgs, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(stringKeys(value))
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid function arguments: %v", err))
+	}
+	return FuncArgs(data), nil
+}
+
+// Checks if no argument has been declared
+func (a FuncArgs) IsEmpty() bool {
+	var text = strings.TrimSpace(string(a))
+	return text == "" || text == "null"
+}
+
+// Decodes the arguments in the given value, as JSON document (e.g.: a struct pointer or a map)
+func (a FuncArgs) Decode(out interface{}) error {
+	if a.IsEmpty() {
+		return nil
+	}
+	return json.Unmarshal(a, out)
+}
+
+// Retrieves the arguments as generic value: maps, lists and basic values
+func (a FuncArgs) Value() (interface{}, error) {
+	var value interface{}
+	err := a.Decode(&value)
+	return value, err
+}
+
+func (a FuncArgs) String() string {
+	return string(a)
+}
+
+func (a FuncArgs) MarshalJSON() ([]byte, error) {
+	if a.IsEmpty() {
+		return []byte("null"), nil
+	}
+	return []byte(a), nil
+}
+
+func (a *FuncArgs) UnmarshalJSON(data []byte) error {
+	if !json.Valid(data) {
+		return errors.New("Invalid function arguments JSON document")
+	}
+	*a = append(FuncArgs{}, data...)
+	return nil
+}
+
+func (a FuncArgs) MarshalYAML() (interface{}, error) {
+	return a.Value()
+}
+
+func (a *FuncArgs) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var value interface{}
+	if err := unmarshal(&value); err != nil {
+		return err
+	}
+	args, err := NewFuncArgs(value)
+	if err == nil {
+		*a = args
+	}
+	return err
+}
+
+func (a FuncArgs) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	return e.EncodeElement(string(a), start)
+}
+
+func (a *FuncArgs) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return err
+	}
+	if strings.TrimSpace(text) == "" {
+		*a = nil
+		return nil
+	}
+	return a.UnmarshalJSON([]byte(strings.TrimSpace(text)))
+}
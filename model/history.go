@@ -0,0 +1,208 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"github.com/hellgate75/go-cron/utils"
+	"strings"
+	"time"
+)
+
+// Describes what started a task run
+type TriggerSource string
+
+const (
+	// Run started by the task time table
+	TriggerSchedule = TriggerSource("schedule")
+	// Run started on demand, out of the task time table
+	TriggerManual = TriggerSource("manual")
+	// New attempt of a failed run, accordingly to the command retry policy
+	TriggerRetry = TriggerSource("retry")
+	// Run started by the completion of the command upstream tasks
+	TriggerDependency = TriggerSource("dependency")
+)
+
+// Describes how a task run ended
+type RunOutcome string
+
+const (
+	// Run completed without errors
+	OutcomeSuccess = RunOutcome("success")
+	// Run completed with errors
+	OutcomeFailure = RunOutcome("failure")
+	// Run killed when the command timeout expired
+	OutcomeTimeout = RunOutcome("timeout")
+	// Run killed when the scheduler stopped
+	OutcomeCancelled = RunOutcome("cancelled")
+)
+
+// Describes the run outcome related to the given run error
+func OutcomeOf(err error) RunOutcome {
+	switch {
+	case err == nil:
+		return OutcomeSuccess
+	case errors.Is(err, context.DeadlineExceeded):
+		return OutcomeTimeout
+	case errors.Is(err, context.Canceled):
+		return OutcomeCancelled
+	}
+	return OutcomeFailure
+}
+
+// Default maximum number of history records kept for each task
+const DefaultHistoryMaxRecords = 100
+
+// Default maximum size in bytes of the standard output and standard error kept in each history record
+const DefaultHistoryOutputLimit = 4096
+
+// Describes a single run of a task
+type RunRecord struct {
+	RunID			string					`yaml:"runId,omitempty" json:"runId,omitempty" xml:"run-id,omitempty"`
+	UUID			string					`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+	Trigger			TriggerSource			`yaml:"trigger,omitempty" json:"trigger,omitempty" xml:"trigger,omitempty"`
+	Host			string					`yaml:"host,omitempty" json:"host,omitempty" xml:"host,omitempty"`
+	// Attempt number of the run, greater than 1 for retries of a failed run
+	Attempt			int						`yaml:"attempt,omitempty" json:"attempt,omitempty" xml:"attempt,omitempty"`
+	Start			time.Time				`yaml:"start,omitempty" json:"start,omitempty" xml:"start,omitempty"`
+	End				time.Time				`yaml:"end,omitempty" json:"end,omitempty" xml:"end,omitempty"`
+	Duration		time.Duration			`yaml:"duration,omitempty" json:"duration,omitempty" xml:"duration,omitempty"`
+	Success			bool					`yaml:"success,omitempty" json:"success,omitempty" xml:"success,omitempty"`
+	Outcome			RunOutcome				`yaml:"outcome,omitempty" json:"outcome,omitempty" xml:"outcome,omitempty"`
+	ExitCode		int						`yaml:"exitCode,omitempty" json:"exitCode,omitempty" xml:"exit-code,omitempty"`
+	Error			string					`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+	Stdout			string					`yaml:"stdout,omitempty" json:"stdout,omitempty" xml:"stdout,omitempty"`
+	Stderr			string					`yaml:"stderr,omitempty" json:"stderr,omitempty" xml:"stderr,omitempty"`
+	// Results of the steps of a composite command
+	Steps			[]StepResult			`yaml:"steps,omitempty" json:"steps,omitempty" xml:"steps>step,omitempty"`
+	// Results of the hook commands run after the attempt
+	Hooks			[]HookResult			`yaml:"hooks,omitempty" json:"hooks,omitempty" xml:"hooks>hook,omitempty"`
+}
+
+// Completes the record with the run outcome
+func (r *RunRecord) Complete(stdout string, stderr string, exitCode int, err error, outputLimit int) {
+	r.End = time.Now()
+	r.Duration = r.End.Sub(r.Start)
+	r.ExitCode = exitCode
+	r.Success = err == nil
+	r.Outcome = OutcomeOf(err)
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.Stdout = TruncateOutput(stdout, outputLimit)
+	r.Stderr = TruncateOutput(stderr, outputLimit)
+}
+
+// Truncates the output to the last limit bytes, when limit is greater than zero, marking it as truncated. The
+// cut is moved forward to the next rune start, so multi-byte characters are never split
+func TruncateOutput(out string, limit int) string {
+	if limit <= 0 || len(out) <= limit {
+		return out
+	}
+	return "[truncated] " + utils.TailString(out, limit)
+}
+
+// Defines the history retention policy
+type HistoryConfig struct {
+	// Maximum number of records kept for each task (default: 100)
+	MaxRecords		int						`yaml:"maxRecords,omitempty" json:"maxRecords,omitempty" xml:"max-records,omitempty"`
+	// Maximum age of the records, as Go duration (e.g.: 720h), empty for no age limit
+	MaxAge			string					`yaml:"maxAge,omitempty" json:"maxAge,omitempty" xml:"max-age,omitempty"`
+	// Maximum size in bytes of the stored standard output and standard error (default: 4096)
+	OutputLimit		int						`yaml:"outputLimit,omitempty" json:"outputLimit,omitempty" xml:"output-limit,omitempty"`
+}
+
+// Retrieves the maximum number of records for each task
+func (h HistoryConfig) Records() int {
+	if h.MaxRecords <= 0 {
+		return DefaultHistoryMaxRecords
+	}
+	return h.MaxRecords
+}
+
+// Retrieves the output size limit
+func (h HistoryConfig) Output() int {
+	if h.OutputLimit <= 0 {
+		return DefaultHistoryOutputLimit
+	}
+	return h.OutputLimit
+}
+
+// Applies the retention policy to the given records, ordered from the oldest to the newest
+func (h HistoryConfig) Retain(records []RunRecord) []RunRecord {
+	var minStart time.Time
+	if h.MaxAge != "" {
+		if d, err := time.ParseDuration(h.MaxAge); err == nil && d > 0 {
+			minStart = time.Now().Add(-d)
+		}
+	}
+	var counts = make(map[string]int)
+	var out = make([]RunRecord, 0)
+	for i := len(records) - 1; i >= 0; i-- {
+		var r = records[i]
+		if !minStart.IsZero() && r.Start.Before(minStart) {
+			continue
+		}
+		if counts[r.UUID] >= h.Records() {
+			continue
+		}
+		counts[r.UUID]++
+		out = append(out, r)
+	}
+	// Restore the chronological order
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return out
+}
+
+// Defines the history query filter, zero values match any record
+type HistoryFilter struct {
+	// Task unique identifier
+	UUID	string
+	// Oldest run start time
+	Since	time.Time
+	// Run status: success, failure (any unsuccessful run), timeout, cancelled or empty for any
+	Status	string
+	// Trigger source
+	Trigger	TriggerSource
+	// Maximum number of most recent records, zero or less for all
+	Limit	int
+}
+
+// Verifies if the record matches the filter
+func (f HistoryFilter) Matches(r RunRecord) bool {
+	if f.UUID != "" && f.UUID != r.UUID {
+		return false
+	}
+	if !f.Since.IsZero() && r.Start.Before(f.Since) {
+		return false
+	}
+	if f.Trigger != "" && f.Trigger != r.Trigger {
+		return false
+	}
+	switch strings.ToLower(f.Status) {
+	case "success":
+		return r.Success
+	case "failure", "failed":
+		return !r.Success
+	case string(OutcomeTimeout):
+		return r.Outcome == OutcomeTimeout
+	case string(OutcomeCancelled), "canceled":
+		return r.Outcome == OutcomeCancelled
+	}
+	return true
+}
+
+// Collects the records matching the filter, in chronological order
+func (f HistoryFilter) Apply(records []RunRecord) []RunRecord {
+	var out = make([]RunRecord, 0)
+	for _, r := range records {
+		if f.Matches(r) {
+			out = append(out, r)
+		}
+	}
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[len(out)-f.Limit:]
+	}
+	return out
+}
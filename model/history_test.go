@@ -0,0 +1,33 @@
+package model
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTruncateOutput(t *testing.T) {
+	var cases = []struct {
+		out      string
+		limit    int
+		expected string
+	}{
+		{"output", 0, "output"},
+		{"output", 6, "output"},
+		{"output", 3, "[truncated] put"},
+		// é is 2 bytes and € is 3 bytes long, the cut never splits them
+		{"perché", 2, "[truncated] é"},
+		{"perché", 1, "[truncated] "},
+		{"10€", 2, "[truncated] "},
+		{"10€", 3, "[truncated] €"},
+		{"10€", 4, "[truncated] 0€"},
+	}
+	for _, c := range cases {
+		var truncated = TruncateOutput(c.out, c.limit)
+		if truncated != c.expected {
+			t.Errorf("Output %q, limit %d: expected %q, found %q", c.out, c.limit, c.expected, truncated)
+		}
+		if !utf8.ValidString(truncated) {
+			t.Errorf("Output %q, limit %d: invalid UTF-8 %q", c.out, c.limit, truncated)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Describes when a hook command runs, after a task run attempt
+type HookEvent string
+
+const (
+	// Hook run after the successful run attempts
+	HookOnSuccess = HookEvent("onSuccess")
+	// Hook run after the failed and timed out run attempts
+	HookOnFailure = HookEvent("onFailure")
+	// Hook run after all the run attempts, after the success or failure hook
+	HookAlways = HookEvent("always")
+)
+
+// Defines the hook commands run after each task run attempt, for local remediation or cleanup. Hooks receive
+// the run outcome in the GO_CRON_* environment variables (shell and exec commands) or in the ExecutionContext
+// Run field (Go functions). Cancelled runs don't run hooks
+type RunHooks struct {
+	// Command run after the successful run attempts
+	OnSuccess		*CommandSpec			`yaml:"onSuccess,omitempty" json:"onSuccess,omitempty" xml:"on-success,omitempty"`
+	// Command run after the failed and timed out run attempts
+	OnFailure		*CommandSpec			`yaml:"onFailure,omitempty" json:"onFailure,omitempty" xml:"on-failure,omitempty"`
+	// Command run after all the run attempts
+	Always			*CommandSpec			`yaml:"always,omitempty" json:"always,omitempty" xml:"always,omitempty"`
+}
+
+// Maximum size in bytes of the run standard output and standard error passed in the hook environment variables,
+// the last bytes are kept
+const HookEnvironmentOutputLimit = 32 * 1024
+
+// Describes a hook command to run
+type HookCommand struct {
+	Hook			HookEvent
+	Command			CommandSpec
+}
+
+// Collects the hook commands run after a run attempt with the given outcome, in execution order
+func (h RunHooks) Commands(outcome RunOutcome) []HookCommand {
+	var out = make([]HookCommand, 0)
+	switch outcome {
+	case OutcomeSuccess:
+		if h.OnSuccess != nil {
+			out = append(out, HookCommand{Hook: HookOnSuccess, Command: *h.OnSuccess})
+		}
+	case OutcomeFailure, OutcomeTimeout:
+		if h.OnFailure != nil {
+			out = append(out, HookCommand{Hook: HookOnFailure, Command: *h.OnFailure})
+		}
+	default:
+		return out
+	}
+	if h.Always != nil {
+		out = append(out, HookCommand{Hook: HookAlways, Command: *h.Always})
+	}
+	return out
+}
+
+// Collects all the declared hook commands
+func (h RunHooks) All() []HookCommand {
+	var out = make([]HookCommand, 0)
+	if h.OnSuccess != nil {
+		out = append(out, HookCommand{Hook: HookOnSuccess, Command: *h.OnSuccess})
+	}
+	if h.OnFailure != nil {
+		out = append(out, HookCommand{Hook: HookOnFailure, Command: *h.OnFailure})
+	}
+	if h.Always != nil {
+		out = append(out, HookCommand{Hook: HookAlways, Command: *h.Always})
+	}
+	return out
+}
+
+// Verifies the hook commands consistency
+func (h RunHooks) Validate() error {
+	for _, hook := range h.All() {
+		if err := hook.Command.Validate(); err != nil {
+			return errors.New(fmt.Sprintf("Invalid %s hook: %v", hook.Hook, err))
+		}
+	}
+	return nil
+}
+
+// Describes the result of a hook command, in the run record of the parent run attempt
+type HookResult struct {
+	Hook			HookEvent				`yaml:"hook,omitempty" json:"hook,omitempty" xml:"hook,omitempty"`
+	Command			string					`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+	Start			time.Time				`yaml:"start,omitempty" json:"start,omitempty" xml:"start,omitempty"`
+	Duration		time.Duration			`yaml:"duration,omitempty" json:"duration,omitempty" xml:"duration,omitempty"`
+	Outcome			RunOutcome				`yaml:"outcome,omitempty" json:"outcome,omitempty" xml:"outcome,omitempty"`
+	ExitCode		int						`yaml:"exitCode,omitempty" json:"exitCode,omitempty" xml:"exit-code,omitempty"`
+	Error			string					`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+	Stdout			string					`yaml:"stdout,omitempty" json:"stdout,omitempty" xml:"stdout,omitempty"`
+	Stderr			string					`yaml:"stderr,omitempty" json:"stderr,omitempty" xml:"stderr,omitempty"`
+	// Results of the steps of a composite hook command
+	Steps			[]StepResult			`yaml:"steps,omitempty" json:"steps,omitempty" xml:"steps>step,omitempty"`
+}
+
+// Builds the environment variables describing the run attempt to the hook commands. NUL bytes are removed from
+// the values, because they can't be passed in a process environment
+func (r RunRecord) HookEnvironment(hook HookEvent, name string) []string {
+	return []string{
+		"GO_CRON_HOOK=" + string(hook),
+		"GO_CRON_TASK_UUID=" + r.UUID,
+		"GO_CRON_TASK_NAME=" + environmentValue(name),
+		"GO_CRON_RUN_ID=" + r.RunID,
+		"GO_CRON_RUN_TRIGGER=" + string(r.Trigger),
+		"GO_CRON_RUN_ATTEMPT=" + strconv.Itoa(r.Attempt),
+		"GO_CRON_RUN_OUTCOME=" + string(r.Outcome),
+		"GO_CRON_RUN_EXIT_CODE=" + strconv.Itoa(r.ExitCode),
+		"GO_CRON_RUN_DURATION=" + strconv.FormatFloat(r.Duration.Seconds(), 'f', 3, 64),
+		"GO_CRON_RUN_ERROR=" + environmentValue(r.Error),
+		"GO_CRON_RUN_STDOUT=" + TruncateOutput(environmentValue(r.Stdout), HookEnvironmentOutputLimit),
+		"GO_CRON_RUN_STDERR=" + TruncateOutput(environmentValue(r.Stderr), HookEnvironmentOutputLimit),
+	}
+}
+
+func environmentValue(value string) string {
+	return strings.ReplaceAll(value, "\x00", "")
+}
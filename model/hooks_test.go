@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRunHooksCommands(t *testing.T) {
+	var success, failure, always = NewShellCommand("echo ok"), NewShellCommand("echo ko"), NewShellCommand("echo done")
+	var all = RunHooks{OnSuccess: &success, OnFailure: &failure, Always: &always}
+	var cases = []struct {
+		name     string
+		hooks    RunHooks
+		outcome  RunOutcome
+		expected []HookEvent
+	}{
+		{"success", all, OutcomeSuccess, []HookEvent{HookOnSuccess, HookAlways}},
+		{"failure", all, OutcomeFailure, []HookEvent{HookOnFailure, HookAlways}},
+		{"timeout", all, OutcomeTimeout, []HookEvent{HookOnFailure, HookAlways}},
+		{"cancelled", all, OutcomeCancelled, []HookEvent{}},
+		{"success only", RunHooks{OnSuccess: &success}, OutcomeFailure, []HookEvent{}},
+		{"always only", RunHooks{Always: &always}, OutcomeFailure, []HookEvent{HookAlways}},
+		{"no hooks", RunHooks{}, OutcomeSuccess, []HookEvent{}},
+	}
+	for _, c := range cases {
+		var hooks = c.hooks.Commands(c.outcome)
+		var events = make([]HookEvent, 0)
+		for _, hook := range hooks {
+			events = append(events, hook.Hook)
+		}
+		if strings.Join(toStrings(events), ",") != strings.Join(toStrings(c.expected), ",") {
+			t.Errorf("%s: expected hooks %v, found %v", c.name, c.expected, events)
+		}
+	}
+	if len(all.All()) != 3 {
+		t.Errorf("Expected 3 declared hooks, found %d", len(all.All()))
+	}
+	var invalid = NewShellCommand(" ")
+	if err := (RunHooks{OnFailure: &invalid}).Validate(); err == nil || !strings.Contains(err.Error(), "onFailure hook") {
+		t.Errorf("Expected invalid onFailure hook error, found: %v", err)
+	}
+}
+
+func toStrings(events []HookEvent) []string {
+	var out = make([]string, 0)
+	for _, e := range events {
+		out = append(out, string(e))
+	}
+	return out
+}
+
+func TestHookEnvironment(t *testing.T) {
+	var record = RunRecord{RunID: "run", UUID: "id", Trigger: TriggerManual, Attempt: 2, ExitCode: 1, Duration: 1500 * time.Millisecond}
+	record.Complete("out\x00put", strings.Repeat("e", HookEnvironmentOutputLimit+10), 1, errors.New("failed\x00"), 0)
+	var env = make(map[string]string)
+	for _, entry := range record.HookEnvironment(HookOnFailure, "na\x00me") {
+		if strings.ContainsRune(entry, 0) {
+			t.Errorf("Unexpected NUL byte in %q", entry)
+		}
+		var pair = strings.SplitN(entry, "=", 2)
+		env[pair[0]] = pair[1]
+	}
+	var expected = map[string]string{
+		"GO_CRON_HOOK":          "onFailure",
+		"GO_CRON_TASK_UUID":     "id",
+		"GO_CRON_TASK_NAME":     "name",
+		"GO_CRON_RUN_ID":        "run",
+		"GO_CRON_RUN_TRIGGER":   "manual",
+		"GO_CRON_RUN_ATTEMPT":   "2",
+		"GO_CRON_RUN_OUTCOME":   "failure",
+		"GO_CRON_RUN_EXIT_CODE": "1",
+		"GO_CRON_RUN_ERROR":     "failed",
+		"GO_CRON_RUN_STDOUT":    "output",
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("Expected %s=%q, found %q", name, value, env[name])
+		}
+	}
+	if stderr := env["GO_CRON_RUN_STDERR"]; !strings.HasPrefix(stderr, "[truncated] ") ||
+		len(stderr) != len("[truncated] ")+HookEnvironmentOutputLimit {
+		t.Errorf("Expected standard error truncated to %d bytes, found %d bytes", HookEnvironmentOutputLimit, len(stderr))
+	}
+}
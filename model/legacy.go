@@ -0,0 +1,111 @@
+package model
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"time"
+)
+
+func init() {
+	// Composite commands were stored in the native files as untyped values, with these names
+	gob.Register([]interface{}{})
+	gob.RegisterName("github.com/hellgate75/go-cron/model.CompositeCommand", LegacyCompositeCommand{})
+}
+
+// Composite command as stored in the native files before the typed command specs, with untyped steps
+type LegacyCompositeCommand struct {
+	Steps				[]CommandValue
+	Mode				CompositeMode
+	ContinueOnError		bool
+}
+
+// Converts the legacy composite command in the command spec
+func (c LegacyCompositeCommand) Migrate() (CommandSpec, error) {
+	var composite = CompositeCommand{Mode: c.Mode, ContinueOnError: c.ContinueOnError}
+	for idx, value := range c.Steps {
+		step, err := CommandOf(value)
+		if err != nil {
+			return CommandSpec{}, errors.New(fmt.Sprintf("Invalid composite command step %d: %v", idx+1, err))
+		}
+		composite.Steps = append(composite.Steps, step)
+	}
+	return NewCompositeCommand(composite), nil
+}
+
+// Command configuration as stored in the native files before the typed command specs, with the command as
+// untyped value. It's used to migrate the native files to the current format
+type LegacyCommandConfig struct {
+	Name				string
+	Enabled				*bool
+	OnDemand			bool
+	Period				string
+	Repeat				int
+	Since				time.Time
+	Schedule			string
+	TimeZone			string
+	Timeout				string
+	Retry				RetryConfig
+	Concurrency			ConcurrencyPolicy
+	DependsOn			[]Dependency
+	Command				CommandValue
+}
+
+// Converts the legacy configuration in the current one
+func (c LegacyCommandConfig) Migrate() (CommandConfig, error) {
+	var command, err = CommandOf(c.Command)
+	return CommandConfig{
+		Name:        c.Name,
+		Enabled:     c.Enabled,
+		OnDemand:    c.OnDemand,
+		Period:      c.Period,
+		Repeat:      c.Repeat,
+		Since:       c.Since,
+		Schedule:    c.Schedule,
+		TimeZone:    c.TimeZone,
+		Timeout:     c.Timeout,
+		Retry:       c.Retry,
+		Concurrency: c.Concurrency,
+		DependsOn:   c.DependsOn,
+		Command:     command,
+	}, err
+}
+
+// Task execution state as stored in the native files before the typed command specs
+type LegacyExecution struct {
+	UUID				string
+	Command				LegacyCommandConfig
+	Next				time.Time
+	Last				time.Time
+	Times				int
+	Scheduled			bool
+	Map					map[string]interface{}
+	TimeZone			string
+	Running				int
+	Queued				int
+	Skipped				int
+	Triggered			int
+	LastTriggered		time.Time
+	Dependencies		[]DependencyState
+}
+
+// Converts the legacy execution state in the current one
+func (e LegacyExecution) Migrate() (Execution, error) {
+	var command, err = e.Command.Migrate()
+	return Execution{
+		UUID:          e.UUID,
+		Command:       command,
+		Next:          e.Next,
+		Last:          e.Last,
+		Times:         e.Times,
+		Scheduled:     e.Scheduled,
+		Map:           e.Map,
+		TimeZone:      e.TimeZone,
+		Running:       e.Running,
+		Queued:        e.Queued,
+		Skipped:       e.Skipped,
+		Triggered:     e.Triggered,
+		LastTriggered: e.LastTriggered,
+		Dependencies:  e.Dependencies,
+	}, err
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"net/mail"
+	"text/template"
+	"time"
+)
+
+// Describes when the SMTP connection is upgraded to TLS with the STARTTLS command
+type StartTLSMode string
+
+const (
+	// Connection upgraded when the server supports STARTTLS
+	StartTLSAuto = StartTLSMode("auto")
+	// Connection upgraded, mails are not sent when the server doesn't support STARTTLS
+	StartTLSAlways = StartTLSMode("always")
+	// Connection never upgraded
+	StartTLSNever = StartTLSMode("never")
+)
+
+// Default subject template of the notification mails
+const DefaultMailSubject = "go-cron: {{.Name}} {{.Event}}"
+
+// Default timeout of the SMTP session
+const DefaultMailTimeout = 30 * time.Second
+
+// Defines the mail notifications of the task runs, as cron MAILTO. Task mail configuration overrides the declared
+// fields of the scheduler one, and its flags are added to the scheduler ones
+type MailConfig struct {
+	// Disables the mail notifications, mails are sent when server and recipients are declared
+	Disabled			bool					`yaml:"disabled,omitempty" json:"disabled,omitempty" xml:"disabled,omitempty"`
+	// SMTP server address, as host:port (e.g.: smtp.example.com:587)
+	Server				string					`yaml:"server,omitempty" json:"server,omitempty" xml:"server,omitempty"`
+	// PLAIN authentication credentials, no authentication when username is empty
+	Username			string					`yaml:"username,omitempty" json:"username,omitempty" xml:"username,omitempty"`
+	Password			string					`yaml:"password,omitempty" json:"password,omitempty" xml:"password,omitempty"`
+	// STARTTLS mode: auto, always or never (default: auto)
+	StartTLS			StartTLSMode			`yaml:"startTls,omitempty" json:"startTls,omitempty" xml:"start-tls,omitempty"`
+	// Skips the server certificate verification, for relays with self signed certificates
+	InsecureSkipVerify	bool					`yaml:"insecureSkipVerify,omitempty" json:"insecureSkipVerify,omitempty" xml:"insecure-skip-verify,omitempty"`
+	// Sender address
+	From				string					`yaml:"from,omitempty" json:"from,omitempty" xml:"from,omitempty"`
+	// Recipient addresses
+	To					[]string				`yaml:"to,omitempty" json:"to,omitempty" xml:"to,omitempty"`
+	// Go template of the subject, executed with the Notification (default: go-cron: {{.Name}} {{.Event}})
+	Subject				string					`yaml:"subject,omitempty" json:"subject,omitempty" xml:"subject,omitempty"`
+	// Excludes the run standard output and standard error from the mail body
+	OmitOutput			bool					`yaml:"omitOutput,omitempty" json:"omitOutput,omitempty" xml:"omit-output,omitempty"`
+	// Sends mails only for failed and timed out runs
+	OnlyOnFailure		bool					`yaml:"onlyOnFailure,omitempty" json:"onlyOnFailure,omitempty" xml:"only-on-failure,omitempty"`
+	// SMTP session timeout, as Go duration (default: 30s)
+	Timeout				string					`yaml:"timeout,omitempty" json:"timeout,omitempty" xml:"timeout,omitempty"`
+}
+
+// Creates the task mail configuration, the declared fields of the task configuration override the scheduler ones
+func (m MailConfig) Merge(task *MailConfig) MailConfig {
+	if task == nil {
+		return m
+	}
+	var out = m
+	out.Disabled = out.Disabled || task.Disabled
+	out.OmitOutput = out.OmitOutput || task.OmitOutput
+	out.OnlyOnFailure = out.OnlyOnFailure || task.OnlyOnFailure
+	if task.Server != "" {
+		// Credentials and TLS options belong to the server
+		out.Server = task.Server
+		out.Username = task.Username
+		out.Password = task.Password
+		out.StartTLS = task.StartTLS
+		out.InsecureSkipVerify = task.InsecureSkipVerify
+	}
+	if task.From != "" {
+		out.From = task.From
+	}
+	if len(task.To) > 0 {
+		out.To = task.To
+	}
+	if task.Subject != "" {
+		out.Subject = task.Subject
+	}
+	if task.Timeout != "" {
+		out.Timeout = task.Timeout
+	}
+	return out
+}
+
+// Checks if the mails are sent: not disabled, with server and recipients
+func (m MailConfig) IsEnabled() bool {
+	return !m.Disabled && m.Server != "" && len(m.To) > 0
+}
+
+// Retrieves the STARTTLS mode, or the default one when not declared
+func (m MailConfig) TLSMode() StartTLSMode {
+	if m.StartTLS == "" {
+		return StartTLSAuto
+	}
+	return m.StartTLS
+}
+
+// Retrieves the SMTP session timeout
+func (m MailConfig) TimeoutDuration() time.Duration {
+	return parsePositiveDuration(m.Timeout, DefaultMailTimeout)
+}
+
+// Retrieves the sender address, default is go-cron at the server domain
+func (m MailConfig) Sender() string {
+	if m.From != "" {
+		return m.From
+	}
+	host, _, _ := net.SplitHostPort(m.Server)
+	return "go-cron@" + host
+}
+
+// Verifies the mail configuration consistency, the server and the recipients can be declared in the scheduler
+// or in the task configuration
+func (m MailConfig) Validate() error {
+	if m.Server != "" {
+		if _, port, err := net.SplitHostPort(m.Server); err != nil || port == "" {
+			return errors.New(fmt.Sprintf("Invalid mail server '%s', it must be a host:port address", m.Server))
+		}
+	}
+	switch m.TLSMode() {
+	case StartTLSAuto, StartTLSAlways, StartTLSNever:
+	default:
+		return errors.New(fmt.Sprintf("Invalid mail STARTTLS mode '%s' (available: auto, always, never)", m.StartTLS))
+	}
+	for _, address := range append([]string{m.From}, m.To...) {
+		if address == "" {
+			continue
+		}
+		if _, err := mail.ParseAddress(address); err != nil {
+			return errors.New(fmt.Sprintf("Invalid mail address '%s': %v", address, err))
+		}
+	}
+	if m.Timeout != "" {
+		if d, err := time.ParseDuration(m.Timeout); err != nil || d <= 0 {
+			return errors.New(fmt.Sprintf("Invalid mail timeout '%s', it must be a positive duration (e.g.: 30s, 5m)", m.Timeout))
+		}
+	}
+	if _, err := m.subjectTemplate(); err != nil {
+		return errors.New(fmt.Sprintf("Invalid mail subject template: %v", err))
+	}
+	return nil
+}
+
+func (m MailConfig) subjectTemplate() (*template.Template, error) {
+	var subject = m.Subject
+	if subject == "" {
+		subject = DefaultMailSubject
+	}
+	return template.New("subject").Funcs(webhookFuncs).Parse(subject)
+}
+
+// Creates the mail subject of the notification
+func (m MailConfig) RenderSubject(notification Notification) (string, error) {
+	t, err := m.subjectTemplate()
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err = t.Execute(&out, notification); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,195 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Describes the task run outcomes notified by the webhooks
+type NotifyEvent string
+
+const (
+	// Run failed or timed out, after the last attempt
+	NotifyFailure = NotifyEvent("failure")
+	// Run completed without errors
+	NotifySuccess = NotifyEvent("success")
+	// Run failed at the last attempt of the command retry policy
+	NotifyRetryExhausted = NotifyEvent("retryExhausted")
+	// Run timed out, after the last attempt
+	NotifyTimeout = NotifyEvent("timeout")
+	// Run completed without errors after a failed run
+	NotifyRecovery = NotifyEvent("recovery")
+)
+
+// Available notified events, from the most to the least specific one
+var NotifyEventList = []NotifyEvent{NotifyRecovery, NotifyRetryExhausted, NotifyTimeout, NotifyFailure, NotifySuccess}
+
+// Default timeout of the webhook requests
+const DefaultWebhookTimeout = 10 * time.Second
+
+// Default maximum number of webhook delivery records kept
+const DefaultDeliveryLogRecords = 200
+
+// Defines an HTTP request sent when a task run ends with one of the declared events
+type Webhook struct {
+	// Webhook name, reported in the delivery log (default: the URL)
+	Name			string					`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	// Request absolute URL, http or https
+	URL				string					`yaml:"url,omitempty" json:"url,omitempty" xml:"url,omitempty"`
+	// Request method (default: POST)
+	Method			string					`yaml:"method,omitempty" json:"method,omitempty" xml:"method,omitempty"`
+	Headers			[]HttpHeader			`yaml:"headers,omitempty" json:"headers,omitempty" xml:"header,omitempty"`
+	// Go template of the request body, executed with the Notification, default is the Notification JSON
+	Body			string					`yaml:"body,omitempty" json:"body,omitempty" xml:"body,omitempty"`
+	// Notified events: failure, success, retryExhausted, timeout, recovery (default: failure)
+	On				[]NotifyEvent			`yaml:"on,omitempty" json:"on,omitempty" xml:"on,omitempty"`
+	// Retry policy of the failed deliveries
+	Retry			RetryConfig				`yaml:"retry,omitempty" json:"retry,omitempty" xml:"retry,omitempty"`
+	// Request timeout, as Go duration (default: 10s)
+	Timeout			string					`yaml:"timeout,omitempty" json:"timeout,omitempty" xml:"timeout,omitempty"`
+}
+
+// Retrieves the webhook name, or the URL when not declared
+func (w Webhook) DisplayName() string {
+	if w.Name == "" {
+		return w.URL
+	}
+	return w.Name
+}
+
+// Retrieves the request method, or the default one when not declared
+func (w Webhook) RequestMethod() string {
+	if w.Method == "" {
+		return "POST"
+	}
+	return strings.ToUpper(w.Method)
+}
+
+// Retrieves the notified events, or the default one when not declared
+func (w Webhook) Events() []NotifyEvent {
+	if len(w.On) == 0 {
+		return []NotifyEvent{NotifyFailure}
+	}
+	return w.On
+}
+
+// Retrieves the most specific of the given run events notified by the webhook, empty when none is notified
+func (w Webhook) Match(events []NotifyEvent) NotifyEvent {
+	for _, candidate := range NotifyEventList {
+		for _, event := range events {
+			if event != candidate {
+				continue
+			}
+			for _, on := range w.Events() {
+				if on == event {
+					return event
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Retrieves the request timeout
+func (w Webhook) TimeoutDuration() time.Duration {
+	return parsePositiveDuration(w.Timeout, DefaultWebhookTimeout)
+}
+
+// Verifies the webhook request and events consistency
+func (w Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New(fmt.Sprintf("Invalid webhook url '%s', it must be an absolute http or https url", w.URL))
+	}
+	if strings.ContainsAny(w.Method, " \t\r\n") {
+		return errors.New(fmt.Sprintf("Invalid webhook method '%s'", w.Method))
+	}
+	for _, h := range w.Headers {
+		if strings.TrimSpace(h.Name) == "" {
+			return errors.New("Webhook headers must have a name")
+		}
+	}
+	for _, on := range w.On {
+		var found bool
+		for _, event := range NotifyEventList {
+			found = found || on == event
+		}
+		if !found {
+			return errors.New(fmt.Sprintf("Invalid webhook event '%s' (available: failure, success, retryExhausted, timeout, recovery)", on))
+		}
+	}
+	if w.Timeout != "" {
+		if d, err := time.ParseDuration(w.Timeout); err != nil || d <= 0 {
+			return errors.New(fmt.Sprintf("Invalid webhook timeout '%s', it must be a positive duration (e.g.: 30s, 5m)", w.Timeout))
+		}
+	}
+	if _, err := w.template(); err != nil {
+		return errors.New(fmt.Sprintf("Invalid webhook body template: %v", err))
+	}
+	return nil
+}
+
+// Functions available in the body templates: json encodes a value as JSON (e.g.: {{json .Run.Error}})
+var webhookFuncs = template.FuncMap{
+	"json": func(value interface{}) (string, error) {
+		data, err := json.Marshal(value)
+		return string(data), err
+	},
+}
+
+func (w Webhook) template() (*template.Template, error) {
+	return template.New("body").Funcs(webhookFuncs).Parse(w.Body)
+}
+
+// Creates the request body of the notification, from the body template or as JSON when no template is declared
+func (w Webhook) Render(notification Notification) (string, error) {
+	if w.Body == "" {
+		data, err := json.Marshal(notification)
+		return string(data), err
+	}
+	t, err := w.template()
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err = t.Execute(&out, notification); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// Describes a task run notification, it's the webhook body template data
+type Notification struct {
+	// Notified event
+	Event			NotifyEvent				`yaml:"event,omitempty" json:"event,omitempty" xml:"event,omitempty"`
+	// Task unique identifier and name
+	UUID			string					`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+	Name			string					`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	// Outcome of the previous run of the task, empty when unknown
+	Previous		RunOutcome				`yaml:"previous,omitempty" json:"previous,omitempty" xml:"previous,omitempty"`
+	// Last run attempt record
+	Run				RunRecord				`yaml:"run,omitempty" json:"run,omitempty" xml:"run,omitempty"`
+}
+
+// Describes a webhook delivery attempt
+type WebhookDelivery struct {
+	Time			time.Time				`yaml:"time,omitempty" json:"time,omitempty" xml:"time,omitempty"`
+	Webhook			string					`yaml:"webhook,omitempty" json:"webhook,omitempty" xml:"webhook,omitempty"`
+	URL				string					`yaml:"url,omitempty" json:"url,omitempty" xml:"url,omitempty"`
+	Event			NotifyEvent				`yaml:"event,omitempty" json:"event,omitempty" xml:"event,omitempty"`
+	// Notified task unique identifier and run id
+	UUID			string					`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
+	RunID			string					`yaml:"runId,omitempty" json:"runId,omitempty" xml:"run-id,omitempty"`
+	// Delivery attempt number, greater than 1 for retries of a failed delivery
+	Attempt			int						`yaml:"attempt,omitempty" json:"attempt,omitempty" xml:"attempt,omitempty"`
+	StatusCode		int						`yaml:"statusCode,omitempty" json:"statusCode,omitempty" xml:"status-code,omitempty"`
+	Duration		time.Duration			`yaml:"duration,omitempty" json:"duration,omitempty" xml:"duration,omitempty"`
+	Delivered		bool					`yaml:"delivered,omitempty" json:"delivered,omitempty" xml:"delivered,omitempty"`
+	Error			string					`yaml:"error,omitempty" json:"error,omitempty" xml:"error,omitempty"`
+}
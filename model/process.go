@@ -0,0 +1,127 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/utils"
+	"strings"
+)
+
+// Describes how the process environment is built
+type EnvMode string
+
+const (
+	// The scheduler process environment is inherited and the declared variables override it (default)
+	EnvInherit = EnvMode("inherit")
+	// Only the declared variables are available to the process
+	EnvClear = EnvMode("clear")
+)
+
+// Defines how the shell and exec commands processes are started and how their output is collected
+type ProcessOptions struct {
+	// Shell command line (e.g.: /bin/sh -c) receiving the shell commands as last argument, when empty the
+	// shell commands are split in arguments honouring single quotes, double quotes and backslash escapes
+	Shell				string					`yaml:"shell,omitempty" json:"shell,omitempty" xml:"shell,omitempty"`
+	// Process working directory, empty for the scheduler one
+	Dir					string					`yaml:"dir,omitempty" json:"dir,omitempty" xml:"dir,omitempty"`
+	// Process environment mode: inherit or clear (default: inherit)
+	EnvMode				EnvMode					`yaml:"envMode,omitempty" json:"envMode,omitempty" xml:"env-mode,omitempty"`
+	// Process environment variables, as NAME=value entries
+	Env					[]string				`yaml:"env,omitempty" json:"env,omitempty" xml:"env,omitempty"`
+	// Content sent to the process standard input
+	Stdin				string					`yaml:"stdin,omitempty" json:"stdin,omitempty" xml:"stdin,omitempty"`
+	// Maximum size in bytes of the collected standard output, the last bytes are kept (0 for no limit)
+	StdoutLimit			int						`yaml:"stdoutLimit,omitempty" json:"stdoutLimit,omitempty" xml:"stdout-limit,omitempty"`
+	// Maximum size in bytes of the collected standard error, the last bytes are kept (0 for no limit)
+	StderrLimit			int						`yaml:"stderrLimit,omitempty" json:"stderrLimit,omitempty" xml:"stderr-limit,omitempty"`
+	// Process exit codes of successful runs, empty for 0 only
+	SuccessExitCodes	[]int					`yaml:"successExitCodes,omitempty" json:"successExitCodes,omitempty" xml:"success-exit-code,omitempty"`
+	// User name or numeric id the process runs as, empty for the scheduler user (requires a root scheduler)
+	User				string					`yaml:"user,omitempty" json:"user,omitempty" xml:"user,omitempty"`
+	// Group name or numeric id the process runs as, empty for the user primary group
+	Group				string					`yaml:"group,omitempty" json:"group,omitempty" xml:"group,omitempty"`
+	// File mode creation mask, as octal number (e.g.: 027), empty for the scheduler one
+	Umask				string					`yaml:"umask,omitempty" json:"umask,omitempty" xml:"umask,omitempty"`
+	// Process niceness, from -20 (highest priority) to 19 (lowest priority), 0 for the scheduler one
+	Nice				int						`yaml:"nice,omitempty" json:"nice,omitempty" xml:"nice,omitempty"`
+	// Process resource limits
+	Limits				ResourceLimits			`yaml:"limits,omitempty" json:"limits,omitempty" xml:"limits,omitempty"`
+}
+
+// Process resource limits, applied as soft and hard limits, 0 for the scheduler ones
+type ResourceLimits struct {
+	// Maximum CPU time in seconds
+	CPUSeconds			uint64					`yaml:"cpuSeconds,omitempty" json:"cpuSeconds,omitempty" xml:"cpu-seconds,omitempty"`
+	// Maximum virtual memory size in bytes
+	AddressSpace		uint64					`yaml:"addressSpace,omitempty" json:"addressSpace,omitempty" xml:"address-space,omitempty"`
+	// Maximum number of open files
+	OpenFiles			uint64					`yaml:"openFiles,omitempty" json:"openFiles,omitempty" xml:"open-files,omitempty"`
+	// Maximum number of processes of the process user
+	Processes			uint64					`yaml:"processes,omitempty" json:"processes,omitempty" xml:"processes,omitempty"`
+}
+
+// Retrieves the environment mode, or the default one when not declared
+func (o ProcessOptions) Mode() EnvMode {
+	if o.EnvMode == "" {
+		return EnvInherit
+	}
+	return o.EnvMode
+}
+
+// Builds the process environment from the given scheduler process environment, nil when the scheduler one
+// is used as it is
+func (o ProcessOptions) Environment(inherited []string) []string {
+	if o.Mode() == EnvInherit && len(o.Env) == 0 {
+		return nil
+	}
+	var env = make([]string, 0)
+	var index = make(map[string]int)
+	var entries = o.Env
+	if o.Mode() == EnvInherit {
+		entries = append(append([]string{}, inherited...), o.Env...)
+	}
+	for _, entry := range entries {
+		var name = strings.SplitN(entry, "=", 2)[0]
+		if idx, ok := index[name]; ok {
+			env[idx] = entry
+			continue
+		}
+		index[name] = len(env)
+		env = append(env, entry)
+	}
+	return env
+}
+
+// Builds the process arguments of a shell command: shell arguments followed by the command line, or the
+// command line split in arguments when no shell is declared
+func (o ProcessOptions) ShellArgs(line string) ([]string, error) {
+	if strings.TrimSpace(o.Shell) == "" {
+		return utils.SplitCommandLine(line)
+	}
+	shell, err := utils.SplitCommandLine(o.Shell)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid shell '%s': %v", o.Shell, err))
+	}
+	return append(shell, line), nil
+}
+
+// Verifies process options consistency
+func (o ProcessOptions) Validate() error {
+	if o.Mode() != EnvInherit && o.Mode() != EnvClear {
+		return errors.New(fmt.Sprintf("Invalid environment mode '%s' (available: inherit, clear)", o.EnvMode))
+	}
+	for _, entry := range o.Env {
+		if idx := strings.Index(entry, "="); idx <= 0 {
+			return errors.New(fmt.Sprintf("Invalid environment variable '%s', it must be in the form NAME=value", entry))
+		}
+	}
+	if o.StdoutLimit < 0 || o.StderrLimit < 0 {
+		return errors.New("Invalid output limit, it must not be negative")
+	}
+	if strings.TrimSpace(o.Shell) != "" {
+		if shell, err := utils.SplitCommandLine(o.Shell); err != nil || len(shell) == 0 {
+			return errors.New(fmt.Sprintf("Invalid shell '%s'", o.Shell))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package model
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessEnvironment(t *testing.T) {
+	var inherited = []string{"PATH=/bin", "HOME=/root", "LANG=C"}
+	var cases = []struct {
+		name     string
+		options  ProcessOptions
+		expected []string
+	}{
+		{"inherit nothing declared", ProcessOptions{}, nil},
+		{"inherit declared", ProcessOptions{Env: []string{"NAME=value"}},
+			[]string{"PATH=/bin", "HOME=/root", "LANG=C", "NAME=value"}},
+		{"inherit override", ProcessOptions{EnvMode: EnvInherit, Env: []string{"HOME=/tmp", "NAME=value"}},
+			[]string{"PATH=/bin", "HOME=/tmp", "LANG=C", "NAME=value"}},
+		{"declared twice", ProcessOptions{Env: []string{"NAME=first", "NAME=second"}},
+			[]string{"PATH=/bin", "HOME=/root", "LANG=C", "NAME=second"}},
+		{"clear nothing declared", ProcessOptions{EnvMode: EnvClear}, []string{}},
+		{"clear declared", ProcessOptions{EnvMode: EnvClear, Env: []string{"PATH=/usr/bin", "EMPTY="}},
+			[]string{"PATH=/usr/bin", "EMPTY="}},
+	}
+	for _, c := range cases {
+		var env = c.options.Environment(inherited)
+		if !reflect.DeepEqual(env, c.expected) {
+			t.Errorf("%s: expected %q, found %q", c.name, c.expected, env)
+		}
+	}
+	if !reflect.DeepEqual(inherited, []string{"PATH=/bin", "HOME=/root", "LANG=C"}) {
+		t.Errorf("The inherited environment has been changed: %q", inherited)
+	}
+}
+
+func TestProcessShellArgs(t *testing.T) {
+	var cases = []struct {
+		shell    string
+		line     string
+		expected []string
+		error    bool
+	}{
+		{"", "echo 'a b' c", []string{"echo", "a b", "c"}, false},
+		{"", "echo 'a b", nil, true},
+		{"/bin/sh -c", "echo $HOME | wc -c", []string{"/bin/sh", "-c", "echo $HOME | wc -c"}, false},
+		{"'/opt/my shell/bin/bash' -lc", "ls", []string{"/opt/my shell/bin/bash", "-lc", "ls"}, false},
+		{"/bin/sh \"-c", "ls", nil, true},
+	}
+	for _, c := range cases {
+		args, err := ProcessOptions{Shell: c.shell}.ShellArgs(c.line)
+		if c.error != (err != nil) {
+			t.Errorf("Shell %q, line %q: expected error %v, found: %v", c.shell, c.line, c.error, err)
+		} else if !c.error && !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("Shell %q, line %q: expected %q, found %q", c.shell, c.line, c.expected, args)
+		}
+	}
+}
+
+func TestProcessOptionsValidate(t *testing.T) {
+	var cases = []struct {
+		name    string
+		options ProcessOptions
+		error   string
+	}{
+		{"defaults", ProcessOptions{}, ""},
+		{"success exit codes", ProcessOptions{SuccessExitCodes: []int{0, 1, 255}}, ""},
+		{"unknown mode", ProcessOptions{EnvMode: "merge"}, "Invalid environment mode"},
+		{"variable without value", ProcessOptions{Env: []string{"NAME"}}, "Invalid environment variable"},
+		{"variable without name", ProcessOptions{Env: []string{"=value"}}, "Invalid environment variable"},
+		{"negative limit", ProcessOptions{StderrLimit: -1}, "Invalid output limit"},
+		{"invalid shell", ProcessOptions{Shell: "'/bin/sh"}, "Invalid shell"},
+	}
+	for _, c := range cases {
+		var err = c.options.Validate()
+		if c.error == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		} else if c.error != "" && (err == nil || !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected error containing %q, found: %v", c.name, c.error, err)
+		}
+	}
+}
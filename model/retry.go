@@ -0,0 +1,119 @@
+package model
+
+import (
+	"math/rand"
+	"regexp"
+	"time"
+)
+
+// Describes how the delay between two attempts of a failed run grows
+type BackoffStrategy string
+
+const (
+	// Same delay between all attempts
+	BackoffFixed = BackoffStrategy("fixed")
+	// Delay doubled at each attempt
+	BackoffExponential = BackoffStrategy("exponential")
+)
+
+// Default delay before the first retry of a failed run
+const DefaultRetryDelay = 10 * time.Second
+
+// Default maximum delay between two attempts of a failed run
+const DefaultRetryMaxDelay = time.Hour
+
+// Defines the retry policy of failed runs
+type RetryConfig struct {
+	// Maximum number of attempts of a run, including the first one (0 or 1 for no retry)
+	MaxAttempts		int						`yaml:"maxAttempts,omitempty" json:"maxAttempts,omitempty" xml:"max-attempts,omitempty"`
+	// Delay growth between attempts: fixed or exponential (default: fixed)
+	Backoff			BackoffStrategy			`yaml:"backoff,omitempty" json:"backoff,omitempty" xml:"backoff,omitempty"`
+	// Delay before the first retry, as Go duration (default: 10s)
+	Delay			string					`yaml:"delay,omitempty" json:"delay,omitempty" xml:"delay,omitempty"`
+	// Maximum delay between two attempts, as Go duration (default: 1h)
+	MaxDelay		string					`yaml:"maxDelay,omitempty" json:"maxDelay,omitempty" xml:"max-delay,omitempty"`
+	// Fraction of the delay randomly added or removed to spread the attempts, between 0 and 1
+	Jitter			float64					`yaml:"jitter,omitempty" json:"jitter,omitempty" xml:"jitter,omitempty"`
+	// Process exit codes that are retried, empty for any failure
+	ExitCodes		[]int					`yaml:"exitCodes,omitempty" json:"exitCodes,omitempty" xml:"exit-code,omitempty"`
+	// Regular expression matched on the run error and standard error, a failure is retried only when it matches
+	ErrorMatch		string					`yaml:"errorMatch,omitempty" json:"errorMatch,omitempty" xml:"error-match,omitempty"`
+}
+
+// Retrieves the maximum number of attempts of a run
+func (r RetryConfig) Attempts() int {
+	if r.MaxAttempts <= 1 {
+		return 1
+	}
+	return r.MaxAttempts
+}
+
+// Retrieves the delay strategy, or the default one when not declared
+func (r RetryConfig) Strategy() BackoffStrategy {
+	if r.Backoff == "" {
+		return BackoffFixed
+	}
+	return r.Backoff
+}
+
+// Calculates the delay before the next attempt, after the given number of failed attempts
+func (r RetryConfig) DelayAfter(failed int) time.Duration {
+	var delay = parsePositiveDuration(r.Delay, DefaultRetryDelay)
+	var maxDelay = parsePositiveDuration(r.MaxDelay, DefaultRetryMaxDelay)
+	if r.Strategy() == BackoffExponential {
+		for i := 1; i < failed && delay < maxDelay; i++ {
+			delay *= 2
+		}
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if r.Jitter > 0 {
+		var jitter = r.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		delay += time.Duration((rand.Float64()*2 - 1) * jitter * float64(delay))
+	}
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
+// Verifies if the failed run, described by the given record, must be retried. Successful and cancelled
+// runs are never retried
+func (r RetryConfig) ShouldRetry(record RunRecord) bool {
+	if record.Outcome != OutcomeFailure && record.Outcome != OutcomeTimeout {
+		return false
+	}
+	if len(r.ExitCodes) > 0 {
+		var found bool
+		for _, code := range r.ExitCodes {
+			if code == record.ExitCode {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if r.ErrorMatch != "" {
+		re, err := regexp.Compile(r.ErrorMatch)
+		if err != nil || !(re.MatchString(record.Error) || re.MatchString(record.Stderr)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Parses a positive Go duration, or returns the default value when empty or not valid
+func parsePositiveDuration(value string, defaultValue time.Duration) time.Duration {
+	if value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d > 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
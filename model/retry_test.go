@@ -0,0 +1,95 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryDelayAfter(t *testing.T) {
+	var cases = []struct {
+		name     string
+		retry    RetryConfig
+		failed   int
+		expected time.Duration
+	}{
+		{"default delay", RetryConfig{}, 1, DefaultRetryDelay},
+		{"fixed", RetryConfig{Delay: "5s"}, 4, 5 * time.Second},
+		{"invalid delay", RetryConfig{Delay: "soon"}, 1, DefaultRetryDelay},
+		{"negative delay", RetryConfig{Delay: "-5s"}, 1, DefaultRetryDelay},
+		{"fixed over maximum", RetryConfig{Delay: "2h"}, 1, DefaultRetryMaxDelay},
+		{"exponential first", RetryConfig{Backoff: BackoffExponential, Delay: "1s"}, 1, time.Second},
+		{"exponential second", RetryConfig{Backoff: BackoffExponential, Delay: "1s"}, 2, 2 * time.Second},
+		{"exponential fifth", RetryConfig{Backoff: BackoffExponential, Delay: "1s"}, 5, 16 * time.Second},
+		{"exponential capped", RetryConfig{Backoff: BackoffExponential, Delay: "1s", MaxDelay: "10s"}, 5, 10 * time.Second},
+		{"exponential many attempts", RetryConfig{Backoff: BackoffExponential, Delay: "1s"}, 1000, DefaultRetryMaxDelay},
+	}
+	for _, c := range cases {
+		if delay := c.retry.DelayAfter(c.failed); delay != c.expected {
+			t.Errorf("%s: expected delay %v after %d failures, found %v", c.name, c.expected, c.failed, delay)
+		}
+	}
+}
+
+func TestRetryJitter(t *testing.T) {
+	var cases = []struct {
+		jitter float64
+		min    time.Duration
+		max    time.Duration
+	}{
+		{0.5, 5 * time.Second, 15 * time.Second},
+		{1, 0, 20 * time.Second},
+		// Jitter greater than 1 is applied as 1
+		{3, 0, 20 * time.Second},
+	}
+	for _, c := range cases {
+		var retry = RetryConfig{Delay: "10s", Jitter: c.jitter}
+		var spread bool
+		for i := 0; i < 100; i++ {
+			var delay = retry.DelayAfter(1)
+			if delay < c.min || delay > c.max {
+				t.Errorf("Jitter %v: delay %v out of [%v, %v]", c.jitter, delay, c.min, c.max)
+			}
+			spread = spread || delay != 10*time.Second
+		}
+		if !spread {
+			t.Errorf("Jitter %v: expected spread delays", c.jitter)
+		}
+	}
+}
+
+func TestRetryShouldRetry(t *testing.T) {
+	var cases = []struct {
+		name     string
+		retry    RetryConfig
+		record   RunRecord
+		expected bool
+	}{
+		{"failure", RetryConfig{}, RunRecord{Outcome: OutcomeFailure}, true},
+		{"timeout", RetryConfig{}, RunRecord{Outcome: OutcomeTimeout}, true},
+		{"success", RetryConfig{}, RunRecord{Outcome: OutcomeSuccess}, false},
+		{"cancelled", RetryConfig{}, RunRecord{Outcome: OutcomeCancelled}, false},
+		{"exit code matched", RetryConfig{ExitCodes: []int{75, 111}}, RunRecord{Outcome: OutcomeFailure, ExitCode: 111}, true},
+		{"exit code not matched", RetryConfig{ExitCodes: []int{75}}, RunRecord{Outcome: OutcomeFailure, ExitCode: 1}, false},
+		{"error matched", RetryConfig{ErrorMatch: "connection (refused|reset)"},
+			RunRecord{Outcome: OutcomeFailure, Error: "dial tcp: connection refused"}, true},
+		{"standard error matched", RetryConfig{ErrorMatch: "(?i)temporarily unavailable"},
+			RunRecord{Outcome: OutcomeFailure, Error: "exit status 1", Stderr: "Resource Temporarily Unavailable"}, true},
+		{"error not matched", RetryConfig{ErrorMatch: "^timeout"},
+			RunRecord{Outcome: OutcomeFailure, Error: "exit status 1", Stderr: "disk full"}, false},
+		{"invalid expression", RetryConfig{ErrorMatch: "(["}, RunRecord{Outcome: OutcomeFailure, Error: "(["}, false},
+		{"exit code and error", RetryConfig{ExitCodes: []int{1}, ErrorMatch: "busy"},
+			RunRecord{Outcome: OutcomeFailure, ExitCode: 1, Stderr: "device busy"}, true},
+		{"exit code without error", RetryConfig{ExitCodes: []int{1}, ErrorMatch: "busy"},
+			RunRecord{Outcome: OutcomeFailure, ExitCode: 1, Stderr: "not found"}, false},
+	}
+	for _, c := range cases {
+		if retry := c.retry.ShouldRetry(c.record); retry != c.expected {
+			t.Errorf("%s: expected retry %v, found %v", c.name, c.expected, retry)
+		}
+	}
+	for attempts, expected := range map[int]int{-1: 1, 0: 1, 1: 1, 3: 3} {
+		if found := (RetryConfig{MaxAttempts: attempts}).Attempts(); found != expected {
+			t.Errorf("Max attempts %d: expected %d attempts, found %d", attempts, expected, found)
+		}
+	}
+}
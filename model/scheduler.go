@@ -1,11 +1,18 @@
 package model
 
 import (
+	"context"
 	"fmt"
+	"github.com/hellgate75/go-cron/schedule"
+	"github.com/hellgate75/go-cron/utils"
+	"io"
 	"time"
 )
 
-// Generic Command Type
+// Time of the process start, used to fire @reboot schedules
+var BootTime = time.Now()
+
+// Generic Command Type, converted in command specs by CommandOf
 type CommandValue interface{}
 
 // Execution context, that will be passed to the ComputableValue
@@ -24,6 +31,15 @@ type ExecutionContext struct {
 	WarningsPipe	chan error
 	// Allows developers to send errors in the log
 	ErrorsPipe		chan error
+	// Run context, done when the task run times out or the scheduler stops: long running computations must
+	// return as soon as it's done
+	Context			context.Context
+	// Arguments of the func command that runs the registered function
+	Arguments		FuncArgs
+	// Hook that runs the function, empty for the task runs
+	Hook			HookEvent
+	// Run attempt record of the hook commands, with outcome, exit code, duration and output, nil for the task runs
+	Run				*RunRecord
 }
 
 // Describes interface that can be executed in the Scheduler (passed as CommandValue) with self encapsulation of the running process
@@ -31,6 +47,29 @@ type ComputableValue interface {
 	Compute(ExecutionContext) error
 }
 
+// Adapts a Go function to the ComputableValue interface, e.g. to register it by name
+type ComputableFunc func(ExecutionContext) error
+
+func (f ComputableFunc) Compute(c ExecutionContext) error {
+	return f(c)
+}
+
+// Error or warning of a task run, sent in the scheduler channels with the task unique identifier and, when the
+// run started, the run id
+type TaskError struct {
+	UUID	string
+	RunID	string
+	Err		error
+}
+
+func (e *TaskError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TaskError) Unwrap() error {
+	return e.Err
+}
+
 func CommandValueToString(c CommandValue) string {
 	return fmt.Sprintf("%v", c)
 }
@@ -46,16 +85,53 @@ type Execution struct {
 	Last    time.Time			`yaml:"lastExecution,omitempty" json:"lastExecution,omitempty" xml:"last-execution,omitempty"`
 	Times   int     			`yaml:"numberOfExecutions,omitempty" json:"numberOfExecutions,omitempty" xml:"number-of-executions,omitempty"`
 	Scheduled bool	   			`yaml:"scheduled,omitempty" json:"scheduled,omitempty" xml:"scheduled,omitempty"`
-	Map map[string]interface{}	`yaml:"scheduled,omitempty" json:"scheduled,omitempty" xml:"scheduled,omitempty"`
+	Map map[string]interface{}	`yaml:"map,omitempty" json:"map,omitempty" xml:"-"`
+	// Scheduler default time zone, used when the command does not declare its own
+	TimeZone string				`yaml:"timeZone,omitempty" json:"timeZone,omitempty" xml:"time-zone,omitempty"`
+	// Number of runs currently executing
+	Running int					`yaml:"running,omitempty" json:"running,omitempty" xml:"running,omitempty"`
+	// Number of runs waiting for the previous run or for a free scheduler slot
+	Queued int					`yaml:"queued,omitempty" json:"queued,omitempty" xml:"queued,omitempty"`
+	// Number of runs skipped by the concurrency policy
+	Skipped int					`yaml:"skipped,omitempty" json:"skipped,omitempty" xml:"skipped,omitempty"`
+	// Number of runs triggered on demand, out of the time table
+	Triggered int				`yaml:"triggered,omitempty" json:"triggered,omitempty" xml:"triggered,omitempty"`
+	// Last time the task has been triggered on demand
+	LastTriggered time.Time		`yaml:"lastTriggered,omitempty" json:"lastTriggered,omitempty" xml:"last-triggered,omitempty"`
+	// Number of runs started by the upstream tasks completion, out of the time table
+	WorkflowRuns int			`yaml:"workflowRuns,omitempty" json:"workflowRuns,omitempty" xml:"workflow-runs,omitempty"`
+	// Last time the task has been started by its upstream tasks
+	LastWorkflowRun time.Time	`yaml:"lastWorkflowRun,omitempty" json:"lastWorkflowRun,omitempty" xml:"last-workflow-run,omitempty"`
+	// Upstream tasks state, in the current workflow run
+	Dependencies []DependencyState	`yaml:"dependencies,omitempty" json:"dependencies,omitempty" xml:"dependency,omitempty"`
+}
+
+// Retrieves the time zone location used to calculate the execution time table
+func (e *Execution) Location() *time.Location {
+	var defaultLocation, err = utils.LoadLocation(e.TimeZone)
+	if err != nil {
+		defaultLocation = time.Local
+	}
+	return e.Command.Location(defaultLocation)
 }
 
 // Reset Command time table
-func (e Execution) Reset() {
+func (e *Execution) Reset() {
 	e.Next = e.Last
 }
 
+// Checks if all the upstream tasks completed with the declared outcome
+func (e *Execution) DependenciesSatisfied() bool {
+	for _, d := range e.Dependencies {
+		if !d.Satisfied {
+			return false
+		}
+	}
+	return len(e.Dependencies) > 0
+}
+
 // Reset Command time table
-func (e Execution) Expired() bool {
+func (e *Execution) Expired() bool {
 	e.UpdateNext()
 	if time.Since(e.Next).Nanoseconds() >= 0 || e.Scheduled {
 		return false
@@ -64,24 +140,28 @@ func (e Execution) Expired() bool {
 }
 
 // Update Command time table and calculate Next Execution
-func (e Execution) UpdateNext() {
+func (e *Execution) UpdateNext() {
 	c := e.Command
+	var loc = e.Location()
+	var now = utils.NowIn(loc)
 	if ! e.Scheduled || c.OnDemand {
 		if c.OnDemand {
 			if c.Repeat > 0 && e.Times > c.Repeat {
 				e.Scheduled = false
-				e.Last = time.Now().Add(-20 * time.Minute)
-				e.Next = time.Now().Add(-20 * time.Minute)
+				e.Last = now.Add(-20 * time.Minute)
+				e.Next = now.Add(-20 * time.Minute)
 			} else {
-				e.Last = time.Now()
-				e.Next = time.Now().Add(2 * time.Second)
+				e.Last = now
+				e.Next = now.Add(2 * time.Second)
 			}
 		} else {
 			if c.Repeat > 0 && e.Times > c.Repeat {
 				e.Scheduled = false
 				e.Next = e.Last
 			} else {
-				if c.Period != "" {
+				if c.Schedule != "" {
+					e.updateScheduleNext()
+				} else if c.Period != "" {
 					d, err := time.ParseDuration(c.Period)
 					if err == nil {
 						if time.Since(c.Since).Nanoseconds() > 0 {
@@ -102,10 +182,10 @@ func (e Execution) UpdateNext() {
 							if e.Last.Sub(c.Since).Nanoseconds() > 0 {
 								e.Next = c.Since
 							} else {
-								e.Next = time.Now().Add(20 * time.Second)
+								e.Next = now.Add(20 * time.Second)
 							}
 						} else {
-							e.Next = time.Now().Add(20 * time.Second)
+							e.Next = now.Add(20 * time.Second)
 						}
 					} else {
 						e.Next = e.Last
@@ -114,16 +194,65 @@ func (e Execution) UpdateNext() {
 			}
 		}
 	}
-	//e.Next =
+	if !e.Next.IsZero() {
+		e.Next = e.Next.In(loc)
+	}
 }
 
-func (e Execution) NeedScheduling() bool {
+// Calculates next execution from the command cron schedule. Next execution is kept until the task runs,
+// so a fire time missed while the scheduler was not running is executed once at the next check.
+func (e *Execution) updateScheduleNext() {
 	c := e.Command
+	sched, err := schedule.Parse(c.Schedule)
+	if err != nil {
+		e.Next = time.Time{}
+		return
+	}
+	if sched.Reboot {
+		if e.Last.Before(BootTime) {
+			e.Next = BootTime
+		} else {
+			e.Next = time.Time{}
+		}
+		return
+	}
+	if e.Next.IsZero() || !e.Next.After(e.Last) {
+		var from = e.Last
+		if from.IsZero() {
+			from = time.Now()
+		}
+		if c.Since.After(from) {
+			from = c.Since
+		}
+		e.Next = sched.Next(from.In(e.Location()))
+	}
+}
+
+// Calculates the next n fire times, starting from the next execution
+func (e *Execution) NextRuns(n int) []time.Time {
+	var out = make([]time.Time, 0)
+	if n <= 0 || e.Next.IsZero() {
+		return out
+	}
+	var next = e.Next.In(e.Location())
+	out = append(out, next)
+	return append(out, e.Command.NextRuns(next, n-1)...)
+}
+
+func (e *Execution) NeedScheduling() bool {
+	c := e.Command
+	if !c.IsEnabled() || c.DependencyOnly() {
+		return false
+	}
 	if c.Repeat > 0 && e.Times > c.Repeat {
 		e.Scheduled = false
 		return false
 	}
 	e.UpdateNext()
+	if c.Schedule != "" && e.Next.IsZero() {
+		// Schedule never fires again
+		return false
+	}
 	return ! e.Scheduled && time.Since(e.Next).Nanoseconds() >= 0
 }
 
@@ -131,12 +260,26 @@ func (e Execution) NeedScheduling() bool {
 type Scheduler interface {
 	// Checks if scheduler is still/already running
 	IsRunning() bool
+	// Retrieves the scheduler default time zone location
+	Location() *time.Location
 	// Start scheduler process
 	Start() error
 	// Stop scheduler
 	Stop() error
 	// Run scheduler once and exit
 	RunOnce() error
+	// Suspends the scheduling of new runs, running tasks complete normally
+	Pause() error
+	// Resumes the scheduling of new runs
+	Resume() error
+	// Enables the scheduling of the task with given unique identifier or name
+	Enable(key string) error
+	// Disables the scheduling of the task with given unique identifier or name, it can still be triggered
+	Disable(key string) error
+	// Retrieves the scheduler state
+	State() SchedulerState
+	// Runs immediately the task with given unique identifier or name, out of its time table
+	Trigger(id string) error
 	// Collects all running tasks
 	Running() []Execution
 	// Load scheduler data from the device
@@ -147,11 +290,24 @@ type Scheduler interface {
 	Planned() []CommandConfig
 	// Collects all next running tasks
 	NextRunningTasks() []Execution
+	// Collects the tasks run history records matching the filter, in chronological order
+	History(filter HistoryFilter) []RunRecord
+	// Retrieves the webhook delivery log of the task with given unique identifier (all tasks when empty), limited
+	// to the given number of most recent records (all when zero or less)
+	Deliveries(uuid string, limit int) []WebhookDelivery
+	// Retrieves reference and configuration of the task with given unique identifier or name
+	Get(key string) (CommandConfigRef, CommandConfig, error)
+	// Update the task with given unique identifier or name, persisting data for not cached tasks
+	Update(key string, cmd CommandConfig) error
+	// Delete the task with given unique identifier or name, persisting data for not cached tasks
+	Delete(key string) error
 	// Add a task and persist data
 	AddAndPersist(cmd CommandConfig) error
-	// Update a task and persist data
+	// Add a task and persist data, retrieving the reference of the new task
+	Add(cmd CommandConfig) (CommandConfigRef, error)
+	// Update a task and persist data (legacy, see Update)
 	UpdateAndPersist(cmd CommandConfig, index int) error
-	// Delete a task and persist data
+	// Delete a task and persist data (legacy, see Delete)
 	DeleteAndPersist(index int) error
 	// Add a task to cache without persist (function executable tasks)
 	AddToCache(cmd CommandConfig) error
@@ -161,9 +317,14 @@ type Scheduler interface {
 	DeleteFromCache(index int) error
 	// Waits until scheduler finish
 	Wait()
-	// Retrieves the scheduler errors channel, used to report live errors from scheduler or scheduler tasks
+	// Subscribes the scheduler events, with given buffer size (DefaultEventBuffer when not positive): events
+	// are dropped and counted when the buffer is full, so slow subscribers never block the scheduler
+	Subscribe(buffer int) *Subscription
+	// Writes the scheduler and tasks metrics in the Prometheus text exposition format
+	WriteMetrics(w io.Writer) error
+	// Retrieves the scheduler errors channel, adapter of the error level events
 	Errors() chan error
-	// Retrieves the scheduler warnings channel, used to report live warnings from scheduler or scheduler tasks
+	// Retrieves the scheduler warnings channel, adapter of the warning level events
 	Warnings() chan error
 	// Stop Scheduler if required, then Destroy content, data and shared memory items
 	// and eventually save last state fto the device if required
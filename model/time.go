@@ -1,6 +1,8 @@
 package model
 
 import (
+	"github.com/hellgate75/go-cron/schedule"
+	"github.com/hellgate75/go-cron/utils"
 	"time"
 )
 
@@ -14,19 +16,134 @@ const (
 	SchedulerStatePaused	= SchedulerState("paused")
 )
 
+// Describes how a command run starts while previous runs of the same command are still executing
+type ConcurrencyPolicy string
+
+const (
+	// Runs start regardless of the previous runs
+	ConcurrencyAllow = ConcurrencyPolicy("allow")
+	// Runs are skipped while a previous run is executing (default)
+	ConcurrencyForbid = ConcurrencyPolicy("forbid")
+	// Runs wait for the previous run to complete
+	ConcurrencyQueue = ConcurrencyPolicy("queue")
+	// Previous runs are cancelled and the new run starts
+	ConcurrencyReplace = ConcurrencyPolicy("replace")
+)
+
+// Verifies the policy is a known one, empty policy is valid and it's the default one
+func (p ConcurrencyPolicy) Valid() bool {
+	switch p {
+	case "", ConcurrencyAllow, ConcurrencyForbid, ConcurrencyQueue, ConcurrencyReplace:
+		return true
+	}
+	return false
+}
+
 // Defines the scheduler configuration
 type CommandConfig struct {
+	// Unique human readable name of the task, it can be used in place of the task unique identifier
+	Name				string										`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	// Scheduling enable flag, disabled tasks run only when triggered on demand (default: true)
+	Enabled				*bool										`yaml:"enabled,omitempty" json:"enabled,omitempty" xml:"enabled,omitempty"`
 	OnDemand			bool										`yaml:"onDemand,omitempty" json:"onDemand,omitempty" xml:"onDemand,omitempty"`
 	Period				string										`yaml:"period,omitempty" json:"period,omitempty" xml:"period,omitempty"`
 	Repeat				int											`yaml:"repeat,omitempty" json:"repeat,omitempty" xml:"repeat,omitempty"`
 	Since				time.Time									`yaml:"since,omitempty" json:"since,omitempty" xml:"since,omitempty"`
-	Command				CommandValue								`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+	// Cron expression (5 or 6 fields, or macro as @daily), when present it replaces the period
+	Schedule			string										`yaml:"schedule,omitempty" json:"schedule,omitempty" xml:"schedule,omitempty"`
+	// IANA time zone name (e.g.: Europe/Rome) used to calculate the schedule, default is the scheduler time zone
+	TimeZone			string										`yaml:"timeZone,omitempty" json:"timeZone,omitempty" xml:"timeZone,omitempty"`
+	// Maximum run duration, as Go duration (e.g.: 30m), empty for no timeout
+	Timeout				string										`yaml:"timeout,omitempty" json:"timeout,omitempty" xml:"timeout,omitempty"`
+	// Failed runs retry policy
+	Retry				RetryConfig									`yaml:"retry,omitempty" json:"retry,omitempty" xml:"retry,omitempty"`
+	// Run overlap policy: allow, forbid, queue or replace (default: forbid)
+	Concurrency			ConcurrencyPolicy							`yaml:"concurrency,omitempty" json:"concurrency,omitempty" xml:"concurrency,omitempty"`
+	// Upstream tasks, the command runs when all of them complete with the declared outcome
+	DependsOn			[]Dependency								`yaml:"dependsOn,omitempty" json:"dependsOn,omitempty" xml:"depends-on,omitempty"`
+	Command				CommandSpec									`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+	// Shell and exec commands process options: shell, working directory, environment, input and output
+	Process				ProcessOptions								`yaml:"process,omitempty" json:"process,omitempty" xml:"process,omitempty"`
+	// Notifications sent when the task runs end, in addition to the scheduler webhooks
+	Webhooks			[]Webhook									`yaml:"webhooks,omitempty" json:"webhooks,omitempty" xml:"webhook,omitempty"`
+	// Mail notifications, the declared fields override the scheduler mail configuration
+	Mail				*MailConfig									`yaml:"mail,omitempty" json:"mail,omitempty" xml:"mail,omitempty"`
+	// Hook commands run after each run attempt: on success, on failure and always
+	Hooks				RunHooks									`yaml:"hooks,omitempty" json:"hooks,omitempty" xml:"hooks,omitempty"`
+}
+
+// Checks if the command runs only when its upstream tasks complete, because it has no time table
+func (c CommandConfig) DependencyOnly() bool {
+	return len(c.DependsOn) > 0 && c.Period == "" && c.Schedule == "" && c.Repeat == 0 && !c.OnDemand
+}
+
+// Retrieves the command run timeout, zero when the command has no valid timeout
+func (c CommandConfig) TimeoutDuration() time.Duration {
+	if c.Timeout != "" {
+		if d, err := time.ParseDuration(c.Timeout); err == nil && d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// Checks if the command is enabled to be scheduled
+func (c CommandConfig) IsEnabled() bool {
+	return c.Enabled == nil || *c.Enabled
+}
+
+// Retrieves the command concurrency policy, or the default one when not declared
+func (c CommandConfig) Policy() ConcurrencyPolicy {
+	if c.Concurrency == "" {
+		return ConcurrencyForbid
+	}
+	return c.Concurrency
+}
+
+// Retrieves the command time zone location, or the given default location when the command does not declare
+// a valid time zone
+func (c CommandConfig) Location(defaultLocation *time.Location) *time.Location {
+	if c.TimeZone != "" {
+		if loc, err := utils.LoadLocation(c.TimeZone); err == nil {
+			return loc
+		}
+	}
+	if defaultLocation == nil {
+		return time.Local
+	}
+	return defaultLocation
+}
+
+// Calculates the next n fire times after the given time, for cron schedule or period based commands.
+// Fire times are expressed in the location of the given time.
+func (c CommandConfig) NextRuns(from time.Time, n int) []time.Time {
+	var out = make([]time.Time, 0)
+	if c.Since.After(from) {
+		from = c.Since.In(from.Location())
+	}
+	if c.Schedule != "" {
+		sched, err := schedule.Parse(c.Schedule)
+		if err == nil {
+			out = append(out, sched.NextN(from, n)...)
+		}
+	} else if c.Period != "" {
+		d, err := time.ParseDuration(c.Period)
+		if err == nil && d > 0 {
+			for i := 1; i <= n; i++ {
+				out = append(out, from.Add(time.Duration(i)*d))
+			}
+		}
+	}
+	return out
 }
 
 // Defines reference the scheduler configuration
 type CommandConfigRef struct {
 	UUID				string										`yaml:"uuid,omitempty" json:"uuid,omitempty" xml:"uuid,omitempty"`
-	Command				CommandValue								`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
+	Name				string										`yaml:"name,omitempty" json:"name,omitempty" xml:"name,omitempty"`
+	// Scheduling enable flag of the task, native item files cannot hold a disabled flag
+	Enabled				*bool										`yaml:"enabled,omitempty" json:"enabled,omitempty" xml:"enabled,omitempty"`
+	Command				CommandSpec									`yaml:"command,omitempty" json:"command,omitempty" xml:"command,omitempty"`
 	Created				time.Time									`yaml:"created,omitempty" json:"created,omitempty" xml:"created,omitempty"`
 	Updated				time.Time									`yaml:"updated,omitempty" json:"updated,omitempty" xml:"updated,omitempty"`
 	FirstRun			time.Time									`yaml:"fistExecution,omitempty" json:"fistExecution,omitempty" xml:"first-execution,omitempty"`
@@ -34,8 +151,41 @@ type CommandConfigRef struct {
 }
 
 
+// Verifies if the reference matches the given task key: unique identifier or name
+func (r CommandConfigRef) Matches(key string) bool {
+	return key != "" && (r.UUID == key || r.Name == key)
+}
+
+
+// Default time given to running tasks to complete when the scheduler stops
+const DefaultStopGracePeriod = 10 * time.Second
+
 // Defines the scheduler configuration if the scheduler is configured in sync mode it will run all tasks immediately all together.
 type SchedulerConfig struct {
 	Sync				bool										`yaml:"sync,omitempty" json:"sync,omitempty" xml:"sync,omitempty"`
+	// Default IANA time zone name of the scheduled commands, default is the host local time zone
+	TimeZone			string										`yaml:"timeZone,omitempty" json:"timeZone,omitempty" xml:"timeZone,omitempty"`
 	Commands			[]CommandConfigRef							`yaml:"commands,omitempty" json:"commands,omitempty" xml:"command,omitempty"`
+	// Maximum number of tasks running at the same time, further runs wait for a free slot (0 for no limit)
+	MaxRunning			int											`yaml:"maxRunning,omitempty" json:"maxRunning,omitempty" xml:"max-running,omitempty"`
+	// Time given to running tasks to complete when the scheduler stops, as Go duration (default: 10s)
+	StopGracePeriod		string										`yaml:"stopGracePeriod,omitempty" json:"stopGracePeriod,omitempty" xml:"stop-grace-period,omitempty"`
+	// Scheduling of new runs suspended, it's kept across scheduler restarts
+	Paused				bool										`yaml:"paused,omitempty" json:"paused,omitempty" xml:"paused,omitempty"`
+	// Run history retention policy
+	History				HistoryConfig								`yaml:"history,omitempty" json:"history,omitempty" xml:"history,omitempty"`
+	// Notifications sent when the runs of any task end
+	Webhooks			[]Webhook									`yaml:"webhooks,omitempty" json:"webhooks,omitempty" xml:"webhook,omitempty"`
+	// Mail notifications of the runs of all tasks
+	Mail				MailConfig									`yaml:"mail,omitempty" json:"mail,omitempty" xml:"mail,omitempty"`
+}
+
+// Retrieves the stop grace period, or the default one when not valid
+func (c SchedulerConfig) GracePeriod() time.Duration {
+	if c.StopGracePeriod != "" {
+		if d, err := time.ParseDuration(c.StopGracePeriod); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return DefaultStopGracePeriod
 }
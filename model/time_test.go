@@ -0,0 +1,72 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimeZoneResolution(t *testing.T) {
+	var cases = []struct {
+		name      string
+		scheduler string
+		command   string
+		expected  string
+	}{
+		{"host time zone", "", "", time.Local.String()},
+		{"scheduler time zone", "America/New_York", "", "America/New_York"},
+		{"command time zone", "", "Asia/Tokyo", "Asia/Tokyo"},
+		{"command overrides scheduler", "America/New_York", "Europe/Rome", "Europe/Rome"},
+		{"utc command", "Europe/Rome", "UTC", "UTC"},
+		{"invalid command time zone", "America/New_York", "Mars/Olympus", "America/New_York"},
+		{"invalid scheduler time zone", "Mars/Olympus", "", time.Local.String()},
+		{"invalid time zones", "Mars/Olympus", "Moon/Tycho", time.Local.String()},
+	}
+	for _, c := range cases {
+		var exec = Execution{TimeZone: c.scheduler, Command: CommandConfig{TimeZone: c.command}}
+		if loc := exec.Location(); loc.String() != c.expected {
+			t.Errorf("%s: expected location %s, found %s", c.name, c.expected, loc)
+		}
+	}
+	if loc := (CommandConfig{}).Location(nil); loc != time.Local {
+		t.Errorf("Expected host time zone without default location, found %s", loc)
+	}
+}
+
+func TestNextRunsInTimeZone(t *testing.T) {
+	rome, err := time.LoadLocation("Europe/Rome")
+	if err != nil {
+		t.Skipf("Time zone database not available: %v", err)
+	}
+	newYork, _ := time.LoadLocation("America/New_York")
+	var cases = []struct {
+		name     string
+		cmd      CommandConfig
+		from     time.Time
+		expected []time.Time
+	}{
+		{"daily in Rome", CommandConfig{Schedule: "0 6 * * *"}, time.Date(2020, 1, 10, 7, 0, 0, 0, rome),
+			[]time.Time{time.Date(2020, 1, 11, 6, 0, 0, 0, rome), time.Date(2020, 1, 12, 6, 0, 0, 0, rome)}},
+		// 2020-03-29 02:00 doesn't exist in Rome, the clocks move from 02:00 to 03:00
+		{"daily across Rome spring forward", CommandConfig{Schedule: "30 6 * * *"}, time.Date(2020, 3, 28, 7, 0, 0, 0, rome),
+			[]time.Time{time.Date(2020, 3, 29, 6, 30, 0, 0, rome), time.Date(2020, 3, 30, 6, 30, 0, 0, rome)}},
+		{"daily in New York", CommandConfig{Schedule: "0 9 * * *"}, time.Date(2020, 11, 1, 0, 0, 0, 0, newYork),
+			[]time.Time{time.Date(2020, 11, 1, 9, 0, 0, 0, newYork), time.Date(2020, 11, 2, 9, 0, 0, 0, newYork)}},
+		{"period keeps elapsed time", CommandConfig{Period: "24h"}, time.Date(2020, 3, 28, 12, 0, 0, 0, rome),
+			[]time.Time{time.Date(2020, 3, 29, 13, 0, 0, 0, rome), time.Date(2020, 3, 30, 13, 0, 0, 0, rome)}},
+		{"since in the future", CommandConfig{Period: "1h", Since: time.Date(2020, 1, 1, 10, 0, 0, 0, time.UTC)},
+			time.Date(2019, 12, 31, 0, 0, 0, 0, rome),
+			[]time.Time{time.Date(2020, 1, 1, 12, 0, 0, 0, rome), time.Date(2020, 1, 1, 13, 0, 0, 0, rome)}},
+	}
+	for _, c := range cases {
+		var runs = c.cmd.NextRuns(c.from, len(c.expected))
+		if len(runs) != len(c.expected) {
+			t.Errorf("%s: expected %d runs, found %v", c.name, len(c.expected), runs)
+			continue
+		}
+		for idx, run := range runs {
+			if !run.Equal(c.expected[idx]) || run.Location().String() != c.from.Location().String() {
+				t.Errorf("%s: expected run %d at %v, found %v", c.name, idx, c.expected[idx], run)
+			}
+		}
+	}
+}
@@ -0,0 +1,220 @@
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Bounds of a cron expression field
+type bounds struct {
+	name  string
+	min   uint
+	max   uint
+	names map[string]uint
+}
+
+var (
+	secondsBounds = bounds{"second", 0, 59, nil}
+	minutesBounds = bounds{"minute", 0, 59, nil}
+	hoursBounds   = bounds{"hour", 0, 23, nil}
+	domBounds     = bounds{"day of month", 1, 31, nil}
+	monthsBounds  = bounds{"month", 1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// Day of week accepts 0-7, where both 0 and 7 are Sunday
+	dowBounds = bounds{"day of week", 0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+// Available expression macros and their standard field equivalent
+var macros = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Macro that fires once when the scheduler starts
+const RebootMacro = "@reboot"
+
+// Parses a cron expression in one of the formats:
+// five fields (minute hour day-of-month month day-of-week),
+// six fields (second minute hour day-of-month month day-of-week),
+// or one of the macros: @yearly, @annually, @monthly, @weekly, @daily, @midnight, @hourly, @reboot.
+func Parse(expression string) (*Schedule, error) {
+	var spec = strings.TrimSpace(expression)
+	if spec == "" {
+		return nil, errors.New("Empty cron expression")
+	}
+	if strings.HasPrefix(spec, "@") {
+		var macro = strings.ToLower(spec)
+		if macro == RebootMacro {
+			return &Schedule{Expression: expression, Reboot: true}, nil
+		}
+		if fields, ok := macros[macro]; ok {
+			sched, err := parseFields(strings.Fields(fields))
+			if err != nil {
+				return nil, err
+			}
+			sched.Expression = expression
+			return sched, nil
+		}
+		return nil, errors.New(fmt.Sprintf("Unknown cron macro: %s", spec))
+	}
+	var fields = strings.Fields(spec)
+	switch len(fields) {
+	case 5:
+		fields = append([]string{"0"}, fields...)
+	case 6:
+	default:
+		return nil, errors.New(fmt.Sprintf("Invalid cron expression '%s': expected 5 or 6 fields, found %v", spec, len(fields)))
+	}
+	sched, err := parseFields(fields)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Invalid cron expression '%s': %v", spec, err))
+	}
+	sched.Expression = expression
+	return sched, nil
+}
+
+// Verifies that given cron expression can be parsed
+func Validate(expression string) error {
+	_, err := Parse(expression)
+	return err
+}
+
+func parseFields(fields []string) (*Schedule, error) {
+	var err error
+	var sched = &Schedule{}
+	if sched.Second, err = parseField(fields[0], secondsBounds); err != nil {
+		return nil, err
+	}
+	if sched.Minute, err = parseField(fields[1], minutesBounds); err != nil {
+		return nil, err
+	}
+	if sched.Hour, err = parseField(fields[2], hoursBounds); err != nil {
+		return nil, err
+	}
+	if sched.Dom, err = parseField(fields[3], domBounds); err != nil {
+		return nil, err
+	}
+	if sched.Month, err = parseField(fields[4], monthsBounds); err != nil {
+		return nil, err
+	}
+	if sched.Dow, err = parseField(fields[5], dowBounds); err != nil {
+		return nil, err
+	}
+	// Sunday can be expressed both as 0 and 7
+	if sched.Dow&(1<<7) != 0 {
+		sched.Dow = (sched.Dow &^ (1 << 7)) | 1
+	}
+	sched.DomStar = isStar(fields[3])
+	sched.DowStar = isStar(fields[5])
+	return sched, nil
+}
+
+func isStar(field string) bool {
+	return field == "*" || field == "?"
+}
+
+// Parses a comma separated list of ranges and returns the bit set of the matching values
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		value, err := parseRange(item, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= value
+	}
+	return bits, nil
+}
+
+// Parses a single range item in the forms: *, ?, n, n-m, */s, n/s, n-m/s
+func parseRange(item string, b bounds) (uint64, error) {
+	if item == "" {
+		return 0, errors.New(fmt.Sprintf("empty %s value", b.name))
+	}
+	var start, end, step uint
+	var err error
+	var rangeAndStep = strings.Split(item, "/")
+	if len(rangeAndStep) > 2 {
+		return 0, errors.New(fmt.Sprintf("too many slashes in %s value: %s", b.name, item))
+	}
+	var lowAndHigh = strings.Split(rangeAndStep[0], "-")
+	if len(lowAndHigh) > 2 {
+		return 0, errors.New(fmt.Sprintf("too many hyphens in %s value: %s", b.name, item))
+	}
+	if isStar(lowAndHigh[0]) {
+		if len(lowAndHigh) > 1 {
+			return 0, errors.New(fmt.Sprintf("invalid %s range: %s", b.name, item))
+		}
+		start, end = b.min, b.max
+		if b.max == 7 {
+			// Avoid duplicating Sunday in day of week wildcards
+			end = 6
+		}
+	} else {
+		if start, err = parseValue(lowAndHigh[0], b); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = parseValue(lowAndHigh[1], b); err != nil {
+				return 0, err
+			}
+		}
+	}
+	step = 1
+	if len(rangeAndStep) == 2 {
+		if step, err = parseNumber(rangeAndStep[1]); err != nil {
+			return 0, errors.New(fmt.Sprintf("invalid %s step: %s", b.name, item))
+		}
+		if step == 0 {
+			return 0, errors.New(fmt.Sprintf("%s step must be greater than zero: %s", b.name, item))
+		}
+		// A single start value with a step means: from start to the end of the range
+		if len(lowAndHigh) == 1 && !isStar(lowAndHigh[0]) {
+			end = b.max
+		}
+	}
+	if start > end {
+		return 0, errors.New(fmt.Sprintf("%s range start %v is beyond end %v: %s", b.name, start, end, item))
+	}
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, nil
+}
+
+func parseValue(value string, b bounds) (uint, error) {
+	if b.names != nil {
+		if n, ok := b.names[strings.ToLower(value)]; ok {
+			return n, nil
+		}
+	}
+	n, err := parseNumber(value)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("invalid %s value: %s", b.name, value))
+	}
+	if n < b.min || n > b.max {
+		return 0, errors.New(fmt.Sprintf("%s value %v out of range [%v-%v]", b.name, n, b.min, b.max))
+	}
+	return n, nil
+}
+
+func parseNumber(value string) (uint, error) {
+	n, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, err
+	}
+	return uint(n), nil
+}
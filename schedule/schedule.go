@@ -0,0 +1,261 @@
+package schedule
+
+import (
+	"time"
+)
+
+// Maximum number of years searched for a matching time, before giving up (e.g.: 30th of February)
+const searchYears = 5
+
+// Describes a parsed cron expression, each field is a bit set of the allowed values
+type Schedule struct {
+	// Original expression
+	Expression string
+	Second     uint64
+	Minute     uint64
+	Hour       uint64
+	Dom        uint64
+	Month      uint64
+	Dow        uint64
+	// Day of month field was a wildcard
+	DomStar bool
+	// Day of week field was a wildcard
+	DowStar bool
+	// Schedule fires only once, when the scheduler starts
+	Reboot bool
+}
+
+// Calculates the first fire time strictly after the given time, in the location of the given time.
+// It returns the zero time when the schedule never fires (reboot schedules or impossible dates).
+//
+// Daylight saving time policy:
+// - local times skipped when clocks move forward fire once, at the first instant after the gap;
+// - local times repeated when clocks move back fire once, at their first occurrence, unless the hour
+// field is a wildcard, in which case they fire at each occurrence (e.g.: every 15 minutes keeps running
+// every 15 minutes).
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.Reboot {
+		return time.Time{}
+	}
+	var loc = t.Location()
+	t = t.Truncate(time.Second)
+	// Search is executed on the wall clock, represented in UTC, so the calendar arithmetic is not
+	// affected by the location offset changes
+	var wall = wallClock(t)
+	for {
+		w, ok := s.nextWall(wall.Add(time.Second))
+		if !ok {
+			return time.Time{}
+		}
+		if next, ok := s.resolve(w, t, loc); ok {
+			if s.everyHour() {
+				if repeated, ok := s.nextRepeated(t, next, loc); ok {
+					return repeated
+				}
+			}
+			return next
+		}
+		wall = w
+	}
+}
+
+// Calculates the next n fire times strictly after the given time
+func (s *Schedule) NextN(t time.Time, n int) []time.Time {
+	var out = make([]time.Time, 0)
+	for i := 0; i < n; i++ {
+		t = s.Next(t)
+		if t.IsZero() {
+			break
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+// Verifies if the given time matches the schedule, in the location of the given time
+func (s *Schedule) Matches(t time.Time) bool {
+	if s.Reboot {
+		return false
+	}
+	return s.Second&(1<<uint(t.Second())) != 0 &&
+		s.Minute&(1<<uint(t.Minute())) != 0 &&
+		s.Hour&(1<<uint(t.Hour())) != 0 &&
+		s.Month&(1<<uint(t.Month())) != 0 &&
+		s.dayMatches(t)
+}
+
+// Standard cron rule: when both day of month and day of week are restricted, a day matches
+// if it satisfies any of the two fields, otherwise it must satisfy both
+func (s *Schedule) dayMatches(t time.Time) bool {
+	var domMatch = s.Dom&(1<<uint(t.Day())) != 0
+	var dowMatch = s.Dow&(1<<uint(t.Weekday())) != 0
+	if s.DomStar || s.DowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
+
+// Finds the first matching wall clock time, at or after the given one (expressed in UTC)
+func (s *Schedule) nextWall(t time.Time) (time.Time, bool) {
+	var yearLimit = t.Year() + searchYears
+	var added = false
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}, false
+	}
+	for s.Month&(1<<uint(t.Month())) == 0 {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+		}
+		t = t.AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+	for !s.dayMatches(t) {
+		if !added {
+			added = true
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		}
+		t = t.AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+	for s.Hour&(1<<uint(t.Hour())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Hour)
+		}
+		t = t.Add(time.Hour)
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+	for s.Minute&(1<<uint(t.Minute())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Minute)
+		}
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+	for s.Second&(1<<uint(t.Second())) == 0 {
+		if !added {
+			added = true
+			t = t.Truncate(time.Second)
+		}
+		t = t.Add(time.Second)
+		if t.Second() == 0 {
+			goto wrap
+		}
+	}
+	return t, true
+}
+
+func (s *Schedule) everyHour() bool {
+	return s.Hour == 1<<24-1
+}
+
+// Converts the given wall clock time (expressed in UTC) to an instant in the given location,
+// applying the daylight saving time policy. It reports false when the wall clock time must not fire
+// after the given time.
+func (s *Schedule) resolve(w time.Time, after time.Time, loc *time.Location) (time.Time, bool) {
+	var occurrences = wallOccurrences(w, loc)
+	if len(occurrences) == 0 {
+		// Skipped local time
+		var end = gapEnd(w, loc)
+		return end, end.After(after)
+	}
+	if s.everyHour() {
+		for _, o := range occurrences {
+			if o.After(after) {
+				return o, true
+			}
+		}
+		return time.Time{}, false
+	}
+	return occurrences[0], occurrences[0].After(after)
+}
+
+// Looks for a fire time in the local times repeated by a backward offset change between the two
+// given instants
+func (s *Schedule) nextRepeated(after time.Time, before time.Time, loc *time.Location) (time.Time, bool) {
+	if offsetAt(before, loc) >= offsetAt(after, loc) {
+		return time.Time{}, false
+	}
+	var transition = transitionBetween(after, before, loc)
+	var start = wallClock(transition)
+	var end = wallClock(transition.Add(-time.Second)).Add(time.Second)
+	w, ok := s.nextWall(start)
+	if !ok || !w.Before(end) {
+		return time.Time{}, false
+	}
+	var instant = w.Add(-time.Duration(offsetAt(transition, loc)) * time.Second).In(loc)
+	return instant, instant.After(after) && instant.Before(before)
+}
+
+// Represents the wall clock of the given time as a UTC time
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), 0, time.UTC)
+}
+
+func offsetAt(t time.Time, loc *time.Location) int {
+	_, offset := t.In(loc).Zone()
+	return offset
+}
+
+// Collects, in chronological order, the instants showing the given wall clock time in the given location
+func wallOccurrences(w time.Time, loc *time.Location) []time.Time {
+	var out = make([]time.Time, 0)
+	var guess = time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), 0, loc)
+	for _, probe := range []time.Time{guess.Add(-12 * time.Hour), guess, guess.Add(12 * time.Hour)} {
+		var instant = w.Add(-time.Duration(offsetAt(probe, loc)) * time.Second).In(loc)
+		if !wallClock(instant).Equal(w) {
+			continue
+		}
+		var found = false
+		for _, o := range out {
+			if o.Equal(instant) {
+				found = true
+			}
+		}
+		if !found {
+			out = append(out, instant)
+		}
+	}
+	for i := 1; i < len(out); i++ {
+		for j := i; j > 0 && out[j].Before(out[j-1]); j-- {
+			out[j], out[j-1] = out[j-1], out[j]
+		}
+	}
+	return out
+}
+
+// Calculates the first instant after the gap that hides the given wall clock time in the given location
+func gapEnd(w time.Time, loc *time.Location) time.Time {
+	var guess = time.Date(w.Year(), w.Month(), w.Day(), w.Hour(), w.Minute(), w.Second(), 0, loc)
+	var before = offsetAt(guess.Add(-12*time.Hour), loc)
+	var after = offsetAt(guess.Add(12*time.Hour), loc)
+	var from = w.Add(-time.Duration(after) * time.Second)
+	var to = w.Add(-time.Duration(before) * time.Second)
+	return transitionBetween(from, to, loc)
+}
+
+// Finds, with a second precision, the first instant after the given start with the offset in effect at the given end
+func transitionBetween(start time.Time, end time.Time, loc *time.Location) time.Time {
+	var target = offsetAt(end, loc)
+	var lo, hi = start.Unix(), end.Unix()
+	for lo < hi {
+		var mid = lo + (hi-lo)/2
+		if offsetAt(time.Unix(mid, 0), loc) == target {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+	return time.Unix(hi, 0).In(loc)
+}
@@ -0,0 +1,279 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("Unable to load location %s: %v", name, err)
+	}
+	return loc
+}
+
+func TestParseErrors(t *testing.T) {
+	var tests = []struct {
+		name       string
+		expression string
+	}{
+		{"empty", ""},
+		{"too few fields", "* * * *"},
+		{"too many fields", "* * * * * * *"},
+		{"unknown macro", "@sometimes"},
+		{"second out of range", "60 * * * * *"},
+		{"minute out of range", "60 * * * *"},
+		{"hour out of range", "0 24 * * *"},
+		{"day of month zero", "0 0 0 * *"},
+		{"day of month out of range", "0 0 32 * *"},
+		{"month zero", "0 0 1 0 *"},
+		{"month out of range", "0 0 1 13 *"},
+		{"day of week out of range", "0 0 * * 8"},
+		{"unknown month name", "0 0 1 foo *"},
+		{"unknown day name", "0 0 * * funday"},
+		{"reversed range", "0 10-5 * * *"},
+		{"zero step", "*/0 * * * *"},
+		{"invalid step", "*/x * * * *"},
+		{"too many slashes", "*/2/3 * * * *"},
+		{"too many hyphens", "1-2-3 * * * *"},
+		{"star range", "*-5 * * * *"},
+		{"empty list item", "1,,2 * * * *"},
+		{"negative value", "-1 * * * *"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if _, err := Parse(test.expression); err == nil {
+				t.Errorf("Expected error parsing '%s'", test.expression)
+			}
+			if err := Validate(test.expression); err == nil {
+				t.Errorf("Expected validation error of '%s'", test.expression)
+			}
+		})
+	}
+}
+
+func TestParseFieldValues(t *testing.T) {
+	var tests = []struct {
+		name       string
+		expression string
+		field      func(s *Schedule) uint64
+		values     []uint
+	}{
+		{"five fields start at second zero", "* * * * *", func(s *Schedule) uint64 { return s.Second }, []uint{0}},
+		{"six fields seconds", "5,10 * * * * *", func(s *Schedule) uint64 { return s.Second }, []uint{5, 10}},
+		{"minute bounds", "0,59 * * * *", func(s *Schedule) uint64 { return s.Minute }, []uint{0, 59}},
+		{"hour bounds", "0 0,23 * * *", func(s *Schedule) uint64 { return s.Hour }, []uint{0, 23}},
+		{"day of month bounds", "0 0 1,31 * *", func(s *Schedule) uint64 { return s.Dom }, []uint{1, 31}},
+		{"month range", "0 0 1 3-5 *", func(s *Schedule) uint64 { return s.Month }, []uint{3, 4, 5}},
+		{"month names", "0 0 1 JAN,jun,Dec *", func(s *Schedule) uint64 { return s.Month }, []uint{1, 6, 12}},
+		{"month names range", "0 0 1 mar-may *", func(s *Schedule) uint64 { return s.Month }, []uint{3, 4, 5}},
+		{"weekday names", "0 0 * * mon,WED,fri", func(s *Schedule) uint64 { return s.Dow }, []uint{1, 3, 5}},
+		{"weekday names range", "0 0 * * mon-fri", func(s *Schedule) uint64 { return s.Dow }, []uint{1, 2, 3, 4, 5}},
+		{"sunday as seven", "0 0 * * 7", func(s *Schedule) uint64 { return s.Dow }, []uint{0}},
+		{"weekday wildcard", "0 0 * * *", func(s *Schedule) uint64 { return s.Dow }, []uint{0, 1, 2, 3, 4, 5, 6}},
+		{"question mark wildcard", "0 0 ? * ?", func(s *Schedule) uint64 { return s.Dow }, []uint{0, 1, 2, 3, 4, 5, 6}},
+		{"star step", "*/15 * * * *", func(s *Schedule) uint64 { return s.Minute }, []uint{0, 15, 30, 45}},
+		{"range step", "10-30/10 * * * *", func(s *Schedule) uint64 { return s.Minute }, []uint{10, 20, 30}},
+		{"start step", "50/5 * * * *", func(s *Schedule) uint64 { return s.Minute }, []uint{50, 55}},
+		{"hour step", "0 */6 * * *", func(s *Schedule) uint64 { return s.Hour }, []uint{0, 6, 12, 18}},
+		{"list of ranges", "0 1-2,20-21 * * *", func(s *Schedule) uint64 { return s.Hour }, []uint{1, 2, 20, 21}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sched, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing '%s': %v", test.expression, err)
+			}
+			var expected uint64
+			for _, v := range test.values {
+				expected |= 1 << v
+			}
+			if found := test.field(sched); found != expected {
+				t.Errorf("Expression '%s': expected bits %b, found %b", test.expression, expected, found)
+			}
+		})
+	}
+}
+
+func TestNext(t *testing.T) {
+	var from = time.Date(2026, time.January, 15, 10, 20, 30, 0, time.UTC)
+	var tests = []struct {
+		name       string
+		expression string
+		expected   []time.Time
+	}{
+		{"every minute", "* * * * *", []time.Time{
+			time.Date(2026, time.January, 15, 10, 21, 0, 0, time.UTC),
+			time.Date(2026, time.January, 15, 10, 22, 0, 0, time.UTC),
+		}},
+		{"every ten seconds", "*/10 * * * * *", []time.Time{
+			time.Date(2026, time.January, 15, 10, 20, 40, 0, time.UTC),
+			time.Date(2026, time.January, 15, 10, 20, 50, 0, time.UTC),
+			time.Date(2026, time.January, 15, 10, 21, 0, 0, time.UTC),
+		}},
+		{"hours range", "0 9-11 * * *", []time.Time{
+			time.Date(2026, time.January, 15, 11, 0, 0, 0, time.UTC),
+			time.Date(2026, time.January, 16, 9, 0, 0, 0, time.UTC),
+		}},
+		{"weekdays", "0 8 * * mon-fri", []time.Time{
+			time.Date(2026, time.January, 16, 8, 0, 0, 0, time.UTC),
+			time.Date(2026, time.January, 19, 8, 0, 0, 0, time.UTC),
+		}},
+		{"day of month or day of week", "0 0 1 * sun", []time.Time{
+			time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.January, 25, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"leap day", "0 0 29 feb *", []time.Time{
+			time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC),
+		}},
+		{"end of month skips short months", "0 0 31 * *", []time.Time{
+			time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC),
+			time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC),
+		}},
+		{"yearly macro", "@yearly", []time.Time{
+			time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"annually macro", "@annually", []time.Time{
+			time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"monthly macro", "@monthly", []time.Time{
+			time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC),
+		}},
+		{"weekly macro", "@weekly", []time.Time{
+			time.Date(2026, time.January, 18, 0, 0, 0, 0, time.UTC),
+		}},
+		{"daily macro", "@daily", []time.Time{
+			time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC),
+		}},
+		{"midnight macro", "@MIDNIGHT", []time.Time{
+			time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC),
+		}},
+		{"hourly macro", "@hourly", []time.Time{
+			time.Date(2026, time.January, 15, 11, 0, 0, 0, time.UTC),
+			time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sched, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing '%s': %v", test.expression, err)
+			}
+			var found = sched.NextN(from, len(test.expected))
+			if len(found) != len(test.expected) {
+				t.Fatalf("Expression '%s': expected %v, found %v", test.expression, test.expected, found)
+			}
+			for idx := range found {
+				if !found[idx].Equal(test.expected[idx]) {
+					t.Errorf("Expression '%s' fire %d: expected %v, found %v", test.expression, idx, test.expected[idx], found[idx])
+				}
+				if !sched.Matches(found[idx]) {
+					t.Errorf("Expression '%s' doesn't match its fire time %v", test.expression, found[idx])
+				}
+			}
+		})
+	}
+}
+
+func TestRebootAndImpossibleDates(t *testing.T) {
+	var from = time.Date(2026, time.January, 15, 10, 20, 30, 0, time.UTC)
+	reboot, err := Parse("@reboot")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing @reboot: %v", err)
+	}
+	if !reboot.Reboot || !reboot.Next(from).IsZero() || reboot.Matches(from) {
+		t.Errorf("Reboot schedule must fire only at start: %+v", reboot)
+	}
+	never, err := Parse("0 0 30 feb *")
+	if err != nil {
+		t.Fatalf("Unexpected error parsing 30th of February: %v", err)
+	}
+	if next := never.Next(from); !next.IsZero() {
+		t.Errorf("30th of February must never fire, found %v", next)
+	}
+}
+
+func TestNextInTimeZones(t *testing.T) {
+	var from = time.Date(2026, time.January, 15, 10, 20, 30, 0, time.UTC)
+	var tests = []struct {
+		location string
+		expected time.Time
+	}{
+		{"UTC", time.Date(2026, time.January, 16, 9, 0, 0, 0, time.UTC)},
+		{"Europe/Rome", time.Date(2026, time.January, 16, 8, 0, 0, 0, time.UTC)},
+		{"America/New_York", time.Date(2026, time.January, 15, 14, 0, 0, 0, time.UTC)},
+		{"Asia/Tokyo", time.Date(2026, time.January, 16, 0, 0, 0, 0, time.UTC)},
+		{"Asia/Kolkata", time.Date(2026, time.January, 16, 3, 30, 0, 0, time.UTC)},
+	}
+	sched, err := Parse("0 9 * * *")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, test := range tests {
+		t.Run(test.location, func(t *testing.T) {
+			var loc = mustLocation(t, test.location)
+			var next = sched.Next(from.In(loc))
+			if !next.Equal(test.expected) {
+				t.Errorf("Expected %v, found %v", test.expected, next)
+			}
+			if next.Location() != loc || next.Hour() != 9 {
+				t.Errorf("Expected 09:00 in %s, found %v", test.location, next)
+			}
+		})
+	}
+}
+
+func TestDaylightSavingPolicy(t *testing.T) {
+	var loc = mustLocation(t, "America/New_York")
+	// 2026-03-08 02:00 EST clocks move forward to 03:00 EDT, 2026-11-01 02:00 EDT clocks move back to 01:00 EST
+	var tests = []struct {
+		name       string
+		expression string
+		from       time.Time
+		expected   []time.Time
+	}{
+		{"skipped time fires after the gap", "30 2 * * *", time.Date(2026, time.March, 7, 12, 0, 0, 0, loc), []time.Time{
+			time.Date(2026, time.March, 8, 7, 0, 0, 0, time.UTC),
+			time.Date(2026, time.March, 9, 6, 30, 0, 0, time.UTC),
+		}},
+		{"time after the gap fires once", "0 3 * * *", time.Date(2026, time.March, 7, 12, 0, 0, 0, loc), []time.Time{
+			time.Date(2026, time.March, 8, 7, 0, 0, 0, time.UTC),
+			time.Date(2026, time.March, 9, 7, 0, 0, 0, time.UTC),
+		}},
+		{"repeated time fires at first occurrence", "30 1 * * *", time.Date(2026, time.October, 31, 12, 0, 0, 0, loc), []time.Time{
+			time.Date(2026, time.November, 1, 5, 30, 0, 0, time.UTC),
+			time.Date(2026, time.November, 2, 6, 30, 0, 0, time.UTC),
+		}},
+		{"wildcard hour fires at each occurrence", "*/30 * * * *", time.Date(2026, time.November, 1, 0, 45, 0, 0, loc), []time.Time{
+			time.Date(2026, time.November, 1, 5, 0, 0, 0, time.UTC),
+			time.Date(2026, time.November, 1, 5, 30, 0, 0, time.UTC),
+			time.Date(2026, time.November, 1, 6, 0, 0, 0, time.UTC),
+			time.Date(2026, time.November, 1, 6, 30, 0, 0, time.UTC),
+			time.Date(2026, time.November, 1, 7, 0, 0, 0, time.UTC),
+		}},
+		{"wildcard hour skips the gap", "*/30 * * * *", time.Date(2026, time.March, 8, 1, 15, 0, 0, loc), []time.Time{
+			time.Date(2026, time.March, 8, 6, 30, 0, 0, time.UTC),
+			time.Date(2026, time.March, 8, 7, 0, 0, 0, time.UTC),
+			time.Date(2026, time.March, 8, 7, 30, 0, 0, time.UTC),
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			sched, err := Parse(test.expression)
+			if err != nil {
+				t.Fatalf("Unexpected error parsing '%s': %v", test.expression, err)
+			}
+			var found = sched.NextN(test.from, len(test.expected))
+			if len(found) != len(test.expected) {
+				t.Fatalf("Expression '%s': expected %v, found %v", test.expression, test.expected, found)
+			}
+			for idx := range found {
+				if !found[idx].Equal(test.expected[idx]) {
+					t.Errorf("Expression '%s' fire %d: expected %v, found %v", test.expression, idx, test.expected[idx], found[idx].UTC())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,164 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"os"
+	"path/filepath"
+)
+
+// Stores the scheduler configuration in a file, in the configured encoding, and the command configs, running
+// tasks, history and delivery log in native files in the configuration file folder. Files are replaced
+// atomically, keeping the previous version as backup to recover the corrupted ones. When the running tasks,
+// history or delivery log file has no valid backup, it's moved aside and the data start again empty
+type FileStore struct {
+	file string
+	dir  string
+	enc  io.Encoding
+}
+
+// Creates the file store of the given configuration file, and its folder when missing
+func NewFileStore(file string, encoding io.Encoding) *FileStore {
+	var dir, _ = filepath.Split(file)
+	if dir != "" && !io.FileExists(dir) {
+		_ = io.CreateFolder(dir, io.FolderMode)
+	}
+	return &FileStore{file: file, dir: dir, enc: encoding}
+}
+
+func (f *FileStore) Location() string {
+	return f.file
+}
+
+func (f *FileStore) nativeFile(name string) string {
+	return fmt.Sprintf("%s%c%s.gob", f.dir, os.PathSeparator, name)
+}
+
+func (f *FileStore) LoadConfig() (model.SchedulerConfig, error) {
+	var config = model.SchedulerConfig{}
+	var err = io.ReadConfig(f.enc, f.file, &config)
+	return config, err
+}
+
+func (f *FileStore) SaveConfig(config model.SchedulerConfig) error {
+	return io.SaveConfig(f.enc, f.file, config)
+}
+
+func (f *FileStore) LoadItem(id string) (*model.CommandConfig, error) {
+	var file = f.nativeFile(id)
+	var config *model.CommandConfig
+	var err = io.ReadNative(file, &config)
+	var corrupted *io.CorruptedError
+	if errors.As(err, &corrupted) {
+		config, err = migrateItem(file, err)
+	}
+	return config, err
+}
+
+func (f *FileStore) SaveItem(id string, config model.CommandConfig) error {
+	return io.SaveNative(f.nativeFile(id), &config)
+}
+
+func (f *FileStore) DeleteItem(id string) error {
+	var file = f.nativeFile(id)
+	_ = io.DeleteFile(io.BackupFile(file))
+	return io.DeleteFile(file)
+}
+
+func (f *FileStore) LoadExecutions() ([]model.Execution, error) {
+	var file = f.nativeFile("executions")
+	var config = make([]model.Execution, 0)
+	if !io.FileExists(file) {
+		// No task has been executed yet
+		return config, nil
+	}
+	var err = io.ReadNative(file, &config)
+	var corrupted *io.CorruptedError
+	if errors.As(err, &corrupted) {
+		config, err = migrateExecutions(file, err)
+		if err != nil {
+			config, err = make([]model.Execution, 0), io.ResetCorrupted(corrupted)
+		}
+	}
+	return config, err
+}
+
+func (f *FileStore) SaveExecutions(executions []model.Execution) error {
+	return io.SaveNative(f.nativeFile("executions"), executions)
+}
+
+func (f *FileStore) LoadHistory() ([]model.RunRecord, error) {
+	var file = f.nativeFile("history")
+	var records = make([]model.RunRecord, 0)
+	if !io.FileExists(file) {
+		// No task has been executed yet
+		return records, nil
+	}
+	var err = io.ReadNative(file, &records)
+	var corrupted *io.CorruptedError
+	if errors.As(err, &corrupted) {
+		records, err = make([]model.RunRecord, 0), io.ResetCorrupted(corrupted)
+	}
+	return records, err
+}
+
+func (f *FileStore) SaveHistory(records []model.RunRecord) error {
+	return io.SaveNative(f.nativeFile("history"), records)
+}
+
+func (f *FileStore) LoadDeliveries() ([]model.WebhookDelivery, error) {
+	var file = f.nativeFile("deliveries")
+	var records = make([]model.WebhookDelivery, 0)
+	if !io.FileExists(file) {
+		// No notification has been sent yet
+		return records, nil
+	}
+	var err = io.ReadNative(file, &records)
+	var corrupted *io.CorruptedError
+	if errors.As(err, &corrupted) {
+		records, err = make([]model.WebhookDelivery, 0), io.ResetCorrupted(corrupted)
+	}
+	return records, err
+}
+
+func (f *FileStore) SaveDeliveries(deliveries []model.WebhookDelivery) error {
+	return io.SaveNative(f.nativeFile("deliveries"), deliveries)
+}
+
+func (f *FileStore) Close() error {
+	return nil
+}
+
+// Reads a command config file stored before the typed command specs and rewrites it in the current format.
+// When the file is not a legacy one, the given read error is returned
+func migrateItem(file string, cause error) (*model.CommandConfig, error) {
+	var legacy *model.LegacyCommandConfig
+	if err := io.ReadNative(file, &legacy); err != nil || legacy == nil {
+		return nil, cause
+	}
+	config, err := legacy.Migrate()
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unable to migrate command config file %s: %v", file, err))
+	}
+	return &config, io.SaveNative(file, &config)
+}
+
+// Reads a running tasks file stored before the typed command specs and rewrites it in the current format.
+// When the file is not a legacy one, the given read error is returned
+func migrateExecutions(file string, cause error) ([]model.Execution, error) {
+	var legacy = make([]model.LegacyExecution, 0)
+	if err := io.ReadNative(file, &legacy); err != nil {
+		return nil, cause
+	}
+	var config = make([]model.Execution, 0)
+	for _, l := range legacy {
+		execution, err := l.Migrate()
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("Unable to migrate running task id: %s, error: %v", l.UUID, err))
+		}
+		config = append(config, execution)
+	}
+	return config, io.SaveNative(file, config)
+}
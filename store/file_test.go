@@ -0,0 +1,157 @@
+package store
+
+import (
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const (
+	legacyShellId     = "11111111-1111-1111-1111-111111111111"
+	legacyExecId      = "22222222-2222-2222-2222-222222222222"
+	legacyCompositeId = "33333333-3333-3333-3333-333333333333"
+)
+
+// Copies the native files stored before the typed command specs in a new scheduler folder
+func legacyFileStore(t *testing.T) (*FileStore, string) {
+	var dir = t.TempDir()
+	files, err := filepath.Glob(filepath.Join("testdata", "legacy", "*.gob"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("Unable to find the legacy fixtures: %v", err)
+	}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("Unable to read fixture %s: %v", file, err)
+		}
+		if err = os.WriteFile(filepath.Join(dir, filepath.Base(file)), data, 0600); err != nil {
+			t.Fatalf("Unable to copy fixture %s: %v", file, err)
+		}
+	}
+	return NewFileStore(filepath.Join(dir, "config.json"), io.EncodingJson), dir
+}
+
+func legacyCommands() map[string]model.CommandSpec {
+	return map[string]model.CommandSpec{
+		legacyShellId: model.NewShellCommand("tar -czf /tmp/backup.tgz /data"),
+		legacyExecId:  model.NewExecCommand("/usr/bin/report", "--daily"),
+		legacyCompositeId: model.NewCompositeCommand(model.CompositeCommand{
+			Mode: model.CompositeParallel,
+			Steps: []model.CommandSpec{
+				model.NewShellCommand("echo first"),
+				model.NewExecCommand("echo", "second"),
+				model.NewCompositeCommand(model.CompositeCommand{Steps: []model.CommandSpec{model.NewShellCommand("echo nested")}}),
+			},
+		}),
+	}
+}
+
+func TestMigrateLegacyItem(t *testing.T) {
+	var fs, dir = legacyFileStore(t)
+	var cases = []struct {
+		id       string
+		name     string
+		schedule string
+		period   string
+		onDemand bool
+	}{
+		{legacyShellId, "backup", "", "1h", false},
+		{legacyExecId, "report", "0 6 * * *", "", false},
+		{legacyCompositeId, "pipeline", "", "", true},
+	}
+	var commands = legacyCommands()
+	for _, c := range cases {
+		cmd, err := fs.LoadItem(c.id)
+		if err != nil {
+			t.Errorf("%s: unable to migrate the legacy item: %v", c.name, err)
+			continue
+		}
+		if cmd.Name != c.name || cmd.Schedule != c.schedule || cmd.Period != c.period || cmd.OnDemand != c.onDemand {
+			t.Errorf("%s: unexpected migrated config: %+v", c.name, *cmd)
+		}
+		if !reflect.DeepEqual(cmd.Command, commands[c.id]) {
+			t.Errorf("%s: expected command %+v, found %+v", c.name, commands[c.id], cmd.Command)
+		}
+		if err = cmd.Command.Validate(); err != nil {
+			t.Errorf("%s: migrated command is not valid: %v", c.name, err)
+		}
+		// The item file has been rewritten in the current format
+		var saved *model.CommandConfig
+		if err = io.ReadNative(filepath.Join(dir, c.id+".gob"), &saved); err != nil || saved == nil {
+			t.Errorf("%s: migrated item file not readable in the current format: %v", c.name, err)
+		} else if !reflect.DeepEqual(*saved, *cmd) {
+			t.Errorf("%s: expected saved config %+v, found %+v", c.name, *cmd, *saved)
+		}
+	}
+	if shell, err := fs.LoadItem(legacyShellId); err != nil || shell.Timeout != "5m" || shell.Since.IsZero() {
+		t.Errorf("Unexpected reload of the migrated item: %+v, error: %v", shell, err)
+	}
+}
+
+func TestMigrateLegacyExecutions(t *testing.T) {
+	var fs, dir = legacyFileStore(t)
+	executions, err := fs.LoadExecutions()
+	if err != nil {
+		t.Fatalf("Unable to migrate the legacy running tasks: %v", err)
+	}
+	var expected = []struct {
+		id        string
+		times     int
+		triggered int
+		scheduled bool
+	}{
+		{legacyShellId, 2, 0, true},
+		{legacyExecId, 7, 1, false},
+		{legacyCompositeId, 0, 3, false},
+	}
+	if len(executions) != len(expected) {
+		t.Fatalf("Expected %d running tasks, found %d", len(expected), len(executions))
+	}
+	var commands = legacyCommands()
+	for idx, e := range expected {
+		var exec = executions[idx]
+		if exec.UUID != e.id || exec.Times != e.times || exec.Triggered != e.triggered || exec.Scheduled != e.scheduled {
+			t.Errorf("Running task %d: unexpected migrated state: %+v", idx, exec)
+		}
+		if !reflect.DeepEqual(exec.Command.Command, commands[e.id]) {
+			t.Errorf("Running task %d: expected command %+v, found %+v", idx, commands[e.id], exec.Command.Command)
+		}
+	}
+	if executions[0].Last.IsZero() || executions[0].Next.IsZero() || executions[1].LastTriggered.IsZero() {
+		t.Errorf("Migrated running tasks lost their times: %+v", executions)
+	}
+	var saved = make([]model.Execution, 0)
+	if err = io.ReadNative(filepath.Join(dir, "executions.gob"), &saved); err != nil {
+		t.Fatalf("Migrated running tasks file not readable in the current format: %v", err)
+	}
+	if !reflect.DeepEqual(saved, executions) {
+		t.Errorf("Expected saved running tasks %+v, found %+v", executions, saved)
+	}
+}
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	var fs = NewFileStore(filepath.Join(t.TempDir(), "config.yaml"), io.EncodingYaml)
+	var commands = legacyCommands()
+	for id, command := range commands {
+		var cmd = model.CommandConfig{Name: id[:8], Period: "1m", Command: command}
+		if err := fs.SaveItem(id, cmd); err != nil {
+			t.Fatalf("Unable to save item %s: %v", id, err)
+		}
+		loaded, err := fs.LoadItem(id)
+		if err != nil {
+			t.Fatalf("Unable to load item %s: %v", id, err)
+		}
+		if !reflect.DeepEqual(*loaded, cmd) {
+			t.Errorf("Item %s: expected %+v, found %+v", id, cmd, *loaded)
+		}
+	}
+	if err := fs.DeleteItem(legacyShellId); err != nil {
+		t.Fatalf("Unable to delete item: %v", err)
+	}
+	if _, err := fs.LoadItem(legacyShellId); err == nil {
+		t.Errorf("Expected error loading a deleted item")
+	}
+}
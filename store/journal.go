@@ -0,0 +1,249 @@
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/io"
+	"hash/crc32"
+	goio "io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Minimum journal size in bytes before it's compacted
+const JournalCompactSize = 1024 * 1024
+
+// Stores the scheduler data in a single append only journal file. Each save appends an entry with the new
+// value, the journal is compacted when the replaced values take most of its size. Entries are checksummed, so
+// the incomplete entry left by a crash is discarded when the journal is opened. Processes sharing the journal
+// must hold the journal folder lock, see io.LockFolder
+type JournalStore struct {
+	keyValueStore
+}
+
+type journalValues struct {
+	sync.Mutex
+	path   string
+	file   *os.File
+	info   os.FileInfo
+	offset int64
+	live   int64
+	values map[string][]byte
+	// Recovery of the last open, reported by the next read
+	recovered error
+}
+
+// Journal entry, the value of the key or its removal
+type journalEntry struct {
+	Key     string
+	Data    []byte
+	Deleted bool
+}
+
+// Size of the entry frame header: payload length and payload checksum
+const journalHeaderSize = 8
+
+// Opens the journal file, creating it and its folder when missing, and reads the stored values
+func OpenJournalStore(path string) (*JournalStore, error) {
+	if dir, _ := filepath.Split(path); dir != "" && !io.FileExists(dir) {
+		if err := io.CreateFolder(dir, io.FolderMode); err != nil {
+			return nil, err
+		}
+	}
+	var j = &journalValues{path: path}
+	if err := j.open(); err != nil {
+		return nil, err
+	}
+	return &JournalStore{keyValueStore{kv: j}}, nil
+}
+
+// Opens the journal file and reads all the entries, the incomplete or corrupted tail is truncated
+func (j *journalValues) open() error {
+	if j.file != nil {
+		_ = j.file.Close()
+	}
+	file, err := os.OpenFile(j.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, io.FileMode)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	j.file, j.info, j.offset, j.live = file, info, 0, 0
+	j.values = make(map[string][]byte)
+	complete, err := j.read()
+	if err == nil && !complete {
+		err = file.Truncate(j.offset)
+		if err == nil {
+			j.recovered = &io.RecoveredError{File: j.path, Cause: errors.New(fmt.Sprintf("invalid entry at offset %d", j.offset)),
+				Recovery: "truncated after the last valid entry"}
+		}
+	}
+	return err
+}
+
+// Reads the entries from the current offset, and reports if the journal ends with a complete entry
+func (j *journalValues) read() (bool, error) {
+	if _, err := j.file.Seek(j.offset, goio.SeekStart); err != nil {
+		return false, err
+	}
+	var reader = bufio.NewReader(j.file)
+	var header = make([]byte, journalHeaderSize)
+	for {
+		if _, err := goio.ReadFull(reader, header); err == goio.EOF {
+			return true, nil
+		} else if err != nil {
+			return false, nil
+		}
+		var length = binary.BigEndian.Uint32(header[0:4])
+		var payload = make([]byte, length)
+		if _, err := goio.ReadFull(reader, payload); err != nil {
+			return false, nil
+		}
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+			return false, nil
+		}
+		var entry journalEntry
+		if err := io.DecodeGobValue(&entry, payload); err != nil {
+			return false, nil
+		}
+		j.apply(entry)
+		j.offset += int64(journalHeaderSize + len(payload))
+	}
+}
+
+func (j *journalValues) apply(entry journalEntry) {
+	if old, ok := j.values[entry.Key]; ok {
+		j.live -= int64(len(entry.Key) + len(old))
+	}
+	if entry.Deleted {
+		delete(j.values, entry.Key)
+		return
+	}
+	j.values[entry.Key] = entry.Data
+	j.live += int64(len(entry.Key) + len(entry.Data))
+}
+
+// Reads the entries appended by other processes, reopening the journal when it has been compacted
+func (j *journalValues) refresh() error {
+	info, err := os.Stat(j.path)
+	if j.file == nil || err != nil || !os.SameFile(info, j.info) {
+		return j.open()
+	}
+	if info.Size() > j.offset {
+		_, err = j.read()
+	}
+	return err
+}
+
+func frame(entry journalEntry) ([]byte, error) {
+	payload, err := io.EncodeGobValue(entry)
+	if err != nil {
+		return nil, err
+	}
+	var out = make([]byte, journalHeaderSize, journalHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(out[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(out[4:8], crc32.ChecksumIEEE(payload))
+	return append(out, payload...), nil
+}
+
+// Appends the entry to the journal and applies it, then compacts the journal when needed
+func (j *journalValues) append(entry journalEntry) error {
+	if err := j.refresh(); err != nil {
+		return err
+	}
+	data, err := frame(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = j.file.Write(data); err != nil {
+		return err
+	}
+	if err = j.file.Sync(); err != nil {
+		return err
+	}
+	j.apply(entry)
+	j.offset += int64(len(data))
+	if j.offset > JournalCompactSize && j.offset > 4*j.live {
+		return j.compact()
+	}
+	return nil
+}
+
+// Rewrites the journal with the current values only, replacing the journal file when it's complete
+func (j *journalValues) compact() error {
+	var buffer bytes.Buffer
+	for key, value := range j.values {
+		data, err := frame(journalEntry{Key: key, Data: value})
+		if err != nil {
+			return err
+		}
+		buffer.Write(data)
+	}
+	var temp = j.path + ".tmp"
+	file, err := os.OpenFile(temp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, j.info.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(buffer.Bytes())
+	if err == nil {
+		err = file.Sync()
+	}
+	if errC := file.Close(); err == nil {
+		err = errC
+	}
+	if err == nil {
+		err = os.Rename(temp, j.path)
+	}
+	if err != nil {
+		_ = os.Remove(temp)
+		return errors.New(fmt.Sprintf("Unable to compact journal %s: %v", j.path, err))
+	}
+	io.SyncFolder(filepath.Dir(j.path))
+	return j.open()
+}
+
+func (j *journalValues) get(key string) ([]byte, bool, error) {
+	j.Lock()
+	defer j.Unlock()
+	if err := j.refresh(); err != nil {
+		return nil, false, err
+	}
+	data, ok := j.values[key]
+	var err = j.recovered
+	j.recovered = nil
+	return data, ok, err
+}
+
+func (j *journalValues) put(key string, data []byte) error {
+	j.Lock()
+	defer j.Unlock()
+	return j.append(journalEntry{Key: key, Data: data})
+}
+
+func (j *journalValues) remove(key string) error {
+	j.Lock()
+	defer j.Unlock()
+	return j.append(journalEntry{Key: key, Deleted: true})
+}
+
+func (j *journalValues) location() string {
+	return j.path
+}
+
+func (j *journalValues) close() error {
+	j.Lock()
+	defer j.Unlock()
+	if j.file == nil {
+		return nil
+	}
+	var err = j.file.Close()
+	j.file = nil
+	return err
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"errors"
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func openJournal(t *testing.T, path string) *JournalStore {
+	s, err := OpenJournalStore(path)
+	if err != nil {
+		t.Fatalf("Unable to open the journal: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+	return s
+}
+
+func journalOf(s *JournalStore) *journalValues {
+	return s.kv.(*journalValues)
+}
+
+func saveTask(t *testing.T, s Store, id string, name string) {
+	if err := s.SaveItem(id, model.CommandConfig{Name: name, Command: model.NewShellCommand("echo " + name)}); err != nil {
+		t.Fatalf("Unable to save task %s: %v", id, err)
+	}
+}
+
+func expectTask(t *testing.T, s Store, id string, name string) error {
+	cmd, err := s.LoadItem(id)
+	var recovered *io.RecoveredError
+	if err != nil && !errors.As(err, &recovered) {
+		t.Errorf("Unable to load task %s: %v", id, err)
+	} else if cmd == nil || cmd.Name != name {
+		t.Errorf("Expected task %s named %s, found %+v", id, name, cmd)
+	}
+	return err
+}
+
+func TestJournalStore(t *testing.T) {
+	var dir = t.TempDir()
+	testEmptyStore(t, openJournal(t, filepath.Join(dir, "empty.journal")))
+	testStoreRoundTrip(t, openJournal(t, filepath.Join(dir, "data", "scheduler.journal")))
+	// Values are read again from the journal file
+	var s = openJournal(t, filepath.Join(dir, "data", "scheduler.journal"))
+	if config, err := s.LoadConfig(); err != nil || config.TimeZone != "Europe/Rome" {
+		t.Errorf("Expected reopened configuration, found %+v, error: %v", config, err)
+	}
+	if _, err := s.LoadItem("a"); err == nil {
+		t.Errorf("Expected deleted item to stay deleted after reopening the journal")
+	}
+	if out, err := s.LoadHistory(); err != nil || len(out) != 1 {
+		t.Errorf("Expected reopened history, found %+v, error: %v", out, err)
+	}
+}
+
+func TestJournalCorruptedTail(t *testing.T) {
+	var cases = []struct {
+		name    string
+		corrupt func(data []byte, valid int) []byte
+	}{
+		{"torn header", func(data []byte, valid int) []byte {
+			return data[:valid+3]
+		}},
+		{"torn payload", func(data []byte, valid int) []byte {
+			return data[:len(data)-1]
+		}},
+		{"checksum mismatch", func(data []byte, valid int) []byte {
+			data[len(data)-1] ^= 0xff
+			return data
+		}},
+	}
+	for _, c := range cases {
+		var path = filepath.Join(t.TempDir(), "scheduler.journal")
+		var s = openJournal(t, path)
+		saveTask(t, s, "a", "first")
+		var valid = int(journalOf(s).offset)
+		saveTask(t, s, "a", "second")
+		_ = s.Close()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("%s: unable to read the journal: %v", c.name, err)
+		}
+		if err = os.WriteFile(path, c.corrupt(data, valid), 0600); err != nil {
+			t.Fatalf("%s: unable to write the journal: %v", c.name, err)
+		}
+
+		s = openJournal(t, path)
+		var recovered *io.RecoveredError
+		if err = expectTask(t, s, "a", "first"); !errors.As(err, &recovered) {
+			t.Errorf("%s: expected recovered error, found: %v", c.name, err)
+		}
+		// The recovery is reported once
+		if err = expectTask(t, s, "a", "first"); err != nil {
+			t.Errorf("%s: unexpected error after the recovery: %v", c.name, err)
+		}
+		if info, err := os.Stat(path); err != nil {
+			t.Errorf("%s: unable to read the journal size: %v", c.name, err)
+		} else if info.Size() != int64(valid) {
+			t.Errorf("%s: expected journal truncated at %d bytes, found: %d", c.name, valid, info.Size())
+		}
+		// New entries follow the last valid one
+		saveTask(t, s, "b", "third")
+		_ = s.Close()
+		s = openJournal(t, path)
+		if err = expectTask(t, s, "a", "first"); err != nil {
+			t.Errorf("%s: unexpected error reopening the journal: %v", c.name, err)
+		}
+		_ = expectTask(t, s, "b", "third")
+	}
+}
+
+func TestJournalCompaction(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "scheduler.journal")
+	var s = openJournal(t, path)
+	for _, name := range []string{"first", "second", "third", "fourth"} {
+		saveTask(t, s, "a", name)
+		saveTask(t, s, "b", name)
+	}
+	saveTask(t, s, "c", "deleted")
+	if err := s.DeleteItem("c"); err != nil {
+		t.Fatalf("Unable to delete the item: %v", err)
+	}
+	var j = journalOf(s)
+	var before = j.offset
+	j.Lock()
+	var err = j.compact()
+	j.Unlock()
+	if err != nil {
+		t.Fatalf("Unable to compact the journal: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Unable to read the journal size: %v", err)
+	}
+	if info.Size() >= before || info.Size() != j.offset {
+		t.Errorf("Expected compacted journal smaller than %d bytes, found: %d, offset: %d", before, info.Size(), j.offset)
+	}
+	if _, err = os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary compaction file, found: %v", err)
+	}
+	_ = expectTask(t, s, "a", "fourth")
+	saveTask(t, s, "d", "after")
+	_ = s.Close()
+
+	s = openJournal(t, path)
+	_ = expectTask(t, s, "a", "fourth")
+	_ = expectTask(t, s, "b", "fourth")
+	_ = expectTask(t, s, "d", "after")
+	if _, err = s.LoadItem("c"); err == nil {
+		t.Errorf("Expected deleted item to stay deleted after the compaction")
+	}
+}
+
+func TestJournalRefresh(t *testing.T) {
+	var path = filepath.Join(t.TempDir(), "scheduler.journal")
+	var writer = openJournal(t, path)
+	var reader = openJournal(t, path)
+	saveTask(t, writer, "a", "first")
+	_ = expectTask(t, reader, "a", "first")
+	saveTask(t, writer, "a", "second")
+	if err := writer.DeleteItem("missing"); err != nil {
+		t.Fatalf("Unable to delete the item: %v", err)
+	}
+	_ = expectTask(t, reader, "a", "second")
+	// Entries appended by the reader are seen by the writer
+	saveTask(t, reader, "b", "third")
+	_ = expectTask(t, writer, "b", "third")
+
+	// The compacted journal is a new file, opened again by the other handle
+	var j = journalOf(writer)
+	j.Lock()
+	var err = j.compact()
+	j.Unlock()
+	if err != nil {
+		t.Fatalf("Unable to compact the journal: %v", err)
+	}
+	saveTask(t, writer, "c", "fourth")
+	_ = expectTask(t, reader, "c", "fourth")
+	_ = expectTask(t, reader, "a", "second")
+	if info, err := os.Stat(path); err != nil || journalOf(reader).offset != info.Size() {
+		t.Errorf("Expected reader at the journal end, found offset %d, error: %v", journalOf(reader).offset, err)
+	}
+}
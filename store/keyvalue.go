@@ -0,0 +1,136 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"strings"
+)
+
+// Keys of the values of the key-value stores
+const (
+	keyConfig     = "config"
+	keyExecutions = "executions"
+	keyHistory    = "history"
+	keyDeliveries = "deliveries"
+	keyItemPrefix = "item/"
+)
+
+// Key-value storage of encoded values
+type keyValue interface {
+	// Retrieves the value of the key, false when missing. An io.RecoveredError comes with a valid value
+	get(key string) ([]byte, bool, error)
+	put(key string, data []byte) error
+	remove(key string) error
+	location() string
+	close() error
+}
+
+// Implements the store over a key-value storage. Values are encoded as in the file store: the configuration
+// in JSON, so the task references keep the disabled flag, and the other values in native format
+type keyValueStore struct {
+	kv keyValue
+}
+
+func (s *keyValueStore) Location() string {
+	return s.kv.location()
+}
+
+func (s *keyValueStore) load(key string, out interface{}) (bool, error) {
+	data, found, err := s.kv.get(key)
+	var recovered *io.RecoveredError
+	if (err != nil && !errors.As(err, &recovered)) || !found {
+		return found, err
+	}
+	var errD error
+	if key == keyConfig {
+		errD = io.DecodeValue(out, data, io.EncodingJson)
+	} else {
+		errD = io.DecodeGobValue(out, data)
+	}
+	if errD != nil {
+		return true, errD
+	}
+	return true, err
+}
+
+func (s *keyValueStore) save(key string, value interface{}) error {
+	var data []byte
+	var err error
+	if key == keyConfig {
+		data, err = io.EncodeValue(value, io.EncodingJson)
+	} else {
+		data, err = io.EncodeGobValue(value)
+	}
+	if err != nil {
+		return err
+	}
+	return s.kv.put(key, data)
+}
+
+func (s *keyValueStore) LoadConfig() (model.SchedulerConfig, error) {
+	var config = model.SchedulerConfig{}
+	// A new store holds an empty scheduler
+	_, err := s.load(keyConfig, &config)
+	return config, err
+}
+
+func (s *keyValueStore) SaveConfig(config model.SchedulerConfig) error {
+	return s.save(keyConfig, config)
+}
+
+func (s *keyValueStore) LoadItem(id string) (*model.CommandConfig, error) {
+	var config *model.CommandConfig
+	found, err := s.load(keyItemPrefix+id, &config)
+	var recovered *io.RecoveredError
+	if !found && (err == nil || errors.As(err, &recovered)) {
+		err = errors.New(fmt.Sprintf("No command config found for task id: %s", id))
+	}
+	return config, err
+}
+
+func (s *keyValueStore) SaveItem(id string, config model.CommandConfig) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("Task id must not be empty")
+	}
+	return s.save(keyItemPrefix+id, &config)
+}
+
+func (s *keyValueStore) DeleteItem(id string) error {
+	return s.kv.remove(keyItemPrefix + id)
+}
+
+func (s *keyValueStore) LoadExecutions() ([]model.Execution, error) {
+	var config = make([]model.Execution, 0)
+	_, err := s.load(keyExecutions, &config)
+	return config, err
+}
+
+func (s *keyValueStore) SaveExecutions(executions []model.Execution) error {
+	return s.save(keyExecutions, executions)
+}
+
+func (s *keyValueStore) LoadHistory() ([]model.RunRecord, error) {
+	var records = make([]model.RunRecord, 0)
+	_, err := s.load(keyHistory, &records)
+	return records, err
+}
+
+func (s *keyValueStore) SaveHistory(records []model.RunRecord) error {
+	return s.save(keyHistory, records)
+}
+
+func (s *keyValueStore) LoadDeliveries() ([]model.WebhookDelivery, error) {
+	var records = make([]model.WebhookDelivery, 0)
+	_, err := s.load(keyDeliveries, &records)
+	return records, err
+}
+
+func (s *keyValueStore) SaveDeliveries(deliveries []model.WebhookDelivery) error {
+	return s.save(keyDeliveries, deliveries)
+}
+
+func (s *keyValueStore) Close() error {
+	return s.kv.close()
+}
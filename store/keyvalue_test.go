@@ -0,0 +1,150 @@
+package store
+
+import (
+	"github.com/hellgate75/go-cron/model"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Saves and loads all the scheduler data in the store
+func testStoreRoundTrip(t *testing.T, s Store) {
+	var disabled = false
+	var config = model.SchedulerConfig{
+		TimeZone:   "Europe/Rome",
+		MaxRunning: 2,
+		Paused:     true,
+		Commands: []model.CommandConfigRef{
+			{UUID: "a", Name: "first", Enabled: &disabled, Command: model.NewShellCommand("ls -l")},
+		},
+	}
+	if err := s.SaveConfig(config); err != nil {
+		t.Fatalf("Unable to save the configuration: %v", err)
+	}
+	loaded, err := s.LoadConfig()
+	if err != nil {
+		t.Fatalf("Unable to load the configuration: %v", err)
+	}
+	if loaded.TimeZone != config.TimeZone || loaded.MaxRunning != 2 || !loaded.Paused || len(loaded.Commands) != 1 {
+		t.Errorf("Unexpected loaded configuration: %+v", loaded)
+	} else if loaded.Commands[0].Enabled == nil || *loaded.Commands[0].Enabled {
+		t.Errorf("Expected the disabled flag of the task reference, found: %v", loaded.Commands[0].Enabled)
+	}
+
+	var cmd = model.CommandConfig{Name: "first", Period: "1m", Command: model.NewExecCommand("ls", "-l")}
+	if err = s.SaveItem("a", cmd); err != nil {
+		t.Fatalf("Unable to save the item: %v", err)
+	}
+	if err = s.SaveItem(" ", cmd); err == nil {
+		t.Errorf("Expected error saving an item without id")
+	}
+	item, err := s.LoadItem("a")
+	if err != nil || !reflect.DeepEqual(*item, cmd) {
+		t.Errorf("Expected item %+v, found %+v, error: %v", cmd, item, err)
+	}
+	if _, err = s.LoadItem("missing"); err == nil || !strings.Contains(err.Error(), "No command config found") {
+		t.Errorf("Expected not found error, found: %v", err)
+	}
+	if err = s.DeleteItem("a"); err != nil {
+		t.Fatalf("Unable to delete the item: %v", err)
+	}
+	if _, err = s.LoadItem("a"); err == nil {
+		t.Errorf("Expected not found error loading a deleted item")
+	}
+
+	var now = time.Now().Round(0)
+	var executions = []model.Execution{{UUID: "a", Command: cmd, Times: 3, Last: now}}
+	if err = s.SaveExecutions(executions); err != nil {
+		t.Fatalf("Unable to save the running tasks: %v", err)
+	}
+	if out, err := s.LoadExecutions(); err != nil || !reflect.DeepEqual(out, executions) {
+		t.Errorf("Expected running tasks %+v, found %+v, error: %v", executions, out, err)
+	}
+	var records = []model.RunRecord{{RunID: "r1", UUID: "a", Outcome: model.OutcomeSuccess, Start: now}}
+	if err = s.SaveHistory(records); err != nil {
+		t.Fatalf("Unable to save the history: %v", err)
+	}
+	if out, err := s.LoadHistory(); err != nil || !reflect.DeepEqual(out, records) {
+		t.Errorf("Expected history %+v, found %+v, error: %v", records, out, err)
+	}
+	var deliveries = []model.WebhookDelivery{{Time: now, Webhook: "hook", UUID: "a", RunID: "r1"}}
+	if err = s.SaveDeliveries(deliveries); err != nil {
+		t.Fatalf("Unable to save the delivery log: %v", err)
+	}
+	if out, err := s.LoadDeliveries(); err != nil || !reflect.DeepEqual(out, deliveries) {
+		t.Errorf("Expected delivery log %+v, found %+v, error: %v", deliveries, out, err)
+	}
+}
+
+// Checks the values of a store that has never been saved
+func testEmptyStore(t *testing.T, s Store) {
+	if config, err := s.LoadConfig(); err != nil || len(config.Commands) != 0 {
+		t.Errorf("Expected empty configuration, found %+v, error: %v", config, err)
+	}
+	if out, err := s.LoadExecutions(); err != nil || out == nil || len(out) != 0 {
+		t.Errorf("Expected no running tasks, found %v, error: %v", out, err)
+	}
+	if out, err := s.LoadHistory(); err != nil || out == nil || len(out) != 0 {
+		t.Errorf("Expected no history, found %v, error: %v", out, err)
+	}
+	if out, err := s.LoadDeliveries(); err != nil || out == nil || len(out) != 0 {
+		t.Errorf("Expected no delivery log, found %v, error: %v", out, err)
+	}
+	if _, err := s.LoadItem("a"); err == nil || !strings.Contains(err.Error(), "No command config found for task id: a") {
+		t.Errorf("Expected not found error, found: %v", err)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testEmptyStore(t, NewMemoryStore())
+	testStoreRoundTrip(t, NewMemoryStore())
+}
+
+func TestMemoryStoreCopiesValues(t *testing.T) {
+	var s = NewMemoryStore()
+	var cmd = model.CommandConfig{Name: "first", Command: model.NewExecCommand("ls", "-l")}
+	if err := s.SaveItem("a", cmd); err != nil {
+		t.Fatalf("Unable to save the item: %v", err)
+	}
+	cmd.Command.Args[0] = "rm"
+	item, err := s.LoadItem("a")
+	if err != nil || item.Command.Args[0] != "ls" {
+		t.Errorf("Expected the stored item not to share the saved value, found %+v, error: %v", item, err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	var dir = t.TempDir()
+	var cases = []struct {
+		value    string
+		kind     Kind
+		location string
+		error    bool
+	}{
+		{"", KindFile, filepath.Join(dir, "file", "config.json"), false},
+		{" Memory ", KindMemory, "memory", false},
+		{"journal", KindJournal, filepath.Join(dir, "journal", "scheduler.journal"), false},
+		{"sql", "", "", true},
+	}
+	for _, c := range cases {
+		kind, err := KindFromValue(c.value)
+		if c.error != (err != nil) || kind != c.kind {
+			t.Errorf("%q: expected kind %q (error %v), found %q, error: %v", c.value, c.kind, c.error, kind, err)
+			continue
+		}
+		if c.error {
+			continue
+		}
+		s, err := Open(kind, c.location, "json")
+		if err != nil {
+			t.Errorf("%q: unable to open the store: %v", c.value, err)
+			continue
+		}
+		if s.Location() != c.location {
+			t.Errorf("%q: expected location %s, found %s", c.value, c.location, s.Location())
+		}
+		_ = s.Close()
+	}
+}
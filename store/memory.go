@@ -0,0 +1,50 @@
+package store
+
+import (
+	"sync"
+)
+
+// Stores the scheduler data in the process memory, for tests and for schedulers embedded in processes that
+// don't need to keep them. Values are stored encoded, so the scheduler and the store never share them
+type MemoryStore struct {
+	keyValueStore
+}
+
+type memoryValues struct {
+	sync.Mutex
+	values map[string][]byte
+}
+
+// Creates an empty memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{keyValueStore{kv: &memoryValues{values: make(map[string][]byte)}}}
+}
+
+func (m *memoryValues) get(key string) ([]byte, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	data, ok := m.values[key]
+	return data, ok, nil
+}
+
+func (m *memoryValues) put(key string, data []byte) error {
+	m.Lock()
+	defer m.Unlock()
+	m.values[key] = data
+	return nil
+}
+
+func (m *memoryValues) remove(key string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+func (m *memoryValues) location() string {
+	return "memory"
+}
+
+func (m *memoryValues) close() error {
+	return nil
+}
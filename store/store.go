@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"github.com/hellgate75/go-cron/io"
+	"github.com/hellgate75/go-cron/model"
+	"strings"
+)
+
+// Describes the scheduler persistence: configuration, command configs, running tasks, run history and
+// webhook delivery log. Missing running tasks, history and delivery log are loaded as empty lists. Load
+// methods return an io.RecoveredError, with valid data, when they recover from corrupted data
+type Store interface {
+	// Describes where the data are stored, for messages
+	Location() string
+	// Reads the scheduler configuration. The file store fails when the configuration file is missing, the
+	// other stores return an empty configuration when it has never been saved
+	LoadConfig() (model.SchedulerConfig, error)
+	// Writes the scheduler configuration
+	SaveConfig(config model.SchedulerConfig) error
+	// Reads the command config of the task with given unique identifier
+	LoadItem(id string) (*model.CommandConfig, error)
+	// Writes the command config of the task with given unique identifier
+	SaveItem(id string, config model.CommandConfig) error
+	// Removes the command config of the task with given unique identifier
+	DeleteItem(id string) error
+	// Reads the running tasks state
+	LoadExecutions() ([]model.Execution, error)
+	// Writes the running tasks state
+	SaveExecutions(executions []model.Execution) error
+	// Reads the tasks run history
+	LoadHistory() ([]model.RunRecord, error)
+	// Writes the tasks run history
+	SaveHistory(records []model.RunRecord) error
+	// Reads the webhook delivery log
+	LoadDeliveries() ([]model.WebhookDelivery, error)
+	// Writes the webhook delivery log
+	SaveDeliveries(deliveries []model.WebhookDelivery) error
+	// Releases the store resources
+	Close() error
+}
+
+// Describes the store implementation
+type Kind string
+
+const (
+	// Configuration file and one native file for each task, running tasks, history and delivery log (default)
+	KindFile = Kind("file")
+	// Process memory, data are lost when the process ends
+	KindMemory = Kind("memory")
+	// Single append only journal file
+	KindJournal = Kind("journal")
+)
+
+var KindList = "file, memory, journal"
+
+// Parses the store kind, file when empty
+func KindFromValue(value string) (Kind, error) {
+	switch Kind(strings.TrimSpace(strings.ToLower(value))) {
+	case KindFile, "":
+		return KindFile, nil
+	case KindMemory:
+		return KindMemory, nil
+	case KindJournal:
+		return KindJournal, nil
+	}
+	return "", errors.New(fmt.Sprintf("Unknown store '%s' (available: %s)", value, KindList))
+}
+
+// Opens the store of given kind: the file store uses the path as configuration file, in the given encoding,
+// the journal store as journal file, while the memory store ignores it
+func Open(kind Kind, path string, encoding io.Encoding) (Store, error) {
+	switch kind {
+	case KindFile, "":
+		return NewFileStore(path, encoding), nil
+	case KindMemory:
+		return NewMemoryStore(), nil
+	case KindJournal:
+		return OpenJournalStore(path)
+	}
+	return nil, errors.New(fmt.Sprintf("Unknown store '%s' (available: %s)", kind, KindList))
+}
@@ -44,3 +44,19 @@ func AddMonthsSince(date time.Time, m int) time.Time {
 	return date.AddDate(0, m, 0)
 }
 
+// Loads the time zone location with given IANA name (e.g.: Europe/Rome), empty name is the host local time zone
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// Calculates current time in the given location, nil location is the host local time zone
+func NowIn(loc *time.Location) time.Time {
+	if loc == nil {
+		loc = time.Local
+	}
+	return time.Now().In(loc)
+}
+
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Sends an HTTP request, collecting the response body as standard output. The exit code is 0 for 2xx
+// response status codes, otherwise it's the response status code. When the context is done the request
+// is aborted and the context error is returned.
+func RunHttpRequest(ctx context.Context, method string, url string, header http.Header, body string) (result CommandResult, err error) {
+	result.ExitCode = -1
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	request, err := http.NewRequestWithContext(ctx, method, url, strings.NewReader(body))
+	if err != nil {
+		return result, err
+	}
+	for name, values := range header {
+		for _, value := range values {
+			request.Header.Add(name, value)
+		}
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return result, err
+	}
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+	result.Stdout = string(data)
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return result, err
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		result.ExitCode = response.StatusCode
+		result.Stderr = response.Status
+		return result, errors.New(fmt.Sprintf("HTTP request failed with status: %s", response.Status))
+	}
+	result.ExitCode = 0
+	return result, nil
+}
@@ -1,10 +1,13 @@
 package utils
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
+	"unicode/utf8"
 )
 
 // Execute a set of command in a single string
@@ -15,12 +18,9 @@ func ExecuteCommandString(commandString string) (string, error) {
 			err = errors.New(fmt.Sprintf("%v", r))
 		}
 	}()
-	var tmp = strings.Split(commandString, " ")
-	var command = make([]string, 0)
-	for _, v := range tmp {
-		if len(strings.TrimSpace(v)) > 0 {
-			command = append(command, strings.TrimSpace(v))
-		}
+	command, err := SplitCommandLine(commandString)
+	if err != nil {
+		return "", err
 	}
 	if len(command) == 0 {
 		return "", errors.New("Command must have a least one not empty value")
@@ -89,3 +89,255 @@ func ExecuteCommand(command string, cmdArgs ...string) (string, error) {
 	}
 	return fmt.Sprintf("%s", stdoutStderr), err
 }
+
+// Describes the outcome of an executed command
+type CommandResult struct {
+	// Command standard output
+	Stdout string
+	// Command standard error
+	Stderr string
+	// Process exit code, -1 when the process could not be started or its exit status is unknown
+	ExitCode int
+}
+
+// Describes how a process is started and how its output is collected
+type ProcessOptions struct {
+	// Working directory, empty for the current one
+	Dir string
+	// Process environment as NAME=value entries, nil for the current process environment
+	Env []string
+	// Content sent to the process standard input
+	Stdin string
+	// Maximum number of bytes kept of the standard output, the last ones are kept (0 for no limit)
+	StdoutLimit int
+	// Maximum number of bytes kept of the standard error, the last ones are kept (0 for no limit)
+	StderrLimit int
+	// Exit codes of successful runs, empty for 0 only
+	SuccessExitCodes []int
+	// User name or numeric id the process runs as, empty for the scheduler user
+	User string
+	// Group name or numeric id the process runs as, empty for the user primary group
+	Group string
+	// File mode creation mask, as octal number (e.g.: 027), empty for the scheduler one
+	Umask string
+	// Process niceness, from -20 (highest priority) to 19 (lowest priority), 0 for the scheduler one
+	Nice int
+	// Process resource limits
+	Limits ResourceLimits
+}
+
+// Process resource limits, applied as soft and hard limits, 0 for the scheduler ones
+type ResourceLimits struct {
+	// Maximum CPU time in seconds
+	CPUSeconds uint64
+	// Maximum virtual memory size in bytes
+	AddressSpace uint64
+	// Maximum number of open files
+	OpenFiles uint64
+	// Maximum number of processes of the process user
+	Processes uint64
+}
+
+// Checks if no resource limit has been declared
+func (l ResourceLimits) IsEmpty() bool {
+	return l.CPUSeconds == 0 && l.AddressSpace == 0 && l.OpenFiles == 0 && l.Processes == 0
+}
+
+// Verifies the process options and that the scheduler process has the permissions to apply them
+func (o ProcessOptions) Validate() error {
+	if _, err := o.umask(); err != nil {
+		return err
+	}
+	if o.Nice < -20 || o.Nice > 19 {
+		return errors.New(fmt.Sprintf("Invalid niceness %d, it must be between -20 and 19", o.Nice))
+	}
+	return validateProcess(o)
+}
+
+// Parses the file mode creation mask, -1 when not declared
+func (o ProcessOptions) umask() (int, error) {
+	if strings.TrimSpace(o.Umask) == "" {
+		return -1, nil
+	}
+	value, err := strconv.ParseUint(strings.TrimSpace(o.Umask), 8, 32)
+	if err != nil || value > 0777 {
+		return -1, errors.New(fmt.Sprintf("Invalid umask '%s', it must be an octal number between 000 and 777", o.Umask))
+	}
+	return int(value), nil
+}
+
+// Checks if the exit code is a successful one
+func (o ProcessOptions) Success(exitCode int) bool {
+	if len(o.SuccessExitCodes) == 0 {
+		return exitCode == 0
+	}
+	for _, code := range o.SuccessExitCodes {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Execute a set of command in a single string, collecting standard output, standard error and exit code.
+// When the context is done the whole process group is killed and the context error is returned.
+func RunCommandString(ctx context.Context, commandString string) (CommandResult, error) {
+	command, err := SplitCommandLine(commandString)
+	if err != nil {
+		return CommandResult{ExitCode: -1}, err
+	}
+	return RunCommandArgs(ctx, command...)
+}
+
+// Execute a Command by tokens, collecting standard output, standard error and exit code.
+// When the context is done the whole process group is killed and the context error is returned.
+func RunCommandArgs(ctx context.Context, command ...string) (CommandResult, error) {
+	return RunProcess(ctx, ProcessOptions{}, command...)
+}
+
+// Execute a Command by tokens with given options, collecting standard output, standard error and exit code.
+// When the context is done the whole process group is killed and the context error is returned.
+func RunProcess(ctx context.Context, options ProcessOptions, command ...string) (result CommandResult, err error) {
+	result.ExitCode = -1
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	if len(command) == 0 || len(command[0]) == 0 {
+		return result, errors.New("Command subject must not be empty")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	var stdout, stderr = &tailBuffer{limit: options.StdoutLimit}, &tailBuffer{limit: options.StderrLimit}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	cmd.Dir = options.Dir
+	cmd.Env = options.Env
+	if options.Stdin != "" {
+		cmd.Stdin = strings.NewReader(options.Stdin)
+	}
+	setProcessGroup(cmd)
+	if err = configureProcess(cmd, options); err != nil {
+		return result, err
+	}
+	if err = cmd.Start(); err != nil {
+		return result, err
+	}
+	var done = make(chan error, 1)
+	go func() {
+		done <- cmd.Wait()
+	}()
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		_ = killProcessGroup(cmd)
+		<-done
+		err = ctx.Err()
+	}
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+	}
+	if ctx.Err() == nil && cmd.ProcessState != nil && cmd.ProcessState.Exited() {
+		// Exit codes are checked against the declared successful ones
+		if options.Success(result.ExitCode) {
+			err = nil
+		} else if err == nil {
+			err = errors.New(fmt.Sprintf("Exit status %d is not a success exit code", result.ExitCode))
+		}
+	}
+	return result, err
+}
+
+// Splits a command line in process arguments on spaces. Single quotes keep the text as it is, double quotes
+// keep the spaces and accept the backslash escape of double quotes and backslashes, outside quotes the
+// backslash escapes any character
+func SplitCommandLine(line string) ([]string, error) {
+	var args = make([]string, 0)
+	var current strings.Builder
+	var inArg bool
+	var quote rune
+	var escaped bool
+	for _, r := range line {
+		switch {
+		case escaped:
+			if quote == '"' && r != '"' && r != '\\' {
+				current.WriteRune('\\')
+			}
+			current.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else if r == '\\' {
+				escaped = true
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n' || r == '\r':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, errors.New(fmt.Sprintf("Unterminated %c quote in command line: %s", quote, line))
+	}
+	if escaped {
+		return nil, errors.New(fmt.Sprintf("Unterminated escape at the end of command line: %s", line))
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+	return args, nil
+}
+
+// Output buffer keeping the last bytes written, up to the limit (0 for no limit). It holds the raw output tail,
+// the truncation marker is added by the run records
+type tailBuffer struct {
+	limit		int
+	data		[]byte
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.data = append(b.data, p...)
+	if b.limit > 0 && len(b.data) > 2*b.limit {
+		b.data = []byte(TailString(string(b.data), b.limit))
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	return TailString(string(b.data), b.limit)
+}
+
+// Retrieves the last limit bytes of the text, when limit is greater than zero. The cut is moved forward to the
+// next rune start, so multi-byte characters are never split
+func TailString(text string, limit int) string {
+	if limit <= 0 || len(text) <= limit {
+		return text
+	}
+	var start = len(text) - limit
+	for i := 0; i < utf8.UTFMax-1 && start < len(text) && !utf8.RuneStart(text[start]); i++ {
+		start++
+	}
+	return text[start:]
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"context"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestSplitCommandLine(t *testing.T) {
+	var cases = []struct {
+		line     string
+		expected []string
+		error    bool
+	}{
+		{"", []string{}, false},
+		{"  ", []string{}, false},
+		{"ls -l  /tmp", []string{"ls", "-l", "/tmp"}, false},
+		{"echo\t'a b'\n\"c d\"", []string{"echo", "a b", "c d"}, false},
+		{`echo 'it''s'`, []string{"echo", "its"}, false},
+		{`echo '$HOME \n "x"'`, []string{"echo", `$HOME \n "x"`}, false},
+		{`echo "say \"hi\" \\ \n"`, []string{"echo", `say "hi" \ \n`}, false},
+		{`echo a\ b \'c\' \\`, []string{"echo", "a b", "'c'", `\`}, false},
+		{`echo "" ''`, []string{"echo", "", ""}, false},
+		{`echo pre"fix"'ed'`, []string{"echo", "prefixed"}, false},
+		{`echo "perché €"`, []string{"echo", "perché €"}, false},
+		{`echo 'open`, nil, true},
+		{`echo "open`, nil, true},
+		{`echo \`, nil, true},
+	}
+	for _, c := range cases {
+		args, err := SplitCommandLine(c.line)
+		if c.error != (err != nil) {
+			t.Errorf("%q: expected error %v, found: %v", c.line, c.error, err)
+		} else if !c.error && !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("%q: expected %q, found %q", c.line, c.expected, args)
+		}
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	var cases = []struct {
+		name     string
+		limit    int
+		writes   []string
+		expected string
+	}{
+		{"no limit", 0, []string{"abc", "def"}, "abcdef"},
+		{"under limit", 10, []string{"abc", "def"}, "abcdef"},
+		{"over limit", 4, []string{"abc", "def"}, "cdef"},
+		{"dropped writes", 3, []string{"abcdef", "ghijkl", "mn"}, "lmn"},
+		// é is 2 bytes and € is 3 bytes long, the cut never splits them
+		{"rune boundary", 4, []string{"perch", "é€"}, "€"},
+		{"rune boundary after drop", 2, []string{"aaaa", "aaé"}, "é"},
+	}
+	for _, c := range cases {
+		var b = &tailBuffer{limit: c.limit}
+		for _, w := range c.writes {
+			if n, err := b.Write([]byte(w)); n != len(w) || err != nil {
+				t.Errorf("%s: unexpected write result %d, error: %v", c.name, n, err)
+			}
+		}
+		var out = b.String()
+		if out != c.expected {
+			t.Errorf("%s: expected %q, found %q", c.name, c.expected, out)
+		}
+		if !utf8.ValidString(out) {
+			t.Errorf("%s: invalid UTF-8 %q", c.name, out)
+		}
+	}
+}
+
+func TestSuccessExitCodes(t *testing.T) {
+	var cases = []struct {
+		codes    []int
+		exitCode int
+		success  bool
+	}{
+		{nil, 0, true},
+		{nil, 1, false},
+		{[]int{0, 3}, 3, true},
+		{[]int{0, 3}, 0, true},
+		{[]int{3}, 0, false},
+		{[]int{3}, 2, false},
+	}
+	for _, c := range cases {
+		var options = ProcessOptions{SuccessExitCodes: c.codes}
+		if options.Success(c.exitCode) != c.success {
+			t.Errorf("Codes %v, exit code %d: expected success %v", c.codes, c.exitCode, c.success)
+		}
+	}
+}
+
+func TestRunProcess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Requires a POSIX shell")
+	}
+	var cases = []struct {
+		name     string
+		options  ProcessOptions
+		script   string
+		exitCode int
+		stdout   string
+		stderr   string
+		error    string
+	}{
+		{"success", ProcessOptions{}, "echo out; echo err >&2", 0, "out\n", "err\n", ""},
+		{"failure", ProcessOptions{}, "exit 3", 3, "", "", "exit status 3"},
+		{"declared success", ProcessOptions{SuccessExitCodes: []int{0, 3}}, "exit 3", 3, "", "", ""},
+		{"zero not declared", ProcessOptions{SuccessExitCodes: []int{3}}, "true", 0, "", "", "Exit status 0 is not a success exit code"},
+		{"stdin", ProcessOptions{Stdin: "input"}, "cat", 0, "input", "", ""},
+		{"environment", ProcessOptions{Env: []string{"NAME=value"}}, "printf %s \"$NAME\"", 0, "value", "", ""},
+		{"output limit", ProcessOptions{StdoutLimit: 3}, "printf 'abcdef'", 0, "def", "", ""},
+	}
+	for _, c := range cases {
+		result, err := RunProcess(context.Background(), c.options, "/bin/sh", "-c", c.script)
+		if c.error == "" && err != nil {
+			t.Errorf("%s: unexpected error: %v", c.name, err)
+		} else if c.error != "" && (err == nil || !strings.Contains(err.Error(), c.error)) {
+			t.Errorf("%s: expected error containing %q, found: %v", c.name, c.error, err)
+		}
+		if result.ExitCode != c.exitCode || result.Stdout != c.stdout || result.Stderr != c.stderr {
+			t.Errorf("%s: unexpected result %+v", c.name, result)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// Environment variable carrying the settings applied by the process launcher before running the command
+const launcherEnv = "GO_CRON_PROCESS_LAUNCHER"
+
+// Settings applied by the process launcher, in the started process, before replacing it with the command.
+// Umask, niceness and resource limits cannot be declared on the started process, so the running executable
+// is started as launcher with the command as arguments. The launcher switches the process user and group
+// after applying them, with the scheduler privileges
+type launcherSettings struct {
+	Umask      int                 `json:"umask"`
+	Nice       int                 `json:"nice,omitempty"`
+	Limits     []rlimit            `json:"limits,omitempty"`
+	Credential *syscall.Credential `json:"credential,omitempty"`
+}
+
+// Resource limit applied by the process launcher
+type rlimit struct {
+	Name     string `json:"name"`
+	Resource int    `json:"resource"`
+	Value    uint64 `json:"value"`
+}
+
+// Tells if the running program calls the process launcher entry point, see RunProcessLauncher
+var launcherEnabled bool
+
+// Process launcher entry point, programs running the scheduler commands call it first in their main function.
+// When the program is started as launcher it applies the process settings and it's replaced by the command,
+// so it never returns. Otherwise it enables the process launcher for the commands started by the program
+func RunProcessLauncher() {
+	if settings, ok := os.LookupEnv(launcherEnv); ok {
+		launch(settings)
+	}
+	launcherEnabled = true
+}
+
+// Applies the launcher settings and replaces the launcher process with the command, it never returns
+func launch(settings string) {
+	var s launcherSettings
+	var err = json.Unmarshal([]byte(settings), &s)
+	if err == nil {
+		err = s.apply()
+	}
+	if err == nil && len(os.Args) < 3 {
+		err = errors.New("Missing launched command")
+	}
+	if err == nil {
+		_ = os.Unsetenv(launcherEnv)
+		err = syscall.Exec(os.Args[1], os.Args[2:], os.Environ())
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "Unable to launch command: %v\n", err)
+	os.Exit(126)
+}
+
+func (s launcherSettings) apply() error {
+	if s.Nice != 0 {
+		if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, s.Nice); err != nil {
+			return errors.New(fmt.Sprintf("Unable to set niceness %d: %v", s.Nice, err))
+		}
+	}
+	for _, l := range s.Limits {
+		if err := syscall.Setrlimit(l.Resource, &syscall.Rlimit{Cur: l.Value, Max: l.Value}); err != nil {
+			return errors.New(fmt.Sprintf("Unable to set %s limit %d: %v", l.Name, l.Value, err))
+		}
+	}
+	if s.Umask >= 0 {
+		syscall.Umask(s.Umask)
+	}
+	if s.Credential != nil {
+		if !s.Credential.NoSetGroups {
+			var groups = make([]int, 0)
+			for _, g := range s.Credential.Groups {
+				groups = append(groups, int(g))
+			}
+			if err := syscall.Setgroups(groups); err != nil {
+				return errors.New(fmt.Sprintf("Unable to set groups %v: %v", groups, err))
+			}
+		}
+		if err := syscall.Setgid(int(s.Credential.Gid)); err != nil {
+			return errors.New(fmt.Sprintf("Unable to set group %d: %v", s.Credential.Gid, err))
+		}
+		if err := syscall.Setuid(int(s.Credential.Uid)); err != nil {
+			return errors.New(fmt.Sprintf("Unable to set user %d: %v", s.Credential.Uid, err))
+		}
+	}
+	return nil
+}
+
+// Collects the declared resource limits
+func resourceLimits(limits ResourceLimits) []rlimit {
+	var list = make([]rlimit, 0)
+	for _, l := range []rlimit{
+		{"CPU time", syscall.RLIMIT_CPU, limits.CPUSeconds},
+		{"address space", syscall.RLIMIT_AS, limits.AddressSpace},
+		{"open files", syscall.RLIMIT_NOFILE, limits.OpenFiles},
+		{"processes", rlimitProcesses, limits.Processes},
+	} {
+		if l.Value > 0 {
+			list = append(list, l)
+		}
+	}
+	return list
+}
+
+// Resolves the credential of the process user and group, nil when the scheduler ones are used
+func processCredential(options ProcessOptions) (*syscall.Credential, error) {
+	if options.User == "" && options.Group == "" {
+		return nil, nil
+	}
+	var credential = &syscall.Credential{Uid: uint32(os.Geteuid()), Gid: uint32(os.Getegid()), NoSetGroups: true}
+	if options.User != "" {
+		u, err := lookupUser(options.User)
+		if err != nil && (options.Group == "" || !isNumeric(options.User)) {
+			return nil, err
+		}
+		credential.NoSetGroups = false
+		credential.Groups = make([]uint32, 0)
+		if u == nil {
+			// Numeric user ids without account run with the declared group only
+			uid, _ := strconv.ParseUint(options.User, 10, 32)
+			credential.Uid = uint32(uid)
+		} else {
+			credential.Uid = parseId(u.Uid)
+			credential.Gid = parseId(u.Gid)
+			if groups, err := u.GroupIds(); err == nil {
+				for _, g := range groups {
+					credential.Groups = append(credential.Groups, parseId(g))
+				}
+			}
+		}
+	}
+	if options.Group != "" {
+		gid, err := lookupGroup(options.Group)
+		if err != nil {
+			return nil, err
+		}
+		credential.Gid = gid
+	}
+	return credential, nil
+}
+
+func lookupUser(name string) (*user.User, error) {
+	var u *user.User
+	var err error
+	if isNumeric(name) {
+		u, err = user.LookupId(name)
+	} else {
+		u, err = user.Lookup(name)
+	}
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Unknown user %s: %v", name, err))
+	}
+	return u, nil
+}
+
+func lookupGroup(name string) (uint32, error) {
+	if isNumeric(name) {
+		return parseId(name), nil
+	}
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("Unknown group %s: %v", name, err))
+	}
+	return parseId(g.Gid), nil
+}
+
+func isNumeric(value string) bool {
+	_, err := strconv.ParseUint(value, 10, 32)
+	return err == nil
+}
+
+func parseId(value string) uint32 {
+	id, _ := strconv.ParseUint(value, 10, 32)
+	return uint32(id)
+}
+
+// Verifies that the process user, group, niceness and resource limits can be applied by the scheduler process
+func validateProcess(options ProcessOptions) error {
+	credential, err := processCredential(options)
+	if err != nil {
+		return err
+	}
+	var root = os.Geteuid() == 0
+	if credential != nil && !root && (credential.Uid != uint32(os.Geteuid()) || credential.Gid != uint32(os.Getegid())) {
+		return errors.New(fmt.Sprintf("Running commands as user '%s' and group '%s' requires the scheduler to run as root", options.User, options.Group))
+	}
+	if options.Nice < 0 && !root {
+		return errors.New(fmt.Sprintf("Negative niceness %d requires the scheduler to run as root", options.Nice))
+	}
+	for _, l := range resourceLimits(options.Limits) {
+		var current syscall.Rlimit
+		if err := syscall.Getrlimit(l.Resource, &current); err != nil {
+			return errors.New(fmt.Sprintf("Unable to read %s limit: %v", l.Name, err))
+		}
+		if l.Value > current.Max && !root {
+			return errors.New(fmt.Sprintf("The %s limit %d exceeds the scheduler hard limit %d, raising it requires the scheduler to run as root", l.Name, l.Value, current.Max))
+		}
+	}
+	return nil
+}
+
+// Applies the process user and group and, when umask, niceness or resource limits are declared, starts the
+// command through the process launcher
+func configureProcess(cmd *exec.Cmd, options ProcessOptions) error {
+	credential, err := processCredential(options)
+	if err != nil {
+		return err
+	}
+	umask, err := options.umask()
+	if err != nil {
+		return err
+	}
+	var settings = launcherSettings{Umask: umask, Nice: options.Nice, Limits: resourceLimits(options.Limits)}
+	if settings.Umask < 0 && settings.Nice == 0 && len(settings.Limits) == 0 {
+		if credential != nil {
+			cmd.SysProcAttr.Credential = credential
+		}
+		return nil
+	}
+	if !launcherEnabled {
+		return errors.New("Process umask, niceness and resource limits require the program to call utils.RunProcessLauncher at start-up")
+	}
+	settings.Credential = credential
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return err
+	}
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.New(fmt.Sprintf("Unable to locate the process launcher: %v", err))
+	}
+	var env = cmd.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(append([]string{}, env...), launcherEnv+"="+string(data))
+	cmd.Args = append([]string{executable, cmd.Path}, cmd.Args...)
+	cmd.Path = executable
+	return nil
+}
+
+// Starts the command in a new process group, so all its children can be killed together
+func setProcessGroup(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// Kills the whole process group of a started command
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+}
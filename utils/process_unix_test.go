@@ -0,0 +1,99 @@
+//go:build !windows
+// +build !windows
+
+package utils
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestProcessCredential(t *testing.T) {
+	current, err := user.Current()
+	if err != nil {
+		t.Skipf("Current user not available: %v", err)
+	}
+	var uid, gid = uint32(os.Geteuid()), uint32(os.Getegid())
+	var cases = []struct {
+		name        string
+		options     ProcessOptions
+		error       bool
+		credential  bool
+		uid         uint32
+		gid         uint32
+		noSetGroups bool
+	}{
+		{"scheduler user", ProcessOptions{}, false, false, 0, 0, false},
+		{"user name", ProcessOptions{User: current.Username}, false, true, parseId(current.Uid), parseId(current.Gid), false},
+		{"user id", ProcessOptions{User: current.Uid}, false, true, parseId(current.Uid), parseId(current.Gid), false},
+		{"group only", ProcessOptions{Group: "54321"}, false, true, uid, 54321, true},
+		{"user and group", ProcessOptions{User: current.Username, Group: "54321"}, false, true, parseId(current.Uid), 54321, false},
+		// Numeric user ids without account are accepted with a declared group only
+		{"user id without account", ProcessOptions{User: "54321", Group: "54322"}, false, true, 54321, 54322, false},
+		{"user id without account and group", ProcessOptions{User: "54321"}, true, false, 0, 0, false},
+		{"unknown user", ProcessOptions{User: "go-cron-missing-user"}, true, false, 0, 0, false},
+		{"unknown group", ProcessOptions{User: current.Username, Group: "go-cron-missing-group"}, true, false, 0, 0, false},
+	}
+	for _, c := range cases {
+		credential, err := processCredential(c.options)
+		if c.error != (err != nil) {
+			t.Errorf("%s: expected error %v, found: %v", c.name, c.error, err)
+			continue
+		}
+		if c.credential != (credential != nil) {
+			t.Errorf("%s: expected credential %v, found %+v", c.name, c.credential, credential)
+			continue
+		}
+		if credential != nil && (credential.Uid != c.uid || credential.Gid != c.gid || credential.NoSetGroups != c.noSetGroups) {
+			t.Errorf("%s: expected user %d, group %d and no set groups %v, found %+v", c.name, c.uid, c.gid, c.noSetGroups, credential)
+		}
+	}
+	if credential, _ := processCredential(ProcessOptions{Group: strconv.Itoa(int(gid))}); credential == nil || credential.Uid != uid {
+		t.Errorf("Expected scheduler user with declared group, found %+v", credential)
+	}
+}
+
+func TestValidateProcess(t *testing.T) {
+	var root = os.Geteuid() == 0
+	var files syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &files); err != nil {
+		t.Fatalf("Unable to read the open files limit: %v", err)
+	}
+	var hardLimit = uint64(files.Max)
+	var other = strconv.Itoa(os.Geteuid() + 1)
+	var cases = []struct {
+		name         string
+		options      ProcessOptions
+		unprivileged bool
+	}{
+		{"no options", ProcessOptions{}, false},
+		{"scheduler user", ProcessOptions{User: strconv.Itoa(os.Geteuid()), Group: strconv.Itoa(os.Getegid())}, false},
+		{"scheduler group", ProcessOptions{Group: strconv.Itoa(os.Getegid())}, false},
+		{"other user", ProcessOptions{User: other, Group: strconv.Itoa(os.Getegid())}, true},
+		{"other group", ProcessOptions{Group: strconv.Itoa(os.Getegid() + 1)}, true},
+		{"lower priority", ProcessOptions{Nice: 10}, false},
+		{"higher priority", ProcessOptions{Nice: -5}, true},
+		{"lower limit", ProcessOptions{Limits: ResourceLimits{OpenFiles: 64}}, false},
+		{"hard limit", ProcessOptions{Limits: ResourceLimits{OpenFiles: hardLimit}}, false},
+	}
+	if hardLimit < ^uint64(0) {
+		cases = append(cases, struct {
+			name         string
+			options      ProcessOptions
+			unprivileged bool
+		}{"over hard limit", ProcessOptions{Limits: ResourceLimits{OpenFiles: hardLimit + 1}}, true})
+	}
+	for _, c := range cases {
+		// Settings requiring privileges are rejected only when the scheduler doesn't run as root
+		var expected = c.unprivileged && !root
+		if err := validateProcess(c.options); expected != (err != nil) {
+			t.Errorf("%s: expected error %v running as root %v, found: %v", c.name, expected, root, err)
+		}
+	}
+	if err := validateProcess(ProcessOptions{User: "go-cron-missing-user"}); err == nil {
+		t.Errorf("Expected error validating an unknown user")
+	}
+}
@@ -0,0 +1,39 @@
+//go:build windows
+// +build windows
+
+package utils
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// Verifies that the process user, group, niceness and resource limits are not declared, they are not
+// available on windows
+func validateProcess(options ProcessOptions) error {
+	if options.User != "" || options.Group != "" || options.Umask != "" || options.Nice != 0 || !options.Limits.IsEmpty() {
+		return errors.New("Process user, group, umask, niceness and resource limits are not available on windows")
+	}
+	return nil
+}
+
+// The process launcher is not available on windows, see validateProcess
+func RunProcessLauncher() {
+}
+
+// Verifies that no unavailable process option is declared
+func configureProcess(cmd *exec.Cmd, options ProcessOptions) error {
+	return validateProcess(options)
+}
+
+// Process groups are not available, the command runs in the scheduler process group
+func setProcessGroup(cmd *exec.Cmd) {
+}
+
+// Kills the started command process
+func killProcessGroup(cmd *exec.Cmd) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
@@ -0,0 +1,4 @@
+package utils
+
+// Maximum number of processes resource
+const rlimitProcesses = 0x6
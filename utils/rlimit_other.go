@@ -0,0 +1,7 @@
+//go:build !windows && !linux
+// +build !windows,!linux
+
+package utils
+
+// Maximum number of processes resource, as defined by BSD systems
+const rlimitProcesses = 0x7
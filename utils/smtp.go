@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"time"
+)
+
+// Describes how a mail is delivered to the SMTP server
+type SMTPOptions struct {
+	// Server address, as host:port
+	Server string
+	// PLAIN authentication credentials, no authentication when username is empty. Credentials are sent only
+	// over TLS connections or to the local host
+	Username string
+	Password string
+	// Upgrades the connection with STARTTLS when the server supports it
+	StartTLS bool
+	// Fails when the server doesn't support STARTTLS
+	RequireTLS bool
+	// Skips the server certificate verification
+	InsecureSkipVerify bool
+	// Session timeout, covering connection, commands and data transfer (0 for no timeout)
+	Timeout time.Duration
+}
+
+// Sends the message, with headers already encoded, from the sender to the recipients
+func SendMail(options SMTPOptions, from string, to []string, message []byte) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.New(fmt.Sprintf("%v", r))
+		}
+	}()
+	host, _, err := net.SplitHostPort(options.Server)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", options.Server, options.Timeout)
+	if err != nil {
+		return err
+	}
+	if options.Timeout > 0 {
+		_ = conn.SetDeadline(time.Now().Add(options.Timeout))
+	}
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		_ = conn.Close()
+		return err
+	}
+	defer client.Close()
+	if options.StartTLS || options.RequireTLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			err = client.StartTLS(&tls.Config{ServerName: host, InsecureSkipVerify: options.InsecureSkipVerify})
+			if err != nil {
+				return err
+			}
+		} else if options.RequireTLS {
+			return errors.New(fmt.Sprintf("SMTP server %s doesn't support STARTTLS", options.Server))
+		}
+	}
+	if options.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return errors.New(fmt.Sprintf("SMTP server %s doesn't support authentication", options.Server))
+		}
+		if err = client.Auth(smtp.PlainAuth("", options.Username, options.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err = client.Mail(from); err != nil {
+		return err
+	}
+	for _, address := range to {
+		if err = client.Rcpt(address); err != nil {
+			return err
+		}
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(message); err != nil {
+		return err
+	}
+	if err = writer.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}